@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var agentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "Inspect the configured agents",
+}
+
+var agentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured agents as chorus builds them",
+	Long: `Lists every configured agent with its role, model, reasoning effort, MCP
+servers and tools. Tool names include the built-in conversation tools; see
+chorus tools list for details. --output json also includes the rendered
+system message.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		a := startApp(cmd)
+		defer a.Close()
+
+		infos, err := a.AgentInfos()
+		if err != nil {
+			exitWith(a, "Failed to build agents", err)
+		}
+
+		err = printOutput(infos, func(w io.Writer) {
+			fmt.Fprintln(w, "NAME\tROLE\tMODEL\tEFFORT\tMCP SERVERS\tTOOLS")
+			for _, info := range infos {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", info.Name, info.Role, info.Model,
+					info.ReasoningEffort, orNone(strings.Join(info.MCPServers, ", ")), len(info.Tools))
+			}
+		})
+		if err != nil {
+			exitWith(a, "Failed to print agents", err)
+		}
+	},
+}
+
+// startApp starts the app for an introspection command.
+func startApp(cmd *cobra.Command) *app.App {
+	cfg.Debug = debug
+
+	a, err := app.New(cmd.Context(), &cfg)
+	if err != nil {
+		clog.Error("Failed to start app", "error", err)
+		os.Exit(1)
+	}
+	return a
+}
+
+// exitWith logs err, shuts the app down and exits.
+func exitWith(a *app.App, msg string, err error) {
+	clog.Error(msg, "error", err)
+	a.Close()
+	os.Exit(1)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(agentsCmd)
+	agentsCmd.AddCommand(agentsListCmd)
+	addOutputFlag(agentsListCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var (
+	chatAgent string
+	chatTUI   bool
+)
+
+var chatCmd = &cobra.Command{
+	Use:   "chat",
+	Short: "Chat interactively with a configured agent",
+	Long: `Starts an interactive chat with one of the configured agents. Replies are
+streamed, tool calls are shown as they happen, and the agent keeps its history
+across turns. Type /help for the available slash commands.
+
+With --tui the chat runs in a full-screen terminal UI with a pane per agent,
+the tool calls and token and cost counters.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg.Debug = debug
+
+		ctx := cmd.Context()
+		a, err := app.New(ctx, &cfg)
+		if err != nil {
+			clog.Error("Failed to start app", "error", err)
+			os.Exit(1)
+		}
+		watchConfig(ctx, a)
+
+		err = runChat(ctx, a)
+		a.Close()
+		if err != nil {
+			clog.Error("Chat failed", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runChat chats with the chosen agent until the user quits, in the terminal
+// UI with --tui.
+func runChat(ctx context.Context, a *app.App) error {
+	if chatTUI {
+		t := a.NewTUI("chat")
+		chat, err := a.NewChat(chatAgent, t.Input(), t.Console())
+		if err != nil {
+			return fmt.Errorf("failed to start chat: %w", err)
+		}
+		chat.Quiet()
+		t.WatchAgents(chat.Agents()...)
+		return t.Run(ctx, os.Stdin, os.Stdout, chat.Run)
+	}
+
+	chat, err := a.NewChat(chatAgent, os.Stdin, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to start chat: %w", err)
+	}
+	return chat.Run(ctx)
+}
+
+func init() {
+	rootCmd.AddCommand(chatCmd)
+	chatCmd.Flags().StringVar(&chatAgent, "agent", "", "name of the agent to chat with (default is the first configured agent)")
+	chatCmd.Flags().BoolVar(&chatTUI, "tui", false, "chat in a terminal UI")
+}
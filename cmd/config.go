@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var (
+	validateOffline      bool
+	validateConversation bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and validate the configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for mistakes",
+	Long: `Checks the config file and prints every problem found, each with the path
+of the offending setting (for example agents[2].reasoning_effort).
+
+It also checks that the MCP server commands can be found. Unless --offline is
+given, the servers are started so that the tools agents refer to can be
+checked too. Exits with status 1 if the config is invalid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg.Debug = debug
+
+		file := configFile
+		if file == "" {
+			fmt.Fprintln(os.Stderr, "No config file found.")
+			os.Exit(1)
+		}
+
+		validate := cfg.Validate
+		if validateConversation {
+			validate = cfg.ValidateConversation
+		}
+		var problems app.ValidationErrors
+		for _, err := range []error{validate(), cfg.ValidateCommands()} {
+			var errs app.ValidationErrors
+			if errors.As(err, &errs) {
+				problems = append(problems, errs...)
+			}
+		}
+		if len(problems) > 0 {
+			reportInvalid(file, problems)
+		}
+
+		if !validateOffline && len(cfg.MCPServers) > 0 {
+			a, err := app.New(cmd.Context(), &cfg)
+			if err != nil {
+				reportInvalid(file, err)
+			}
+			err = a.Validate()
+			a.Close()
+			if err != nil {
+				reportInvalid(file, err)
+			}
+		}
+
+		fmt.Printf("%s is valid.\n", file)
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for the config file",
+	Long: `Prints a JSON Schema describing the config file. Point your editor at it
+for completion and inline validation.`,
+	PersistentPreRun: skipConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := json.MarshalIndent(app.ConfigSchema(), "", "  ")
+		if err != nil {
+			clog.Error("Failed to encode schema", "error", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	},
+}
+
+// reportInvalid prints the problems with the config and exits.
+func reportInvalid(file string, err error) {
+	var errs app.ValidationErrors
+	if !errors.As(err, &errs) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		os.Exit(1)
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", file, e)
+	}
+	fmt.Fprintf(os.Stderr, "%d problem(s) found.\n", len(errs))
+	os.Exit(1)
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd, configSchemaCmd)
+	configValidateCmd.Flags().BoolVar(&validateOffline, "offline", false, "don't start MCP servers; skip checks that need them")
+	configValidateCmd.Flags().BoolVar(&validateConversation, "conversation", false, "also check the agents can run an orchestrated conversation")
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+	"github.com/standrze/chorus/pkg/transcript"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <run>",
+	Short: "Render the transcript of a saved run",
+	Long: `Renders the transcript saved when a conversation run ends as Markdown, JSON
+or a self-contained HTML page. <run> is the conversation ID, its workspace
+directory or the transcript.json file itself.
+
+The format defaults to the extension of --output, or Markdown.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		t, err := app.LoadTranscript(args[0])
+		if err != nil {
+			clog.Error("Failed to load transcript", "error", err)
+			os.Exit(1)
+		}
+		// Saved transcripts are already redacted; this applies rules added since.
+		t.Redact(cfg.Redactor())
+
+		format := exportFormat
+		if format == "" {
+			format = transcript.FormatFor(exportOutput)
+		}
+		out := os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			if out, err = os.Create(exportOutput); err != nil {
+				clog.Error("Failed to create output", "error", err)
+				os.Exit(1)
+			}
+		}
+		err = transcript.Export(out, t, format)
+		if out != os.Stdout {
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			clog.Error("Failed to export transcript", "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "", fmt.Sprintf("output format: %s", strings.Join(transcript.Formats, ", ")))
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "write to this file instead of stdout")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+)
+
+var (
+	initDir   string
+	initForce bool
+	initList  bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [template]",
+	Short: "Create a new chorus project from a template",
+	Long: `Writes a config file, example prompts and a workspace directory for a
+new project. Without a template name the review template is used; run
+chorus init --list to see them all.`,
+	Args:             cobra.MaximumNArgs(1),
+	PersistentPreRun: skipConfig,
+	Run: func(cmd *cobra.Command, args []string) {
+		if initList {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for i, t := range app.Templates() {
+				name := t.Name
+				if i == 0 {
+					name += " (default)"
+				}
+				fmt.Fprintf(w, "%s\t%s\n", name, t.Description)
+			}
+			w.Flush()
+			return
+		}
+
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		written, err := app.Scaffold(name, initDir, initForce)
+		for _, path := range written {
+			fmt.Printf("created %s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println()
+		if initDir != "." {
+			fmt.Printf("cd %s\n", initDir)
+		}
+		fmt.Println("Check base_url in config.yaml, then try: chorus run --objective \"...\"")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initDir, "dir", ".", "directory to create the project in")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite existing files")
+	initCmd.Flags().BoolVar(&initList, "list", false, "list the available templates")
+}
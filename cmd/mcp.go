@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Inspect the configured MCP servers",
+}
+
+var mcpListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show each MCP server's status, tools, resources and prompts",
+	Run: func(cmd *cobra.Command, args []string) {
+		a := startApp(cmd)
+		defer a.Close()
+
+		infos := a.MCPServerInfos(cmd.Context())
+
+		err := printOutput(infos, func(w io.Writer) {
+			for i, info := range infos {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				status := info.Status
+				if info.Server != "" {
+					status += " (" + info.Server + ")"
+				}
+				fmt.Fprintf(w, "%s\t%s\n", info.Name, status)
+				fmt.Fprintf(w, "  command:\t%s\n", strings.Join(append([]string{info.Command}, info.Args...), " "))
+				if info.Error != "" {
+					fmt.Fprintf(w, "  error:\t%s\n", info.Error)
+				}
+				fmt.Fprintf(w, "  tools:\t%s\n", orNone(strings.Join(info.Tools, ", ")))
+				fmt.Fprintf(w, "  resources:\t%s\n", orNone(strings.Join(info.Resources, ", ")))
+				fmt.Fprintf(w, "  prompts:\t%s\n", orNone(strings.Join(info.Prompts, ", ")))
+			}
+		})
+		if err != nil {
+			exitWith(a, "Failed to print MCP servers", err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+	mcpCmd.AddCommand(mcpListCmd)
+	addOutputFlag(mcpListCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "mcp-serve",
+	Short: "Serve chorus agents as MCP tools over stdio",
+	Long: `Runs chorus as an MCP server on stdin/stdout. Each configured agent is
+exposed as an ask_<agent> tool, and run_conversation runs a full
+orchestrated conversation for an objective.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg.Debug = debug
+
+		ctx := cmd.Context()
+		a, err := app.New(ctx, &cfg)
+		if err != nil {
+			clog.Error("Failed to start app", "error", err)
+			os.Exit(1)
+		}
+		defer a.Close()
+		watchConfig(ctx, a)
+
+		if err := a.ServeMCP(ctx, &mcp.StdioTransport{}); err != nil {
+			clog.Error("MCP server failed", "error", err)
+			a.Close()
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpServeCmd)
+}
@@ -4,29 +4,36 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"log"
+	"context"
 	"os"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	app "github.com/standrze/chorus/internal"
 	clog "github.com/standrze/chorus/pkg/log"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var cfgFile string
+var configFile string // the config file actually read, if any
+var profile string
 var cfg app.Config
 var debug bool
+var traceFile string
+var metricsAddr string
+var logLevel, logFormat, logFile string
 
 var rootCmd = &cobra.Command{
 	Use:   "chorus",
 	Short: "Chorus AI Agent Orchestrator",
 	Long: `Chorus is a powerful AI agent orchestration platform that allows you 
 to create, manage, and coordinate multiple AI agents to solve complex tasks.`,
+	// Subcommands that don't need a config override this.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
-		clog.SetDebug(debug)
 		cfg.Debug = debug
 		err := app.Start(&cfg)
 		if err != nil {
@@ -46,44 +53,82 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.json)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.json, .yaml, .yml or .toml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "apply the named profile from the config file")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "log level: debug, info, warn or error (default info)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "log format: text or json (default text)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "write logs to this file, rotating it, instead of stderr")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "serve Prometheus metrics on this address while the command runs")
+	rootCmd.PersistentFlags().StringVar(&traceFile, "trace", "", "write OpenTelemetry traces as OTLP JSON to this file (- for stderr)")
+}
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug logging")
+// skipConfig is a PersistentPreRun for commands that work without a config,
+// so that a broken config file doesn't stop them.
+func skipConfig(cmd *cobra.Command, args []string) {
+	configureLogging()
+}
+
+// watchConfig reloads the config file, if one was read, as it changes.
+func watchConfig(ctx context.Context, a *app.App) {
+	if configFile == "" {
+		return
+	}
+	if err := a.WatchConfig(ctx, configFile, profile); err != nil {
+		clog.Error("Failed to watch config", "error", err)
+	}
 }
 
-// initConfig reads in config file and ENV variables if set.
+// initConfig loads the config file, if there is one.
 func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Search config in current directory with name "config" (without extension).
-		viper.AddConfigPath(".")
-		viper.SetConfigName("config")
-		viper.SetConfigType("json")
+	loaded, file, err := app.LoadConfig(cfgFile, profile)
+	if err != nil {
+		configureLogging()
+		clog.Error("Unable to load config", "error", err)
+		os.Exit(1)
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	cfg = *loaded
+	configFile = file
+	if traceFile != "" {
+		cfg.Tracing.File = traceFile
+	}
+	if metricsAddr != "" {
+		cfg.Metrics.Addr = metricsAddr
+	}
+	configureLogging()
+	if file != "" {
+		clog.Debug("Using config file", "file", file)
+	}
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err != nil {
-		// Only log error if config file was explicitly set, otherwise it might be optional?
-		// But in this app logic, config seems required for Agents. Let's warn.
-		// log.Println("No config file found.")
-	} else {
-		if debug {
-			log.Println("Using config file:", viper.ConfigFileUsed())
-		}
+// configureLogging applies the log config, overridden by the --log-* and
+// --debug flags.
+func configureLogging() {
+	if logLevel != "" {
+		cfg.Log.Level = logLevel
+	}
+	if debug {
+		cfg.Log.Level = "debug"
+	}
+	if logFormat != "" {
+		cfg.Log.Format = logFormat
+	}
+	if logFile != "" {
+		cfg.Log.File = logFile
 	}
 
-	if err := viper.Unmarshal(&cfg); err != nil {
-		log.Fatalf("Unable to decode config into struct: %v", err)
+	opts, err := cfg.Log.Options()
+	opts.Redactor = cfg.Redactor()
+	if err == nil {
+		err = clog.Configure(opts)
+	}
+	if err != nil {
+		clog.Error("Invalid log settings", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+// Exit codes for chorus run.
+const (
+	exitMaxTurns       = 2
+	exitBudgetExceeded = 3
+)
+
+var (
+	objective     string
+	objectiveFile string
+	maxTurns      int
+	tokenBudget   int64
+	runTUI        bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run an orchestrated multi-agent conversation",
+	Long: `Builds the configured agents and runs a conversation in which the agent
+with role "orchestrator" plans, delegates to the other agents and finishes
+with a result, which is printed to stdout.
+
+With --tui the run is shown in a full-screen terminal UI, with a pane per
+agent, the plan, the tool calls and token and cost counters, from which the
+run can be paused, tool calls approved and messages sent to agents.
+
+Exits with status 2 if the turn limit is reached and 3 if the token budget
+is exceeded.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg.Debug = debug
+
+		obj, err := readObjective()
+		if err != nil {
+			clog.Error("Invalid objective", "error", err)
+			os.Exit(1)
+		}
+		if cmd.Flags().Changed("max-turns") {
+			cfg.MaxTurns = maxTurns
+		}
+		if cmd.Flags().Changed("budget") {
+			cfg.TokenBudget = tokenBudget
+		}
+		if err := cfg.ValidateConversation(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config:\n%v\n", err)
+			os.Exit(1)
+		}
+
+		ctx := cmd.Context()
+		a, err := app.New(ctx, &cfg)
+		if err != nil {
+			clog.Error("Failed to start app", "error", err)
+			os.Exit(1)
+		}
+
+		var result string
+		if runTUI {
+			t := a.NewTUI(obj)
+			a.WatchConversations(t.WatchConversation)
+			err = t.Run(ctx, os.Stdin, os.Stdout, func(ctx context.Context) error {
+				var err error
+				result, err = a.RunConversation(ctx, obj)
+				return err
+			})
+		} else {
+			result, err = a.RunConversation(ctx, obj)
+		}
+		a.Close()
+		if err != nil {
+			clog.Error("Conversation failed", "error", err)
+			switch {
+			case errors.Is(err, chorus.ErrMaxTurns):
+				os.Exit(exitMaxTurns)
+			case errors.Is(err, chorus.ErrBudgetExceeded):
+				os.Exit(exitBudgetExceeded)
+			default:
+				os.Exit(1)
+			}
+		}
+
+		fmt.Println(result)
+	},
+}
+
+// readObjective takes the objective from --objective or --objective-file ("-" reads stdin).
+func readObjective() (string, error) {
+	if objective != "" && objectiveFile != "" {
+		return "", fmt.Errorf("use either --objective or --objective-file, not both")
+	}
+	if objectiveFile != "" {
+		var data []byte
+		var err error
+		if objectiveFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(objectiveFile)
+		}
+		if err != nil {
+			return "", err
+		}
+		objective = string(data)
+	}
+
+	obj := strings.TrimSpace(objective)
+	if obj == "" {
+		return "", fmt.Errorf("an objective is required (--objective or --objective-file)")
+	}
+	return obj, nil
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().StringVar(&objective, "objective", "", "the objective for the orchestrator")
+	runCmd.Flags().StringVar(&objectiveFile, "objective-file", "", "read the objective from a file (- for stdin)")
+	runCmd.Flags().IntVar(&maxTurns, "max-turns", 0, "maximum orchestrator turns (overrides max_turns)")
+	runCmd.Flags().Int64Var(&tokenBudget, "budget", 0, "token budget across all agents (overrides token_budget)")
+	runCmd.Flags().BoolVar(&runTUI, "tui", false, "watch the run in a terminal UI")
+}
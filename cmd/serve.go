@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	app "github.com/standrze/chorus/internal"
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var (
+	serveAddr  string
+	serveTTL   time.Duration
+	serveToken string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP API for agents and conversations",
+	Long: `Serves a JSON API for creating agents and conversations, sending them
+messages, running conversations in the background and fetching their status
+and transcripts. Agents and finished conversations are forgotten after --ttl
+without use. Set --token (or CHORUS_API_TOKEN) to require a bearer token.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg.Debug = debug
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		a, err := app.New(ctx, &cfg)
+		if err != nil {
+			clog.Error("Failed to start app", "error", err)
+			os.Exit(1)
+		}
+		defer a.Close()
+		watchConfig(ctx, a)
+
+		if serveToken == "" {
+			serveToken = os.Getenv("CHORUS_API_TOKEN")
+		}
+		server := &http.Server{
+			Addr:    serveAddr,
+			Handler: a.NewServer(ctx, app.ServerOptions{Token: serveToken, TTL: serveTTL}).Handler(),
+		}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		clog.Info("Serving HTTP API", "addr", serveAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			clog.Error("HTTP server failed", "error", err)
+			a.Close()
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080", "address to listen on")
+	serveCmd.Flags().DurationVar(&serveTTL, "ttl", time.Hour, "how long unused agents and finished conversations are kept (0 keeps them)")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "bearer token required on every request")
+}
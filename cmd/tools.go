@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var toolsAgent string
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Inspect the tools given to agents",
+}
+
+var toolsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List each agent's tools and their parameters",
+	Long: `Lists the tools each agent can call: the built-in tools it gets in an
+orchestrated conversation (source "conversation") and the MCP tools it is
+allowed (source "mcp:<server>"). The table shows parameter names, with * marking
+required ones; --output json includes each tool's full JSON schema.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		a := startApp(cmd)
+		defer a.Close()
+
+		infos, err := a.ToolInfos(toolsAgent)
+		if err != nil {
+			exitWith(a, "Failed to list tools", err)
+		}
+
+		err = printOutput(infos, func(w io.Writer) {
+			fmt.Fprintln(w, "AGENT\tTOOL\tSOURCE\tPARAMETERS\tDESCRIPTION")
+			for _, info := range infos {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", info.Agent, info.Name, info.Source,
+					orNone(parameterSummary(info.Parameters)), info.Description)
+			}
+		})
+		if err != nil {
+			exitWith(a, "Failed to print tools", err)
+		}
+	},
+}
+
+// parameterSummary lists the property names of a JSON schema, marking the
+// required ones with *.
+func parameterSummary(schema map[string]any) string {
+	properties, _ := schema["properties"].(map[string]any)
+	var required []string
+	switch r := schema["required"].(type) {
+	case []string:
+		required = r
+	case []any:
+		for _, name := range r {
+			if s, ok := name.(string); ok {
+				required = append(required, s)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		if slices.Contains(required, name) {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(toolsCmd)
+	toolsCmd.AddCommand(toolsListCmd)
+	toolsListCmd.Flags().StringVar(&toolsAgent, "agent", "", "only list this agent's tools")
+	addOutputFlag(toolsListCmd)
+}
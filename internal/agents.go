@@ -0,0 +1,354 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// reasoningEfforts lists the accepted reasoning_effort values.
+var reasoningEfforts = []openai.ReasoningEffort{
+	openai.ReasoningEffortNone,
+	openai.ReasoningEffortMinimal,
+	openai.ReasoningEffortLow,
+	openai.ReasoningEffortMedium,
+	openai.ReasoningEffortHigh,
+	openai.ReasoningEffortXhigh,
+}
+
+// systemMessageData is what system message templates are rendered with.
+type systemMessageData struct {
+	Name   string
+	Role   string
+	Model  string
+	Agents []string // names of every configured agent
+	Vars   map[string]any
+}
+
+// NewAgent builds a fresh agent from its configuration, with the MCP tools attached.
+// The agent follows later changes to the servers' tool lists.
+func (app *App) NewAgent(agentCfg AgentConfig) (*chorus.Agent, error) {
+	return app.newAgent(app.config(), agentCfg)
+}
+
+// newAgent builds an agent from agentCfg, resolving references against cfg.
+func (app *App) newAgent(cfg *Config, agentCfg AgentConfig) (*chorus.Agent, error) {
+	agentOpts, err := app.agentOptions(cfg, agentCfg)
+	if err != nil {
+		name := agentCfg.Name
+		if name == "" {
+			name = "(unnamed)"
+		}
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+
+	agent := chorus.NewAgent(app.client, agentOpts...)
+	app.metrics.instrumentAgent(agent)
+	return agent, nil
+}
+
+// NewAgents builds a fresh agent for every configured agent.
+func (app *App) NewAgents() ([]*chorus.Agent, error) {
+	cfg := app.config()
+	agents := make([]*chorus.Agent, 0, len(cfg.Agents))
+	for _, agentCfg := range cfg.Agents {
+		agent, err := app.newAgent(cfg, agentCfg)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+// ErrUnknownAgent is returned by newAgentsNamed for a name no agent is
+// configured with.
+var ErrUnknownAgent = errors.New("unknown agent")
+
+// newAgentsNamed builds fresh agents for the named configured agents, in the
+// order given, or for every configured agent if names is empty.
+func (app *App) newAgentsNamed(names []string) ([]*chorus.Agent, error) {
+	if len(names) == 0 {
+		return app.NewAgents()
+	}
+
+	cfg := app.config()
+	agents := make([]*chorus.Agent, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(cfg.Agents, func(a AgentConfig) bool { return a.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w %q", ErrUnknownAgent, name)
+		}
+		agent, err := app.newAgent(cfg, cfg.Agents[i])
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, nil
+}
+
+func (app *App) agentOptions(cfg *Config, agentCfg AgentConfig) ([]func(*chorus.Agent), error) {
+	toolOpts, err := app.toolOptions(cfg, agentCfg)
+	if err != nil {
+		return nil, err
+	}
+	agentOpts, err := cfg.generationOptions(agentCfg)
+	if err != nil {
+		return nil, err
+	}
+	return append(toolOpts, agentOpts...), nil
+}
+
+// generationOptions applies the agent's name, role, model, generation
+// settings and system message.
+func (c *Config) generationOptions(agentCfg AgentConfig) ([]func(*chorus.Agent), error) {
+	effort := openai.ReasoningEffortMedium
+	if agentCfg.ReasoningEffort != "" {
+		effort = openai.ReasoningEffort(agentCfg.ReasoningEffort)
+		if !slices.Contains(reasoningEfforts, effort) {
+			return nil, fmt.Errorf("invalid reasoning_effort %q", agentCfg.ReasoningEffort)
+		}
+	}
+
+	agentOpts := []func(*chorus.Agent){
+		chorus.WithReasoningEffort(effort),
+	}
+
+	if agentCfg.Name != "" {
+		agentOpts = append(agentOpts, chorus.WithName(agentCfg.Name))
+	}
+	if agentCfg.Model != "" {
+		agentOpts = append(agentOpts, chorus.WithModel(agentCfg.Model))
+	}
+	if agentCfg.Role != "" {
+		agentOpts = append(agentOpts, chorus.WithRole(chorus.Role(agentCfg.Role)))
+	}
+	if agentCfg.Seed != nil {
+		agentOpts = append(agentOpts, chorus.WithSeed(int(*agentCfg.Seed)))
+	}
+	if agentCfg.Temperature != nil {
+		agentOpts = append(agentOpts, chorus.WithTemperature(*agentCfg.Temperature))
+	}
+	if agentCfg.TopP != nil {
+		agentOpts = append(agentOpts, chorus.WithTopP(*agentCfg.TopP))
+	}
+	if agentCfg.MaxTokens > 0 {
+		agentOpts = append(agentOpts, chorus.WithMaxTokens(agentCfg.MaxTokens))
+	}
+	if len(agentCfg.Stop) > 0 {
+		agentOpts = append(agentOpts, chorus.WithStop(agentCfg.Stop...))
+	}
+	if agentCfg.ResponseFormat != nil {
+		format, err := responseFormat(*agentCfg.ResponseFormat)
+		if err != nil {
+			return nil, err
+		}
+		agentOpts = append(agentOpts, chorus.WithResponseFormat(format))
+	}
+	if agentCfg.Context.MaxMessages > 0 {
+		agentOpts = append(agentOpts, chorus.WithContextPolicy(chorus.ContextPolicy{
+			MaxMessages: agentCfg.Context.MaxMessages,
+		}))
+	}
+
+	system, err := c.systemMessage(agentCfg)
+	if err != nil {
+		return nil, err
+	}
+	if system != "" {
+		agentOpts = append(agentOpts, chorus.WithSystemMessage(system))
+	}
+
+	return agentOpts, nil
+}
+
+// toolOptions attaches the toolsets of the agent's MCP servers, narrowed to
+// its tool list if it has one.
+func (app *App) toolOptions(cfg *Config, agentCfg AgentConfig) ([]func(*chorus.Agent), error) {
+	for _, name := range agentCfg.MCPServers {
+		if !slices.ContainsFunc(cfg.MCPServers, func(s MCPServerConfig) bool { return s.DisplayName() == name }) {
+			return nil, fmt.Errorf("unknown MCP server %q", name)
+		}
+	}
+
+	names := cfg.serverNames(agentCfg)
+	var opts []func(*chorus.Agent)
+	for _, server := range app.servers {
+		if !slices.Contains(names, server.cfg.DisplayName()) {
+			continue
+		}
+		opts = append(opts, chorus.WithToolset(server.toolset, agentCfg.Tools...))
+	}
+	return opts, nil
+}
+
+// serverNames returns the names of the MCP servers an agent uses: the ones it
+// lists, or every configured server if it lists none.
+func (c *Config) serverNames(agentCfg AgentConfig) []string {
+	if len(agentCfg.MCPServers) > 0 {
+		return agentCfg.MCPServers
+	}
+	names := make([]string, len(c.MCPServers))
+	for i, server := range c.MCPServers {
+		names[i] = server.DisplayName()
+	}
+	return names
+}
+
+// systemMessage loads and renders the agent's system message.
+func (c *Config) systemMessage(agentCfg AgentConfig) (string, error) {
+	text := agentCfg.SystemMessage
+	if agentCfg.SystemMessageFile != "" {
+		if text != "" {
+			return "", fmt.Errorf("system_message and system_message_file are mutually exclusive")
+		}
+		data, err := os.ReadFile(agentCfg.SystemMessageFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read system message: %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("system_message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid system message template: %w", err)
+	}
+	// Config keys are read lowercased, so vars are looked up in lower case.
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			lowerVarKeys(t.Root)
+		}
+	}
+
+	data := systemMessageData{
+		Name:  agentCfg.Name,
+		Role:  agentCfg.Role,
+		Model: agentCfg.Model,
+		Vars:  lowerKeys(agentCfg.Vars),
+	}
+	for _, other := range c.Agents {
+		data.Agents = append(data.Agents, other.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render system message: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// lowerVarKeys lowercases the keys a template looks up in .Vars, so
+// {{.Vars.Topic}} finds topic.
+func lowerVarKeys(node parse.Node) {
+	lower := func(ident []string) {
+		if len(ident) > 1 && ident[0] == "Vars" {
+			for i := 1; i < len(ident); i++ {
+				ident[i] = strings.ToLower(ident[i])
+			}
+		}
+	}
+	branch := func(b *parse.BranchNode) {
+		lowerVarKeys(b.Pipe)
+		lowerVarKeys(b.List)
+		lowerVarKeys(b.ElseList)
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				lowerVarKeys(child)
+			}
+		}
+	case *parse.PipeNode:
+		if n != nil {
+			for _, cmd := range n.Cmds {
+				lowerVarKeys(cmd)
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			lowerVarKeys(arg)
+		}
+	case *parse.ActionNode:
+		lowerVarKeys(n.Pipe)
+	case *parse.IfNode:
+		branch(&n.BranchNode)
+	case *parse.RangeNode:
+		branch(&n.BranchNode)
+	case *parse.WithNode:
+		branch(&n.BranchNode)
+	case *parse.TemplateNode:
+		lowerVarKeys(n.Pipe)
+	case *parse.ChainNode:
+		lowerVarKeys(n.Node)
+	case *parse.FieldNode:
+		lower(n.Ident)
+	case *parse.VariableNode:
+		// $.Vars.Topic
+		lower(n.Ident[1:])
+	}
+}
+
+// lowerKeys returns vars with its keys, and those of maps in it, lowercased
+// to match the lookups lowerVarKeys makes.
+func lowerKeys(vars map[string]any) map[string]any {
+	if vars == nil {
+		return nil
+	}
+	lowered := make(map[string]any, len(vars))
+	for k, v := range vars {
+		if m, ok := v.(map[string]any); ok {
+			v = lowerKeys(m)
+		}
+		lowered[strings.ToLower(k)] = v
+	}
+	return lowered
+}
+
+func responseFormat(cfg ResponseFormatConfig) (openai.ChatCompletionNewParamsResponseFormatUnion, error) {
+	var format openai.ChatCompletionNewParamsResponseFormatUnion
+
+	switch cfg.Type {
+	case ResponseFormatText:
+		format.OfText = &openai.ResponseFormatTextParam{}
+	case ResponseFormatJSONObject:
+		format.OfJSONObject = &openai.ResponseFormatJSONObjectParam{}
+	case ResponseFormatJSONSchema:
+		if cfg.Name == "" || cfg.SchemaFile == "" {
+			return format, fmt.Errorf("response_format json_schema needs a name and schema_file")
+		}
+		data, err := os.ReadFile(cfg.SchemaFile)
+		if err != nil {
+			return format, fmt.Errorf("failed to read response schema: %w", err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return format, fmt.Errorf("invalid response schema %s: %w", cfg.SchemaFile, err)
+		}
+		format.OfJSONSchema = &openai.ResponseFormatJSONSchemaParam{
+			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   cfg.Name,
+				Strict: openai.Bool(cfg.Strict),
+				Schema: schema,
+			},
+		}
+	default:
+		return format, fmt.Errorf("invalid response_format type %q", cfg.Type)
+	}
+
+	return format, nil
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAgent_SystemMessageTemplate(t *testing.T) {
+	app := &App{
+		cfg: &Config{Agents: []AgentConfig{
+			{Name: "Teacher"},
+			{Name: "Professor"},
+		}},
+		client: &stubClient{},
+	}
+
+	agent, err := app.NewAgent(AgentConfig{
+		Name:          "Teacher",
+		SystemMessage: "You are {{.Name}}, working with {{range .Agents}}{{.}} {{end}}on {{.Vars.topic}}.",
+		Vars:          map[string]any{"topic": "biology"},
+	})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	want := "You are Teacher, working with Teacher Professor on biology."
+	if got := systemMessageOf(agent); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNewAgent_VarsIgnoreCase(t *testing.T) {
+	// Vars come from the config with their keys lowercased.
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+agents:
+  - name: Teacher
+    system_message: "{{.Vars.Topic}} for {{.Vars.Course.Name}}{{if .Vars.Course}}, {{$.Vars.TOPIC}}{{end}}"
+    vars:
+      Topic: biology
+      Course: {Name: BIO101}
+`)
+	cfg, _, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{cfg: cfg, client: &stubClient{}}
+	agent, err := app.NewAgent(cfg.Agents[0])
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+	if got, want := systemMessageOf(agent), "biology for BIO101, biology"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNewAgent_SystemMessageFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "system.txt")
+	if err := os.WriteFile(file, []byte("From a file."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &App{cfg: &Config{}, client: &stubClient{}}
+	agent, err := app.NewAgent(AgentConfig{SystemMessageFile: file})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+	if got := systemMessageOf(agent); got != "From a file." {
+		t.Errorf("Expected system message from file, got %q", got)
+	}
+}
+
+func TestNewAgent_Settings(t *testing.T) {
+	temperature, topP := 0.2, 0.9
+	app := &App{cfg: &Config{}, client: &stubClient{}}
+
+	agent, err := app.NewAgent(AgentConfig{
+		Name:           "Teacher",
+		Temperature:    &temperature,
+		TopP:           &topP,
+		MaxTokens:      512,
+		Stop:           []string{"END"},
+		ResponseFormat: &ResponseFormatConfig{Type: ResponseFormatJSONObject},
+		Context:        ContextConfig{MaxMessages: 10},
+	})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	if agent.Temperature.Value != 0.2 || agent.TopP.Value != 0.9 || agent.MaxTokens.Value != 512 {
+		t.Errorf("Sampling settings not applied")
+	}
+	if len(agent.Stop) != 1 || agent.ResponseFormat.OfJSONObject == nil || agent.ContextPolicy.MaxMessages != 10 {
+		t.Errorf("Output settings not applied")
+	}
+}
+
+func TestNewAgent_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AgentConfig
+		want string
+	}{
+		{"reasoning effort", AgentConfig{ReasoningEffort: "extreme"}, "invalid reasoning_effort"},
+		{"response format", AgentConfig{ResponseFormat: &ResponseFormatConfig{Type: "xml"}}, "invalid response_format"},
+		{"json schema", AgentConfig{ResponseFormat: &ResponseFormatConfig{Type: ResponseFormatJSONSchema}}, "needs a name"},
+		{"mcp server", AgentConfig{MCPServers: []string{"missing"}}, "unknown MCP server"},
+		{"system message", AgentConfig{SystemMessage: "{{.Vars.missing}}"}, "failed to render"},
+		{"both system messages", AgentConfig{SystemMessage: "a", SystemMessageFile: "b"}, "mutually exclusive"},
+	}
+
+	app := &App{cfg: &Config{}, client: &stubClient{}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := app.NewAgent(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -3,165 +3,141 @@ package internal
 import (
 	"context"
 	"fmt"
-
-	"encoding/json"
-	"log"
-	"os/exec"
+	"sync"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
-	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	chorus "github.com/standrze/chorus/pkg/agent"
 	"github.com/standrze/chorus/pkg/client"
-	"github.com/standrze/chorus/pkg/tools"
+	"github.com/standrze/chorus/pkg/redact"
 )
 
-type App struct {
-	client client.Client
-	agents []*chorus.Agent
-}
-
-type AgentConfig struct {
-	Name  string `mapstructure:"name"`
-	Model string `mapstructure:"model"`
-	//ReasoningEffort openai.ReasoningEffort `mapstructure:"reasoning_effort"`
-	SystemMessage string `mapstructure:"system_message"`
-}
-
-type Config struct {
-	BaseURL    string            `mapstructure:"base_url"`
-	APIKey     string            `mapstructure:"api_key"`
-	Agents     []AgentConfig     `mapstructure:"agents"`
-	MCPServers []MCPServerConfig `mapstructure:"mcp_servers"`
-	Debug      bool              `mapstructure:"-"`
-}
+const version = "0.1.0"
 
-type MCPServerConfig struct {
-	Command string   `mapstructure:"command"`
-	Args    []string `mapstructure:"args"`
+type App struct {
+	client  client.Client
+	servers []*mcpServer
+	// failed holds why MCP servers that aren't in servers failed to connect.
+	failed map[string]error
+
+	// cfg is replaced when the config is reloaded, see WatchConfig.
+	cfgMu      sync.RWMutex
+	cfg        *Config
+	cfgVersion uint64
+
+	rootsMu       sync.Mutex
+	workspaceRoot *mcp.Root
+	// rootsHolder is the conversation whose run holds the root, if any, and
+	// rootsLease is full while it does.
+	rootsHolder string
+	rootsLease  chan struct{}
+
+	metrics *metrics
+	// redactor masks secrets in traces and transcripts; nil when redaction is off.
+	redactor *redact.Redactor
+	// watchers are called with each new conversation, see WatchConversations.
+	watchers []func(*chorus.Conversation)
+	// stopMetrics and stopTracing are set when metrics are served on an
+	// address of their own and when tracing is on.
+	stopMetrics func(context.Context) error
+	stopTracing func(context.Context) error
 }
 
-func Start(cfg *Config) error {
-	app := &App{}
-
-	app.client = client.NewClient(cfg.BaseURL)
-
-	ctx := context.Background()
+// New validates the config, creates the model client, starts tracing and
+// serving metrics if they are configured and connects to the configured MCP
+// servers. Call Close to shut them all down.
+func New(ctx context.Context, cfg *Config) (*App, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
 
-	// Initialize MCP Servers and fetch tools
-	var mcpFuncTools []tools.FunctionTool
-
-	for _, mcpCfg := range cfg.MCPServers {
-		// Create a new client
-		client := mcp.NewClient(&mcp.Implementation{
-			Name:    "chorus",
-			Version: "0.1.0",
-		}, nil)
-
-		// Connect to a server over stdin/stdout.
-		transport := &mcp.CommandTransport{
-			Command: exec.Command(mcpCfg.Command, mcpCfg.Args...),
-		}
+	m := newMetrics()
+	app := &App{
+		cfg:      cfg,
+		client:   client.NewClient(cfg.BaseURL, cfg.APIKey, option.WithMiddleware(m.countRetries)),
+		metrics:  m,
+		redactor: cfg.Redactor(),
+	}
 
-		session, err := client.Connect(ctx, transport, nil)
+	if cfg.Tracing.File != "" {
+		stop, err := startTracing(cfg.Tracing, app.redactor)
 		if err != nil {
-			log.Printf("Failed to connect to MCP server %s: %v", mcpCfg.Command, err)
-			continue
+			return nil, err
 		}
-		// Note: we can't easily defer session.Close() here inside a loop if we want the tools to work later.
-		// We probably need to keep the sessions alive.
-		// For this simple implementation, we'll let them run until the app exits.
-		// A proper implementation might store these sessions in the App struct and Close them on shutdown.
-
-		listToolsRes, err := session.ListTools(ctx, nil)
+		app.stopTracing = stop
+	}
+	if cfg.Metrics.Addr != "" {
+		stop, err := m.serve(cfg.Metrics.Addr)
 		if err != nil {
-			log.Printf("Failed to list tools from MCP server %s: %v", mcpCfg.Command, err)
-			continue
+			app.Close()
+			return nil, err
 		}
+		app.stopMetrics = stop
+	}
 
-		for _, t := range listToolsRes.Tools {
-			// Capture session and name for closure
-			toolName := t.Name
-			mcpSession := session
-
-			// Define the wrapper function
-			wrapper := func(args json.RawMessage) (string, error) {
-				// unmarshal args to map[string]interface{}
-				var argsMap map[string]interface{}
-				if err := json.Unmarshal(args, &argsMap); err != nil {
-					return "", fmt.Errorf("invalid arguments: %v", err)
-				}
-
-				callParams := &mcp.CallToolParams{
-					Name:      toolName,
-					Arguments: argsMap,
-				}
-
-				res, err := mcpSession.CallTool(ctx, callParams)
-				if err != nil {
-					return "", err
-				}
-
-				if res.IsError {
-					return "", fmt.Errorf("tool execution failed")
-				}
-
-				// Combine content
-				var sb string
-				for _, c := range res.Content {
-					if textContent, ok := c.(*mcp.TextContent); ok {
-						sb += textContent.Text + "\n"
-					}
-				}
-				return sb, nil
-			}
-
-			// Convert InputSchema (ToolInputSchema object) to openai.FunctionParameters
-			// The SDK's ToolInputSchema likely has a structure we can marshal to JSON and then unmarshal?
-			// Or we can just inspect it.
-			// Let's assume it's compatible or we can marshal/unmarshal.
-
-			schemaBytes, err := json.Marshal(t.InputSchema)
-			if err != nil {
-				log.Printf("Failed to marshal schema for tool %s: %v", t.Name, err)
-				continue
-			}
-
-			var params openai.FunctionParameters
-			if err := json.Unmarshal(schemaBytes, &params); err != nil {
-				log.Printf("Failed to parse schema for tool %s: %v", t.Name, err)
-				continue
-			}
-
-			mcpFuncTools = append(mcpFuncTools, tools.FunctionTool{
-				Name:        t.Name,
-				Description: t.Description,
-				Parameters:  params,
-				Func:        wrapper,
-			})
-		}
+	// Initialize MCP Servers and fetch tools
+	if err := app.connectMCPServers(ctx); err != nil {
+		app.Close()
+		return nil, err
+	}
+	if errs := app.validateTools(cfg); len(errs) > 0 {
+		app.Close()
+		return nil, fmt.Errorf("invalid config:\n%w", errs)
 	}
 
-	for _, agentCfg := range cfg.Agents {
-		agentOpts := []func(*chorus.Agent){
-			chorus.WithReasoningEffort(openai.ReasoningEffortMedium),
-			chorus.WithFunctionTools(mcpFuncTools...),
-		}
+	return app, nil
+}
+
+// config returns the current config. It must be treated as read-only.
+func (app *App) config() *Config {
+	cfg, _ := app.configVersion()
+	return cfg
+}
+
+// configVersion returns the current config and how many times it has been reloaded.
+func (app *App) configVersion() (*Config, uint64) {
+	app.cfgMu.RLock()
+	defer app.cfgMu.RUnlock()
+	return app.cfg, app.cfgVersion
+}
 
-		if agentCfg.Name != "" {
-			agentOpts = append(agentOpts, chorus.WithName(agentCfg.Name))
+// Close shuts down every MCP session opened by New, stops serving metrics
+// and flushes the traces.
+func (app *App) Close() error {
+	var firstErr error
+	for _, server := range app.servers {
+		if err := server.session.Close(); err != nil && firstErr == nil {
+			firstErr = err
 		}
-		if agentCfg.Model != "" {
-			agentOpts = append(agentOpts, chorus.WithModel(agentCfg.Model))
+	}
+	if app.stopMetrics != nil {
+		if err := app.stopMetrics(context.Background()); err != nil && firstErr == nil {
+			firstErr = err
 		}
-		if agentCfg.SystemMessage != "" {
-			agentOpts = append(agentOpts, chorus.WithSystemMessage(agentCfg.SystemMessage))
+	}
+	if app.stopTracing != nil {
+		if err := app.stopTracing(context.Background()); err != nil && firstErr == nil {
+			firstErr = err
 		}
+	}
+	return firstErr
+}
+
+func Start(cfg *Config) error {
+	ctx := context.Background()
+
+	app, err := New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
 
-		agent := chorus.NewAgent(app.client, agentOpts...)
-		app.agents = append(app.agents, agent)
+	agents, err := app.NewAgents()
+	if err != nil {
+		return err
 	}
 
-	for _, agent := range app.agents {
+	for _, agent := range agents {
 		fmt.Printf("Agent: %s\n", agent.Name)
 		result, err := agent.Generate(ctx, chorus.WithUserMessage("Hello, how are you?"))
 		if err != nil {
@@ -0,0 +1,377 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+const chatHelp = `Commands:
+  /reset            clear the conversation, keeping the system message
+  /save <file>      save the conversation to a JSON file
+  /load <file>      load a conversation saved with /save, with its agent
+  /system [text]    show or replace the system message
+  /model [name]     show or change the model
+  /plan             show the plan the agents last worked on
+  /tools            list the agent's tools
+  /agents           list the configured agents
+  /switch <name>    talk to another agent
+  /help             show this help
+  /exit             leave the chat`
+
+// Chat is an interactive session with the configured agents. Each agent
+// keeps its own history for the lifetime of the session, and picks up config
+// reloads between turns.
+type Chat struct {
+	app     *App
+	agents  []*chorus.Agent
+	current *chorus.Agent
+	in      *bufio.Reader
+	out     io.Writer
+
+	// version is the config version the agents were last refreshed from.
+	version uint64
+	// models and systems hold what /model and /system set, by agent, so they
+	// survive config reloads.
+	models  map[*chorus.Agent]string
+	systems map[*chorus.Agent]string
+
+	// plan is the latest plan the agents made or updated, kept in /save.
+	plan *chorus.Plan
+
+	// streamed records whether the current reply was printed as it arrived.
+	streamed bool
+	// unsubscribe stops printing the agents' events, see Quiet.
+	unsubscribe []func()
+}
+
+// savedChat is the file format used by /save and /load.
+type savedChat struct {
+	Agent    string                                   `json:"agent"`
+	Model    string                                   `json:"model"`
+	Messages []openai.ChatCompletionMessageParamUnion `json:"messages"`
+	Plan     *chorus.Plan                             `json:"plan,omitempty"`
+}
+
+// NewChat starts a chat with the named agent, or the first configured agent
+// if name is empty. With no agents configured a default one is created.
+func (app *App) NewChat(name string, in io.Reader, out io.Writer) (*Chat, error) {
+	_, version := app.configVersion()
+	agents, err := app.NewAgents()
+	if err != nil {
+		return nil, err
+	}
+	if len(agents) == 0 {
+		agent, err := app.NewAgent(AgentConfig{})
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+
+	c := &Chat{
+		app:     app,
+		agents:  agents,
+		in:      bufio.NewReader(in),
+		out:     out,
+		version: version,
+		models:  make(map[*chorus.Agent]string),
+		systems: make(map[*chorus.Agent]string),
+	}
+
+	for _, agent := range c.agents {
+		c.unsubscribe = append(c.unsubscribe, agent.Events.Subscribe(c.printEvent))
+		agent.Events.Subscribe(c.trackPlan)
+	}
+
+	c.current = c.agents[0]
+	if name != "" {
+		c.current = c.find(name)
+		if c.current == nil {
+			return nil, fmt.Errorf("agent %q not found", name)
+		}
+	}
+
+	return c, nil
+}
+
+// Agents returns the agents the chat can talk to.
+func (c *Chat) Agents() []*chorus.Agent {
+	return c.agents
+}
+
+// Quiet stops the chat printing replies as they stream in and the tools
+// agents call, for when something else shows them. Replies are printed
+// whole once they are complete.
+func (c *Chat) Quiet() {
+	for _, stop := range c.unsubscribe {
+		stop()
+	}
+	c.unsubscribe = nil
+}
+
+// Run reads lines until EOF or /exit, sending each to the current agent.
+func (c *Chat) Run(ctx context.Context) error {
+	fmt.Fprintf(c.out, "Chatting with %s. Type /help for commands.\n", c.current.Name)
+
+	for {
+		fmt.Fprintf(c.out, "%s> ", c.current.Name)
+
+		line, err := c.in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if line == "" && errors.Is(err, io.EOF) {
+			fmt.Fprintln(c.out)
+			return nil
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		c.refresh()
+		switch {
+		case strings.HasPrefix(line, "/"):
+			quit, cmdErr := c.command(line)
+			if cmdErr != nil {
+				fmt.Fprintf(c.out, "error: %v\n", cmdErr)
+			}
+			if quit {
+				return nil
+			}
+		default:
+			c.send(ctx, line)
+		}
+	}
+}
+
+// refresh applies a reloaded config to the agents. Changes made with /model
+// and /system are kept over the config's settings.
+func (c *Chat) refresh() {
+	version := c.version
+	for _, agent := range c.agents {
+		v, err := c.app.refreshAgent(agent, c.version)
+		if err != nil {
+			fmt.Fprintf(c.out, "error: failed to reload %s: %v\n", agent.Name, err)
+			continue
+		}
+		version = v
+		if model, ok := c.models[agent]; ok {
+			agent.Model = model
+		}
+		if system, ok := c.systems[agent]; ok {
+			agent.SetSystemMessage(system)
+		}
+	}
+	if version != c.version {
+		c.version = version
+		fmt.Fprintln(c.out, "Config reloaded.")
+	}
+}
+
+func (c *Chat) send(ctx context.Context, line string) {
+	c.streamed = false
+
+	reply, err := c.current.Respond(ctx, chorus.WithUserMessage(line))
+	if err != nil {
+		fmt.Fprintf(c.out, "\nerror: %v\n", err)
+		return
+	}
+
+	if !c.streamed {
+		fmt.Fprint(c.out, reply)
+	}
+	fmt.Fprintln(c.out)
+}
+
+// command runs a slash command and reports whether the chat should end.
+func (c *Chat) command(line string) (bool, error) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "/exit", "/quit":
+		return true, nil
+	case "/help":
+		fmt.Fprintln(c.out, chatHelp)
+	case "/reset":
+		c.current.Reset()
+		fmt.Fprintln(c.out, "Conversation cleared.")
+	case "/save":
+		return false, c.save(arg)
+	case "/load":
+		return false, c.load(arg)
+	case "/system":
+		if arg == "" {
+			fmt.Fprintln(c.out, systemMessageOf(c.current))
+			return false, nil
+		}
+		c.current.SetSystemMessage(arg)
+		c.systems[c.current] = arg
+		fmt.Fprintln(c.out, "System message updated.")
+	case "/model":
+		if arg == "" {
+			fmt.Fprintln(c.out, c.current.Model)
+			return false, nil
+		}
+		c.current.Model = arg
+		c.models[c.current] = arg
+		fmt.Fprintf(c.out, "Model set to %s.\n", arg)
+	case "/plan":
+		if c.plan == nil {
+			fmt.Fprintln(c.out, "No plan.")
+			return false, nil
+		}
+		fmt.Fprintln(c.out, c.plan)
+	case "/tools":
+		if len(c.current.Tools) == 0 {
+			fmt.Fprintln(c.out, "No tools.")
+		}
+		for _, t := range c.current.Tools {
+			if t.OfFunction != nil {
+				fmt.Fprintf(c.out, "  %s - %s\n", t.OfFunction.Function.Name, t.OfFunction.Function.Description.Value)
+			}
+		}
+	case "/agents":
+		for _, agent := range c.agents {
+			marker := " "
+			if agent == c.current {
+				marker = "*"
+			}
+			fmt.Fprintf(c.out, "%s %s (%s, %s)\n", marker, agent.Name, agent.Role, agent.Model)
+		}
+	case "/switch":
+		agent := c.find(arg)
+		if agent == nil {
+			return false, fmt.Errorf("agent %q not found", arg)
+		}
+		c.current = agent
+		fmt.Fprintf(c.out, "Now chatting with %s.\n", agent.Name)
+	default:
+		return false, fmt.Errorf("unknown command %s (try /help)", name)
+	}
+	return false, nil
+}
+
+func (c *Chat) save(path string) error {
+	if path == "" {
+		return fmt.Errorf("usage: /save <file>")
+	}
+
+	// Checkpoints are redacted like transcripts.
+	data, err := c.app.redactor.Marshal(savedChat{
+		Agent:    c.current.Name,
+		Model:    c.current.Model,
+		Messages: c.current.Messages,
+		Plan:     c.plan,
+	})
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, indented.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Saved %d messages to %s.\n", len(c.current.Messages), path)
+	return nil
+}
+
+func (c *Chat) load(path string) error {
+	if path == "" {
+		return fmt.Errorf("usage: /load <file>")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var saved savedChat
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("invalid chat file: %w", err)
+	}
+
+	// The history belongs to the agent that had it.
+	if saved.Agent != "" && saved.Agent != c.current.Name {
+		agent := c.find(saved.Agent)
+		if agent == nil {
+			return fmt.Errorf("%s was saved from agent %q, which is not configured", path, saved.Agent)
+		}
+		c.current = agent
+		fmt.Fprintf(c.out, "Now chatting with %s.\n", agent.Name)
+	}
+
+	c.current.Messages = saved.Messages
+	if saved.Plan != nil {
+		c.plan = saved.Plan
+	}
+	if saved.Model != "" {
+		c.current.Model = saved.Model
+		c.models[c.current] = saved.Model
+	}
+
+	fmt.Fprintf(c.out, "Loaded %d messages from %s.\n", len(saved.Messages), path)
+	return nil
+}
+
+func (c *Chat) find(name string) *chorus.Agent {
+	for _, agent := range c.agents {
+		if agent.Name == name {
+			return agent
+		}
+	}
+	return nil
+}
+
+// trackPlan keeps the latest plan the agents made or updated.
+func (c *Chat) trackPlan(ev chorus.Event) {
+	if (ev.Type == chorus.EventPlanCreated || ev.Type == chorus.EventPlanUpdated) && ev.Plan != nil {
+		c.plan = ev.Plan
+	}
+}
+
+// printEvent shows replies as they stream in and the tools the agent calls.
+func (c *Chat) printEvent(ev chorus.Event) {
+	switch ev.Type {
+	case chorus.EventDelta:
+		c.streamed = true
+		fmt.Fprint(c.out, ev.Text)
+	case chorus.EventToolCall:
+		fmt.Fprintf(c.out, "\n[tool] %s %s\n", ev.Tool, ev.Arguments)
+	case chorus.EventToolResult:
+		if ev.Error != "" {
+			fmt.Fprintf(c.out, "[tool] %s failed: %s\n", ev.Tool, ev.Error)
+			return
+		}
+		fmt.Fprintf(c.out, "[tool] %s -> %s\n", ev.Tool, truncate(ev.Text, 200))
+	}
+}
+
+func systemMessageOf(agent *chorus.Agent) string {
+	if len(agent.Messages) > 0 && agent.Messages[0].OfSystem != nil {
+		return agent.Messages[0].OfSystem.Content.OfString.Value
+	}
+	return "(no system message)"
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+func newTestChat(t *testing.T, input string) (*Chat, *strings.Builder, *stubClient) {
+	t.Helper()
+
+	stub := &stubClient{reply: "hi there"}
+	app := &App{
+		cfg: &Config{Agents: []AgentConfig{
+			{Name: "Teacher", SystemMessage: "You teach."},
+			{Name: "Professor"},
+		}},
+		client: stub,
+	}
+
+	var out strings.Builder
+	chat, err := app.NewChat("", strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("NewChat failed: %v", err)
+	}
+	return chat, &out, stub
+}
+
+func TestChat_KeepsHistory(t *testing.T) {
+	chat, out, stub := newTestChat(t, "hello\nagain\n")
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(stub.params) != 2 {
+		t.Fatalf("Expected 2 completions, got %d", len(stub.params))
+	}
+	// system, user, assistant, user
+	if n := len(stub.params[1].Messages); n != 4 {
+		t.Errorf("Expected history to carry over, second request had %d messages", n)
+	}
+	if !strings.Contains(out.String(), "hi there") {
+		t.Errorf("Reply not printed: %s", out.String())
+	}
+}
+
+func TestChat_Commands(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chat.json")
+	input := strings.Join([]string{
+		"hello",
+		"/save " + file,
+		"/reset",
+		"/system Be terse.",
+		"/load " + file,
+		"/switch Professor",
+		"/model local/other",
+		"/bogus",
+		"/exit",
+		"never sent",
+	}, "\n")
+
+	chat, out, stub := newTestChat(t, input)
+	teacher := chat.current
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(stub.params) != 1 {
+		t.Errorf("Expected only one completion, got %d", len(stub.params))
+	}
+	if len(teacher.Messages) != 3 {
+		t.Errorf("Expected /load to restore 3 messages, got %d", len(teacher.Messages))
+	}
+	if chat.current.Name != "Professor" || chat.current.Model != "local/other" {
+		t.Errorf("Expected to switch to Professor with new model, got %s (%s)", chat.current.Name, chat.current.Model)
+	}
+	if !strings.Contains(out.String(), "unknown command /bogus") {
+		t.Errorf("Expected unknown command error: %s", out.String())
+	}
+}
+
+func TestChat_SaveRedacts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chat.json")
+	chat, _, _ := newTestChat(t, "mail bob@example.com\n/save "+file+"\n")
+	chat.app.redactor = redact.Default()
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "bob@example.com") || !strings.Contains(string(data), "mail [REDACTED]") {
+		t.Errorf("Expected the email to be redacted, got %s", data)
+	}
+}
+
+func TestChat_SavesPlan(t *testing.T) {
+	dir := t.TempDir()
+	saved := filepath.Join(dir, "saved.json")
+	plan := `{"agent":"Teacher","messages":[],"plan":{"revision":1,"steps":[{"id":"1","description":"outline","status":"done"}]}}`
+	if err := os.WriteFile(saved, []byte(plan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resaved := filepath.Join(dir, "resaved.json")
+	chat, out, _ := newTestChat(t, "/load "+saved+"\n/plan\n/save "+resaved+"\n")
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "1. outline") {
+		t.Errorf("Expected /plan to show the loaded plan, got %s", out)
+	}
+	data, _ := os.ReadFile(resaved)
+	if !strings.Contains(string(data), `"description": "outline"`) {
+		t.Errorf("Expected the plan in the checkpoint, got %s", data)
+	}
+}
+
+func TestChat_LoadSwitchesAgent(t *testing.T) {
+	dir := t.TempDir()
+	professor := filepath.Join(dir, "professor.json")
+	if err := os.WriteFile(professor, []byte(`{"agent":"Professor","messages":[{"role":"user","content":"grade this"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stranger := filepath.Join(dir, "stranger.json")
+	if err := os.WriteFile(stranger, []byte(`{"agent":"Stranger","messages":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chat, out, _ := newTestChat(t, "/load "+professor+"\n/load "+stranger+"\n")
+	teacher := chat.current
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if chat.current.Name != "Professor" || len(chat.current.Messages) != 1 {
+		t.Errorf("Expected the Professor's history loaded into the Professor, got %s with %d messages", chat.current.Name, len(chat.current.Messages))
+	}
+	if len(teacher.Messages) != 1 {
+		t.Errorf("Expected the Teacher's history untouched, got %d messages", len(teacher.Messages))
+	}
+	if !strings.Contains(out.String(), `agent "Stranger", which is not configured`) {
+		t.Errorf("Expected a history from an unknown agent to be refused, got %s", out)
+	}
+}
+
+func TestChat_ResetKeepsSystemMessage(t *testing.T) {
+	chat, _, _ := newTestChat(t, "hello\n/system Be terse.\n/reset\n")
+
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	msgs := chat.current.Messages
+	if len(msgs) != 1 || msgs[0].OfSystem == nil || msgs[0].OfSystem.Content.OfString.Value != "Be terse." {
+		t.Errorf("Expected only the replaced system message, got %d messages", len(msgs))
+	}
+}
+
+func TestChat_KeepsOverridesOnReload(t *testing.T) {
+	chat, _, _ := newTestChat(t, "/model local/other\n/system Be terse.\n")
+	teacher := chat.current
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	temperature := 0.5
+	cfg := *chat.app.cfg
+	cfg.Agents = []AgentConfig{{Name: "Teacher", Model: "model-b", SystemMessage: "You teach tersely.", Temperature: &temperature}}
+	chat.app.cfg = &cfg
+	chat.app.cfgVersion++
+
+	chat.in = bufio.NewReader(strings.NewReader("hello\n"))
+	if err := chat.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if teacher.Model != "local/other" || systemMessageOf(teacher) != "Be terse." {
+		t.Errorf("Expected /model and /system to survive the reload, got %s / %q", teacher.Model, systemMessageOf(teacher))
+	}
+	if !teacher.Temperature.Valid() || teacher.Temperature.Value != 0.5 {
+		t.Errorf("Expected the rest of the reloaded config, got %+v", teacher.Temperature)
+	}
+}
+
+func TestNewChat_UnknownAgent(t *testing.T) {
+	app := &App{cfg: &Config{}, client: &stubClient{}}
+	if _, err := app.NewChat("Nobody", strings.NewReader(""), &strings.Builder{}); err == nil {
+		t.Error("Expected error for unknown agent")
+	}
+}
@@ -0,0 +1,312 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/log"
+)
+
+// TeamModelPrefix turns a team name into the model name the OpenAI-compatible
+// endpoint offers it as.
+const TeamModelPrefix = "chorus/"
+
+type chatCompletionRequest struct {
+	Model         string               `json:"model"`
+	Messages      []chatRequestMessage `json:"messages"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type chatRequestMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int          `json:"index"`
+	Message      *chatMessage `json:"message,omitempty"`
+	Delta        *chatDelta   `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatDelta struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+type chatUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+type modelList struct {
+	Object string      `json:"object"`
+	Data   []modelInfo `json:"data"`
+}
+
+type modelInfo struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// listModels lists the configured teams as models.
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	models := modelList{Object: "list", Data: []modelInfo{}}
+	for _, team := range s.app.config().Teams {
+		models.Data = append(models.Data, modelInfo{ID: TeamModelPrefix + team.Name, Object: "model", OwnedBy: "chorus"})
+	}
+	writeJSON(w, http.StatusOK, models)
+}
+
+// chatCompletions answers an OpenAI chat completion request by running the
+// team named by the model, with the last user message as the objective. The
+// reply is the result the orchestrator finished with.
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body: %v", err)
+		return
+	}
+
+	name, ok := strings.CutPrefix(req.Model, TeamModelPrefix)
+	teams := s.app.config().Teams
+	i := slices.IndexFunc(teams, func(t TeamConfig) bool { return t.Name == name })
+	if !ok || i < 0 {
+		writeOpenAIError(w, http.StatusNotFound, "model_not_found", "model %q does not name a configured team", req.Model)
+		return
+	}
+
+	objective, err := lastUserMessage(req.Messages)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "%v", err)
+		return
+	}
+
+	agents, err := s.app.newAgentsNamed(teams[i].Agents)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "%v", err)
+		return
+	}
+	// The run ends if the client goes away.
+	conv, err := s.app.newLimitedConversation(r.Context(), agents...)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "%v", err)
+		return
+	}
+	defer conv.Close()
+	log.Info("Running team", "team", name, "conversation", conv.ID(), "workspace", conv.Workspace())
+
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + conv.ID(),
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+	if req.Stream {
+		s.streamCompletion(w, r, conv, agents, objective, completion, req.StreamOptions != nil && req.StreamOptions.IncludeUsage)
+		return
+	}
+
+	result, err := s.app.run(r.Context(), conv, objective)
+	if err != nil {
+		log.Error("Team run failed", "team", name, "conversation", conv.ID(), "error", err)
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "conversation %s: %v", conv.ID(), err)
+		return
+	}
+
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []chatChoice{{
+		Message:      &chatMessage{Role: "assistant", Content: result},
+		FinishReason: &stop,
+	}}
+	completion.Usage = usageOf(agents)
+	data, err := s.app.redactor.Marshal(completion)
+	if err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "failed to encode completion: %v", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, json.RawMessage(data))
+}
+
+// streamCompletion runs the conversation and streams its result as chat
+// completion chunks. The result only exists once the orchestrator finishes,
+// so until then the agents' replies stream as reasoning_content, each agent's
+// introduced by its name, as clients show a model's thinking.
+func (s *Server) streamCompletion(w http.ResponseWriter, r *http.Request, conv *chorus.Conversation, agents []*chorus.Agent, objective string, chunk chatCompletion, includeUsage bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	chunk.Object = "chat.completion.chunk"
+	send := func(choices []chatChoice, usage *chatUsage) {
+		chunk.Choices = choices
+		chunk.Usage = usage
+		data, _ := s.app.redactor.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	}
+	send([]chatChoice{{Delta: &chatDelta{Role: "assistant"}}}, nil)
+
+	// The agents publish on the run's goroutine, so their deltas are handed
+	// over to be written here.
+	deltas := make(chan chorus.Event, 64)
+	unsubscribe := conv.Events().Subscribe(func(ev chorus.Event) {
+		if ev.Type == chorus.EventDelta {
+			deltas <- ev
+		}
+	})
+	defer unsubscribe()
+	var speaker string
+	think := func(ev chorus.Event) {
+		text := ev.Text
+		if ev.Agent != speaker {
+			text = ev.Agent + ": " + text
+			if speaker != "" {
+				text = "\n\n" + text
+			}
+			speaker = ev.Agent
+		}
+		send([]chatChoice{{Delta: &chatDelta{ReasoningContent: text}}}, nil)
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := s.app.run(r.Context(), conv, objective)
+		done <- outcome{result, err}
+	}()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	var out outcome
+wait:
+	for {
+		select {
+		case out = <-done:
+			break wait
+		case ev := <-deltas:
+			think(ev)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+	// Run has returned, so no more deltas are coming, but some may be queued.
+	for len(deltas) > 0 {
+		think(<-deltas)
+	}
+
+	if out.err != nil {
+		log.Error("Team run failed", "model", chunk.Model, "conversation", conv.ID(), "error", out.err)
+		data, _ := s.app.redactor.Marshal(openAIError(fmt.Sprintf("conversation %s: %v", conv.ID(), out.err), "server_error"))
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+		return
+	}
+
+	stop := "stop"
+	send([]chatChoice{{Delta: &chatDelta{Content: out.result}}}, nil)
+	send([]chatChoice{{Delta: &chatDelta{}, FinishReason: &stop}}, nil)
+	if includeUsage {
+		send([]chatChoice{}, usageOf(agents))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// lastUserMessage returns the text of the last user message.
+func lastUserMessage(messages []chatRequestMessage) (string, error) {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		text, err := messages[i].text()
+		if err != nil {
+			return "", fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		if strings.TrimSpace(text) == "" {
+			return "", fmt.Errorf("messages[%d]: the objective is empty", i)
+		}
+		return text, nil
+	}
+	return "", errors.New("no user message to use as the objective")
+}
+
+// text returns the message content, joining the text parts of multi-part
+// content.
+func (m chatRequestMessage) text() (string, error) {
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return text, nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of content parts")
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+func usageOf(agents []*chorus.Agent) *chatUsage {
+	usage := &chatUsage{}
+	for _, a := range agents {
+		usage.PromptTokens += a.PromptTokens
+		usage.CompletionTokens += a.CompletionTokens
+		usage.TotalTokens += a.TotalTokens
+	}
+	return usage
+}
+
+func openAIError(message, typ string) map[string]any {
+	return map[string]any{"error": map[string]string{"message": message, "type": typ}}
+}
+
+// writeOpenAIError replies with an error in the shape OpenAI clients expect.
+func writeOpenAIError(w http.ResponseWriter, code int, typ, format string, args ...any) {
+	writeJSON(w, code, openAIError(fmt.Sprintf(format, args...), typ))
+}
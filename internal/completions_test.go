@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/standrze/chorus/pkg/client"
+)
+
+func TestChatCompletions(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{Token: "secret"})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	// Another chorus instance talks to the team like any model.
+	c := client.NewClient(ts.URL+"/v1", "secret")
+	params := openai.ChatCompletionNewParams{
+		Model: "chorus/review",
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage("ignored"),
+			openai.UserMessage("first"),
+			openai.AssistantMessage("ok"),
+			openai.UserMessage("teach photosynthesis"),
+		},
+	}
+
+	completion, err := c.ChatCompletion(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := completion.Choices[0].Message.Content; got != "done" {
+		t.Errorf("Expected the Finish result as the reply, got %q", got)
+	}
+	if completion.Usage.TotalTokens != 7 {
+		t.Errorf("Expected the team's token usage, got %d", completion.Usage.TotalTokens)
+	}
+
+	var streamed strings.Builder
+	completion, err = c.ChatCompletionStream(context.Background(), params, func(chunk openai.ChatCompletionChunk) {
+		if len(chunk.Choices) > 0 {
+			streamed.WriteString(chunk.Choices[0].Delta.Content)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != "done" || completion.Choices[0].Message.Content != "done" {
+		t.Errorf("Expected the result streamed, got %q / %q", streamed.String(), completion.Choices[0].Message.Content)
+	}
+	if completion.Usage.TotalTokens != 7 {
+		t.Errorf("Expected usage in the stream, got %d", completion.Usage.TotalTokens)
+	}
+}
+
+// streamingFinishClient streams a thought before making the orchestrator
+// finish.
+type streamingFinishClient struct {
+	finishClient
+}
+
+func (c *streamingFinishClient) ChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(openai.ChatCompletionChunk)) (*openai.ChatCompletion, error) {
+	for _, text := range []string{"Let me ", "finish."} {
+		onChunk(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{
+			Delta: openai.ChatCompletionChunkChoiceDelta{Content: text},
+		}}})
+	}
+	return c.ChatCompletion(ctx, params)
+}
+
+func TestChatCompletions_StreamsDeltas(t *testing.T) {
+	s := newTestServer(t, &streamingFinishClient{}, ServerOptions{})
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	var reasoning, content strings.Builder
+	c := client.NewClient(ts.URL+"/v1", "")
+	_, err := c.ChatCompletionStream(context.Background(), openai.ChatCompletionNewParams{
+		Model:    "chorus/review",
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage("teach")},
+	}, func(chunk openai.ChatCompletionChunk) {
+		if len(chunk.Choices) == 0 {
+			return
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if field, ok := chunk.Choices[0].Delta.JSON.ExtraFields["reasoning_content"]; ok {
+			var text string
+			if err := json.Unmarshal([]byte(field.Raw()), &text); err != nil {
+				t.Errorf("Unexpected reasoning_content %s", field.Raw())
+			}
+			reasoning.WriteString(text)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reasoning.String() != "Teacher: Let me finish." || content.String() != "done" {
+		t.Errorf("Expected the orchestrator's deltas as reasoning and the result as content, got %q / %q", reasoning.String(), content.String())
+	}
+}
+
+func TestChatCompletions_Errors(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"model":"chorus/missing","messages":[{"role":"user","content":"hi"}]}`, http.StatusNotFound},
+		{`{"model":"review","messages":[{"role":"user","content":"hi"}]}`, http.StatusNotFound},
+		{`{"model":"chorus/review","messages":[{"role":"system","content":"hi"}]}`, http.StatusBadRequest},
+		{`{"model":"chorus/review","messages":[{"role":"user","content":42}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if code := request(t, s, "POST", "/v1/chat/completions", tt.body, &body); code != tt.want || body.Error.Message == "" {
+			t.Errorf("%s: expected %d with a message, got %d %+v", tt.body, tt.want, code, body)
+		}
+	}
+
+	var models modelList
+	request(t, s, "GET", "/v1/models", "", &models)
+	if len(models.Data) != 1 || models.Data[0].ID != "chorus/review" {
+		t.Errorf("Expected the team as a model, got %+v", models)
+	}
+}
+
+func TestLastUserMessage_Parts(t *testing.T) {
+	text, err := lastUserMessage([]chatRequestMessage{
+		{Role: "user", Content: []byte(`[{"type":"text","text":"one"},{"type":"image_url"},{"type":"text","text":"two"}]`)},
+	})
+	if err != nil || text != "one\ntwo" {
+		t.Errorf("Expected the text parts joined, got %q, %v", text, err)
+	}
+}
@@ -0,0 +1,149 @@
+package internal
+
+type AgentConfig struct {
+	Name  string `mapstructure:"name"`
+	Model string `mapstructure:"model"`
+	// Role is "orchestrator" for the agent that drives a conversation; anything else is a worker.
+	Role string `mapstructure:"role"`
+	// ReasoningEffort is one of none, minimal, low, medium, high or xhigh. Defaults to medium.
+	ReasoningEffort string   `mapstructure:"reasoning_effort"`
+	Seed            *int64   `mapstructure:"seed"`
+	Temperature     *float64 `mapstructure:"temperature"`
+	TopP            *float64 `mapstructure:"top_p"`
+	MaxTokens       int64    `mapstructure:"max_tokens"`
+	Stop            []string `mapstructure:"stop"`
+	// ResponseFormat constrains replies to plain text, any JSON object or a JSON schema.
+	ResponseFormat *ResponseFormatConfig `mapstructure:"response_format"`
+	// MCPServers limits the agent to tools from the named servers; empty means all servers.
+	MCPServers []string `mapstructure:"mcp_servers"`
+	// Tools limits the agent to the named MCP tools; empty means every tool of its servers.
+	// The built-in tools a conversation gives its agents, such as the file
+	// tools and DelegateTask, are not filtered.
+	Tools   []string      `mapstructure:"tools"`
+	Context ContextConfig `mapstructure:"context"`
+	// SystemMessage and the contents of SystemMessageFile are Go templates, see systemMessageData.
+	// A relative SystemMessageFile is found next to the config file that sets it.
+	SystemMessage     string `mapstructure:"system_message"`
+	SystemMessageFile string `mapstructure:"system_message_file"`
+	// Vars are made available to the system message template as .Vars. Their
+	// keys are case-insensitive: config keys are read lowercased, and so are
+	// the keys the template looks up.
+	Vars map[string]any `mapstructure:"vars"`
+}
+
+// Response formats.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+type ResponseFormatConfig struct {
+	// Type is text, json_object or json_schema.
+	Type string `mapstructure:"type"`
+	// Name and SchemaFile describe the schema for json_schema. The schema is
+	// read from a file because config keys are case-folded; a relative path is
+	// found next to the config file that sets it.
+	Name       string `mapstructure:"name"`
+	SchemaFile string `mapstructure:"schema_file"`
+	Strict     bool   `mapstructure:"strict"`
+}
+
+type ContextConfig struct {
+	// MaxMessages sends only the most recent messages with each request; zero sends the full history.
+	MaxMessages int `mapstructure:"max_messages"`
+}
+
+// StrategyConfig chooses how the agents of a conversation take turns.
+type StrategyConfig struct {
+	// Type is orchestrator (the default), round_robin, group_chat, pipeline
+	// or debate.
+	Type string `mapstructure:"type"`
+	// Agents speak in this order, or for group_chat are who the orchestrator
+	// chooses from. Empty means every worker, in config order.
+	Agents []string `mapstructure:"agents"`
+	// Rounds is how many times each agent speaks in round_robin, zero meaning
+	// 3, or how many times the agents revise their answers in a debate, zero
+	// meaning 2.
+	Rounds int `mapstructure:"rounds"`
+	// Judge names the agent that decides a debate; empty means a majority vote.
+	Judge string `mapstructure:"judge"`
+}
+
+// TeamConfig groups agents that chorus serve offers as a single model,
+// chorus/<name>, on its OpenAI-compatible endpoint.
+type TeamConfig struct {
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	// Agents names the team's agents: one orchestrator and at least one worker.
+	Agents []string `mapstructure:"agents"`
+}
+
+type Config struct {
+	BaseURL    string            `mapstructure:"base_url"`
+	APIKey     string            `mapstructure:"api_key"`
+	Agents     []AgentConfig     `mapstructure:"agents"`
+	Teams      []TeamConfig      `mapstructure:"teams"`
+	MCPServers []MCPServerConfig `mapstructure:"mcp_servers"`
+	Sampling   *SamplingConfig   `mapstructure:"sampling"`
+	// Roots are extra directories advertised to MCP servers alongside the conversation workspace.
+	Roots []string `mapstructure:"roots"`
+	// MaxTurns limits the turns of a conversation's run, one per orchestrator
+	// turn or, under other strategies, per reply; zero keeps the default.
+	MaxTurns int `mapstructure:"max_turns"`
+	// TokenBudget caps the tokens a conversation may use across all agents; zero means no limit.
+	TokenBudget int64 `mapstructure:"token_budget"`
+	// Strategy is how the agents of a conversation take turns.
+	Strategy StrategyConfig `mapstructure:"strategy"`
+	// Prices are what models cost, for the cost shown by --tui.
+	Prices []PriceConfig `mapstructure:"prices"`
+	// Tracing, Metrics, Log and Redaction are read once at startup; reloading
+	// the config doesn't change them.
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Log       LogConfig       `mapstructure:"log"`
+	Redaction RedactionConfig `mapstructure:"redaction"`
+	// Include lists config files merged in before this one, see LoadConfig.
+	Include []string `mapstructure:"include"`
+	// Profiles are named sets of overrides, selected with --profile.
+	Profiles map[string]any `mapstructure:"profiles"`
+	Debug    bool           `mapstructure:"-"`
+}
+
+type MCPServerConfig struct {
+	// Name identifies the server in logs and prompts; it defaults to Command.
+	Name    string   `mapstructure:"name"`
+	Command string   `mapstructure:"command"`
+	Args    []string `mapstructure:"args"`
+}
+
+func (c MCPServerConfig) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Command
+}
+
+// Sampling approval policies.
+const (
+	SamplingApprovalAuto   = "auto"
+	SamplingApprovalPrompt = "prompt"
+	SamplingApprovalDeny   = "deny"
+)
+
+// SamplingConfig enables answering MCP sampling/createMessage requests with a
+// local model. Without it, servers that request sampling are refused.
+type SamplingConfig struct {
+	// Agent names a configured agent whose model and system message are used.
+	Agent string `mapstructure:"agent"`
+	// Model is used when Agent is empty.
+	Model string `mapstructure:"model"`
+	// Approval is one of "auto", "prompt" (ask on the terminal) or "deny".
+	// Defaults to "prompt", which needs a terminal: without one, chorus
+	// refuses to start rather than deny every request.
+	Approval string `mapstructure:"approval"`
+	// MaxTokens caps the tokens sampled per request, whatever the server asks for.
+	MaxTokens int64 `mapstructure:"max_tokens"`
+	// MaxTotalTokens caps the tokens spent on sampling over the app's lifetime.
+	MaxTotalTokens int64 `mapstructure:"max_total_tokens"`
+}
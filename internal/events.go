@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment so
+// proxies don't close it.
+const keepAliveInterval = 15 * time.Second
+
+// eventLog keeps a conversation's events so clients can replay them from any
+// point. An agent's streamed deltas are only kept until the message they make
+// up arrives, so a replay has the message instead.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []chorus.Event
+	changed chan struct{} // closed when an event is added
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{changed: make(chan struct{})}
+}
+
+func (l *eventLog) append(ev chorus.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ev.Type == chorus.EventMessage || isFinal(ev) {
+		l.dropDeltas(ev)
+	}
+	l.events = append(l.events, ev)
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// dropDeltas removes the deltas ev makes redundant: the agent's for a
+// message, everyone's once the run ends. Readers may still hold the old slice,
+// so the kept events are copied rather than moved in place.
+func (l *eventLog) dropDeltas(ev chorus.Event) {
+	stale := func(e chorus.Event) bool {
+		return e.Type == chorus.EventDelta && (ev.Type != chorus.EventMessage || e.Agent == ev.Agent)
+	}
+	if !slices.ContainsFunc(l.events, stale) {
+		return
+	}
+	kept := make([]chorus.Event, 0, len(l.events))
+	for _, e := range l.events {
+		if !stale(e) {
+			kept = append(kept, e)
+		}
+	}
+	l.events = kept
+}
+
+// after returns the events numbered above seq, and a channel that is closed
+// when another event is added. ended reports whether the latest event ends a
+// run.
+func (l *eventLog) after(seq int64) (events []chorus.Event, changed <-chan struct{}, ended bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := len(l.events)
+	i := sort.Search(n, func(i int) bool { return l.events[i].Seq > seq })
+	ended = n > 0 && isFinal(l.events[n-1])
+	return l.events[i:n:n], l.changed, ended
+}
+
+// streamEvents sends a conversation's events as Server-Sent Events until its
+// run has ended, the conversation is deleted or the client goes away. A
+// reconnecting client resumes after the event in its Last-Event-ID header.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	var seq int64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		var err error
+		if seq, err = strconv.ParseInt(id, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID %q", id)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	stopped := run.ctx.Done()
+
+	for {
+		events, changed, ended := run.events.after(seq)
+		for _, ev := range events {
+			if err := writeEvent(w, s.app.redactor, ev); err != nil {
+				return
+			}
+			seq = ev.Seq
+		}
+		flusher.Flush()
+		if ended {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-stopped:
+			// A cancelled run still reports how it ended.
+			if run.describe().Status != StatusRunning {
+				return
+			}
+			stopped = nil
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent sends ev with its secrets masked, as transcripts are.
+func writeEvent(w http.ResponseWriter, redactor *redact.Redactor, ev chorus.Event) error {
+	data, err := redactor.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+	return err
+}
+
+// isFinal reports whether ev ends a run.
+func isFinal(ev chorus.Event) bool {
+	return ev.Type == chorus.EventFinished || ev.Type == chorus.EventError
+}
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/tools"
+)
+
+// Tool sources reported by ToolInfos.
+const (
+	// ToolSourceConversation marks the built-in tools an agent is given when it
+	// takes part in an orchestrated conversation.
+	ToolSourceConversation = "conversation"
+	// ToolSourceMCP prefixes the name of the MCP server a tool comes from.
+	ToolSourceMCP = "mcp:"
+)
+
+// MCP server statuses reported by MCPServerInfos.
+const (
+	MCPStatusConnected = "connected"
+	MCPStatusFailed    = "failed"
+)
+
+// AgentInfo describes a configured agent as chorus builds it.
+type AgentInfo struct {
+	Name            string   `json:"name"`
+	Role            string   `json:"role"`
+	Model           string   `json:"model"`
+	ReasoningEffort string   `json:"reasoning_effort"`
+	MCPServers      []string `json:"mcp_servers"`
+	Tools           []string `json:"tools"`
+	SystemMessage   string   `json:"system_message,omitempty"`
+}
+
+// ToolInfo describes a tool available to an agent.
+type ToolInfo struct {
+	Agent       string         `json:"agent"`
+	Name        string         `json:"name"`
+	Source      string         `json:"source"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// MCPServerInfo describes a configured MCP server and what it offers.
+type MCPServerInfo struct {
+	Name      string   `json:"name"`
+	Command   string   `json:"command"`
+	Args      []string `json:"args,omitempty"`
+	Status    string   `json:"status"`
+	Server    string   `json:"server,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Tools     []string `json:"tools"`
+	Resources []string `json:"resources"`
+	Prompts   []string `json:"prompts"`
+}
+
+// AgentInfos describes every configured agent.
+func (app *App) AgentInfos() ([]AgentInfo, error) {
+	cfg := app.config()
+	infos := make([]AgentInfo, 0, len(cfg.Agents))
+	for _, agentCfg := range cfg.Agents {
+		agent, err := app.newAgent(cfg, agentCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		info := AgentInfo{
+			Name:            agent.Name,
+			Role:            string(agent.Role),
+			Model:           agent.Model,
+			ReasoningEffort: string(agent.ReasoningEffort),
+			MCPServers:      cfg.serverNames(agentCfg),
+			Tools:           []string{},
+			SystemMessage:   systemMessageOf(agent),
+		}
+		toolInfos, err := app.agentTools(cfg, agentCfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range toolInfos {
+			info.Tools = append(info.Tools, t.Name)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// ToolInfos lists the tools each configured agent can use, or only the named
+// agent's if agentName is set: the built-in conversation tools for its role
+// followed by the MCP tools it is allowed.
+func (app *App) ToolInfos(agentName string) ([]ToolInfo, error) {
+	cfg := app.config()
+	if agentName != "" && !slices.ContainsFunc(cfg.Agents, func(a AgentConfig) bool { return a.Name == agentName }) {
+		return nil, fmt.Errorf("agent %q not found", agentName)
+	}
+
+	var infos []ToolInfo
+	for _, agentCfg := range cfg.Agents {
+		if agentName != "" && agentCfg.Name != agentName {
+			continue
+		}
+
+		agentInfos, err := app.agentTools(cfg, agentCfg)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, agentInfos...)
+	}
+	return infos, nil
+}
+
+// agentTools lists the tools one agent can use; see ToolInfos.
+func (app *App) agentTools(cfg *Config, agentCfg AgentConfig) ([]ToolInfo, error) {
+	var infos []ToolInfo
+	for _, t := range chorus.BuiltinTools(chorus.Role(agentCfg.Role)) {
+		info, err := toolInfo(agentCfg.Name, ToolSourceConversation, t)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	for _, name := range cfg.serverNames(agentCfg) {
+		server := app.server(name)
+		if server == nil {
+			continue
+		}
+		funcTools, _ := server.toolset.Snapshot()
+		for _, t := range funcTools {
+			if len(agentCfg.Tools) > 0 && !slices.Contains(agentCfg.Tools, t.Name) {
+				continue
+			}
+			info, err := toolInfo(agentCfg.Name, ToolSourceMCP+name, t)
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func toolInfo(agent, source string, t tools.FunctionTool) (ToolInfo, error) {
+	params := t.Parameters
+	if params == nil && t.Func != nil {
+		var err error
+		params, err = tools.GenerateSchema(t.Func)
+		if err != nil {
+			return ToolInfo{}, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+	}
+	return ToolInfo{
+		Agent:       agent,
+		Name:        t.Name,
+		Source:      source,
+		Description: t.Description,
+		Parameters:  params,
+	}, nil
+}
+
+// MCPServerInfos reports the status of every configured MCP server and the
+// tools, resources and prompts the connected ones offer.
+func (app *App) MCPServerInfos(ctx context.Context) []MCPServerInfo {
+	cfg := app.config()
+	infos := make([]MCPServerInfo, 0, len(cfg.MCPServers))
+	for _, serverCfg := range cfg.MCPServers {
+		name := serverCfg.DisplayName()
+		info := MCPServerInfo{
+			Name:      name,
+			Command:   serverCfg.Command,
+			Args:      serverCfg.Args,
+			Tools:     []string{},
+			Resources: []string{},
+			Prompts:   []string{},
+		}
+
+		server := app.server(name)
+		if server == nil {
+			info.Status = MCPStatusFailed
+			if err := app.failed[name]; err != nil {
+				info.Error = err.Error()
+			}
+			infos = append(infos, info)
+			continue
+		}
+
+		info.Status = MCPStatusConnected
+		init := server.session.InitializeResult()
+		if init != nil && init.ServerInfo != nil {
+			info.Server = init.ServerInfo.Name
+			if init.ServerInfo.Version != "" {
+				info.Server += " " + init.ServerInfo.Version
+			}
+		}
+
+		funcTools, _ := server.toolset.Snapshot()
+		for _, t := range funcTools {
+			info.Tools = append(info.Tools, t.Name)
+		}
+
+		// Only ask for what the server says it supports.
+		if init != nil && init.Capabilities != nil && init.Capabilities.Resources != nil {
+			for r, err := range server.session.Resources(ctx, nil) {
+				if err != nil {
+					info.Error = fmt.Sprintf("listing resources: %v", err)
+					break
+				}
+				info.Resources = append(info.Resources, r.URI)
+			}
+		}
+		if init != nil && init.Capabilities != nil && init.Capabilities.Prompts != nil {
+			for p, err := range server.session.Prompts(ctx, nil) {
+				if err != nil {
+					info.Error = fmt.Sprintf("listing prompts: %v", err)
+					break
+				}
+				info.Prompts = append(info.Prompts, p.Name)
+			}
+		}
+
+		infos = append(infos, info)
+	}
+	return infos
+}
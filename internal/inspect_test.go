@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/standrze/chorus/pkg/tools"
+)
+
+func newInspectApp() *App {
+	type QueryArgs struct {
+		Query string `json:"query"`
+	}
+	search := tools.FunctionTool{
+		Name: "search",
+		Func: func(args QueryArgs) (string, error) { return "", nil },
+	}
+	browse := tools.FunctionTool{
+		Name: "browse",
+		Func: func(args QueryArgs) (string, error) { return "", nil },
+	}
+
+	web := MCPServerConfig{Name: "web", Command: "web-server"}
+	return &App{
+		cfg: &Config{
+			Agents: []AgentConfig{
+				{Name: "Lead", Role: "orchestrator", Model: "big", MCPServers: []string{}},
+				{Name: "Researcher", Tools: []string{"search"}},
+			},
+			MCPServers: []MCPServerConfig{web, {Name: "files", Command: "files-server"}},
+		},
+		client:  &stubClient{},
+		servers: []*mcpServer{{cfg: web, toolset: tools.NewToolset("web", search, browse)}},
+		failed:  map[string]error{"files": errors.New("exec: not found")},
+	}
+}
+
+func TestToolInfos(t *testing.T) {
+	app := newInspectApp()
+
+	infos, err := app.ToolInfos("Researcher")
+	if err != nil {
+		t.Fatalf("ToolInfos failed: %v", err)
+	}
+	// Four conversation tools, then only the allowed MCP tool.
+	if len(infos) != 5 {
+		t.Fatalf("Expected 5 tools, got %d", len(infos))
+	}
+	last := infos[4]
+	if last.Name != "search" || last.Source != "mcp:web" {
+		t.Errorf("Expected search from mcp:web, got %s from %s", last.Name, last.Source)
+	}
+	if _, ok := last.Parameters["properties"].(map[string]any)["query"]; !ok {
+		t.Errorf("Expected a generated schema, got %v", last.Parameters)
+	}
+
+	if _, err := app.ToolInfos("Nobody"); err == nil {
+		t.Error("Expected an error for an unknown agent")
+	}
+}
+
+func TestAgentInfos(t *testing.T) {
+	infos, err := newInspectApp().AgentInfos()
+	if err != nil {
+		t.Fatalf("AgentInfos failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 agents, got %d", len(infos))
+	}
+
+	lead := infos[0]
+	if lead.Role != "orchestrator" || lead.Model != "big" || lead.ReasoningEffort != "medium" {
+		t.Errorf("Unexpected agent info: %+v", lead)
+	}
+	// Ten conversation tools plus both web tools.
+	if len(lead.Tools) != 12 {
+		t.Errorf("Expected 12 tools for the orchestrator, got %v", lead.Tools)
+	}
+}
+
+func TestMCPServerInfos_Failed(t *testing.T) {
+	app := newInspectApp()
+	app.servers = nil
+	infos := app.MCPServerInfos(context.Background())
+
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(infos))
+	}
+	if infos[0].Status != MCPStatusFailed || infos[0].Error != "" {
+		t.Errorf("Expected web to be reported as failed without a reason, got %+v", infos[0])
+	}
+	if infos[1].Status != MCPStatusFailed || infos[1].Error != "exec: not found" {
+		t.Errorf("Expected files to be reported as failed, got %+v", infos[1])
+	}
+}
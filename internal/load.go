@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// configExts are the supported config formats, in the order the default
+// config file is searched for.
+var configExts = []string{"json", "yaml", "yml", "toml"}
+
+// envRef matches $$ (a literal $) and ${VAR} or ${VAR:-default}.
+var envRef = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// LoadConfig reads the config file at path, or config.json, .yaml, .yml or
+// .toml in the working directory when path is empty. Included files are merged
+// in, ${VAR} references are expanded and the named profile, if any, is applied.
+//
+// It returns the file that was read, which is empty when path is empty and no
+// default config exists; the config is then empty too.
+func LoadConfig(path, profile string) (*Config, string, error) {
+	cfg, files, err := loadConfig(path, profile)
+	if len(files) > 0 {
+		path = files[0]
+	}
+	return cfg, path, err
+}
+
+// loadConfig is LoadConfig, but returns every file read: the config file
+// first, then the files it includes.
+func loadConfig(path, profile string) (*Config, []string, error) {
+	if path == "" {
+		for _, ext := range configExts {
+			if _, err := os.Stat("config." + ext); err == nil {
+				path = "config." + ext
+				break
+			}
+		}
+	}
+
+	settings := map[string]any{}
+	var files []string
+	if path != "" {
+		files = append(files, path)
+		var err error
+		settings, err = loadSettings(path, nil, &files)
+		if err != nil {
+			return nil, files, err
+		}
+	}
+
+	if profile != "" {
+		if err := applyProfile(settings, profile); err != nil {
+			return nil, files, err
+		}
+	}
+
+	// Unknown keys are almost always typos, so report them instead of ignoring them.
+	v := viper.New()
+	v.AutomaticEnv()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, files, err
+	}
+	var cfg Config
+	if err := v.Unmarshal(&cfg, func(dc *mapstructure.DecoderConfig) {
+		dc.ErrorUnused = true
+	}); err != nil {
+		return nil, files, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cfg, files, nil
+}
+
+// loadSettings reads one config file and the files it includes. Included
+// files are read first, in order, and the including file is merged over them.
+// stack holds the files currently being read, to catch include cycles, and
+// included files are added to files.
+func loadSettings(path string, stack []string, files *[]string) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+	stack = append(stack, abs)
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("unable to read config %s: %w", path, err)
+	}
+
+	settings := v.AllSettings()
+	if err := expandEnv(settings, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	resolveAgentFiles(settings, filepath.Dir(path))
+
+	includes, err := includePaths(settings["include"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(settings, "include")
+
+	merged := map[string]any{}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		*files = append(*files, include)
+		included, err := loadSettings(include, stack, files)
+		if err != nil {
+			return nil, err
+		}
+		mergeSettings(merged, included, true)
+	}
+	mergeSettings(merged, settings, true)
+
+	return merged, nil
+}
+
+// resolveAgentFiles makes the files agents name relative to the config file
+// that names them, dir, rather than to the working directory: each agent's
+// system_message_file and response_format.schema_file, in the config and in
+// its profiles.
+func resolveAgentFiles(settings map[string]any, dir string) {
+	sections := []map[string]any{settings}
+	profiles, _ := settings["profiles"].(map[string]any)
+	for _, profile := range profiles {
+		if profile, ok := profile.(map[string]any); ok {
+			sections = append(sections, profile)
+		}
+	}
+
+	resolve := func(m map[string]any, key string) {
+		if file, ok := m[key].(string); ok && file != "" && !filepath.IsAbs(file) {
+			m[key] = filepath.Join(dir, file)
+		}
+	}
+	for _, section := range sections {
+		agents, _ := section["agents"].([]any)
+		for _, agent := range agents {
+			agent, ok := agent.(map[string]any)
+			if !ok {
+				continue
+			}
+			resolve(agent, "system_message_file")
+			if format, ok := agent["response_format"].(map[string]any); ok {
+				resolve(format, "schema_file")
+			}
+		}
+	}
+}
+
+// includePaths accepts include as a single path or a list of paths.
+func includePaths(include any) ([]string, error) {
+	switch include := include.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{include}, nil
+	case []any:
+		paths := make([]string, len(include))
+		for i, p := range include {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("include[%d]: expected a path, got %T", i, p)
+			}
+			paths[i] = s
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("include: expected a path or a list of paths, got %T", include)
+	}
+}
+
+// applyProfile merges profiles.<name> over the rest of the settings. Lists in
+// a profile replace the base lists rather than adding to them, agents
+// included: a profile's agents are the only agents.
+func applyProfile(settings map[string]any, name string) error {
+	profiles, _ := settings["profiles"].(map[string]any)
+	// Config keys are case-insensitive, so profile names are too.
+	profile, ok := profiles[strings.ToLower(name)].(map[string]any)
+	if !ok {
+		available := slices.Sorted(maps.Keys(profiles))
+		if len(available) == 0 {
+			return fmt.Errorf("profile %q not found: the config defines no profiles", name)
+		}
+		return fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(available, ", "))
+	}
+
+	mergeSettings(settings, profile, false)
+	return nil
+}
+
+// mergeSettings merges src into dst. Nested maps are merged key by key and
+// other values in src win, except that lists are appended when appendLists is set.
+func mergeSettings(dst, src map[string]any, appendLists bool) {
+	for key, value := range src {
+		switch value := value.(type) {
+		case map[string]any:
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeSettings(existing, value, appendLists)
+				continue
+			}
+		case []any:
+			if existing, ok := dst[key].([]any); ok && appendLists {
+				dst[key] = append(slices.Clone(existing), value...)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// expandEnv replaces ${VAR} and ${VAR:-default} in every string value, in
+// place. As in the shell, the default is used when VAR is unset or empty; a
+// VAR that is unset without a default is an error so secrets aren't silently blank.
+func expandEnv(value any, path string) error {
+	switch value := value.(type) {
+	case map[string]any:
+		// Sorted so the first error reported is stable.
+		keys := slices.Collect(maps.Keys(value))
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if s, ok := value[key].(string); ok {
+				expanded, err := expandString(s)
+				if err != nil {
+					return fmt.Errorf("%s: %w", child, err)
+				}
+				value[key] = expanded
+				continue
+			}
+			if err := expandEnv(value[key], child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range value {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if s, ok := item.(string); ok {
+				expanded, err := expandString(s)
+				if err != nil {
+					return fmt.Errorf("%s: %w", child, err)
+				}
+				value[i] = expanded
+				continue
+			}
+			if err := expandEnv(item, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func expandString(s string) (string, error) {
+	var errs []error
+	expanded := envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		m := envRef.FindStringSubmatch(ref)
+		name, hasDefault, def := m[1], m[2] != "", m[3]
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		if hasDefault {
+			return def
+		}
+		if _, ok := os.LookupEnv(name); !ok {
+			errs = append(errs, fmt.Errorf("environment variable %s is not set", name))
+		}
+		return ""
+	})
+	return expanded, errors.Join(errs...)
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_IncludesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "lib/agents.toml", `
+[[agents]]
+name = "Worker"
+model = "${CHORUS_TEST_MODEL:-local/default}"
+system_message_file = "prompts/worker.md"
+`)
+	path := writeFile(t, dir, "config.yaml", `
+include: lib/agents.toml
+base_url: http://localhost:8080/v1
+api_key: ${CHORUS_TEST_KEY}
+agents:
+  - name: Teacher
+    role: orchestrator
+    system_message: "Costs $$5"
+    response_format: {type: json_schema, name: lesson, schema_file: lesson.schema.json}
+`)
+	t.Setenv("CHORUS_TEST_KEY", "secret")
+
+	cfg, file, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if file != path {
+		t.Errorf("Expected file %s, got %s", path, file)
+	}
+	if cfg.APIKey != "secret" {
+		t.Errorf("Expected api_key from the environment, got %q", cfg.APIKey)
+	}
+	if len(cfg.Agents) != 2 || cfg.Agents[0].Name != "Worker" || cfg.Agents[1].Name != "Teacher" {
+		t.Fatalf("Expected included agents before the file's own, got %+v", cfg.Agents)
+	}
+	if cfg.Agents[0].Model != "local/default" {
+		t.Errorf("Expected default model, got %q", cfg.Agents[0].Model)
+	}
+	if cfg.Agents[1].SystemMessage != "Costs $5" {
+		t.Errorf("Expected $$ to become $, got %q", cfg.Agents[1].SystemMessage)
+	}
+	// Files are found next to the config file that names them.
+	if want := filepath.Join(dir, "lib/prompts/worker.md"); cfg.Agents[0].SystemMessageFile != want {
+		t.Errorf("Expected system_message_file %s, got %s", want, cfg.Agents[0].SystemMessageFile)
+	}
+	if want := filepath.Join(dir, "lesson.schema.json"); cfg.Agents[1].ResponseFormat.SchemaFile != want {
+		t.Errorf("Expected schema_file %s, got %s", want, cfg.Agents[1].ResponseFormat.SchemaFile)
+	}
+}
+
+func TestLoadConfig_Profile(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{
+		"base_url": "http://remote/v1",
+		"roots": ["a", "b"],
+		"profiles": {
+			"local": {"base_url": "http://localhost/v1", "roots": ["c"]}
+		}
+	}`)
+
+	cfg, _, err := LoadConfig(path, "Local")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.BaseURL != "http://localhost/v1" {
+		t.Errorf("Expected profile base_url, got %q", cfg.BaseURL)
+	}
+	if len(cfg.Roots) != 1 || cfg.Roots[0] != "c" {
+		t.Errorf("Expected profile lists to replace the base, got %v", cfg.Roots)
+	}
+
+	if _, _, err := LoadConfig(path, "staging"); err == nil || !strings.Contains(err.Error(), "available: local") {
+		t.Errorf("Expected unknown profile error, got %v", err)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	cycle := writeFile(t, dir, "a.yaml", "include: b.yaml\n")
+	writeFile(t, dir, "b.yaml", "include: a.yaml\n")
+	unset := writeFile(t, dir, "unset.yaml", "agents:\n  - name: A\n    model: ${CHORUS_TEST_UNSET}\n")
+	typo := writeFile(t, dir, "typo.json", `{"agents": [{"nmae": "A"}]}`)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"include cycle", cycle, "include cycle"},
+		{"unset variable", unset, "agents[0].model: environment variable CHORUS_TEST_UNSET is not set"},
+		{"unknown key", typo, "nmae"},
+		{"missing file", filepath.Join(dir, "missing.yaml"), "unable to read config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := LoadConfig(tt.path, ""); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_DefaultFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	cfg, file, err := LoadConfig("", "")
+	if err != nil || file != "" || len(cfg.Agents) != 0 {
+		t.Fatalf("Expected an empty config without a file, got %q, %v", file, err)
+	}
+
+	writeFile(t, dir, "config.toml", "base_url = \"http://localhost/v1\"\n")
+	cfg, file, err = LoadConfig("", "")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if file != "config.toml" || cfg.BaseURL != "http://localhost/v1" {
+		t.Errorf("Expected config.toml to be found, got %q (%q)", file, cfg.BaseURL)
+	}
+}
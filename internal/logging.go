@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	clog "github.com/standrze/chorus/pkg/log"
+)
+
+var mcpLog = clog.Component(clog.ComponentMCP)
+
+// LogConfig controls how and where chorus logs. The --log-* flags override it.
+type LogConfig struct {
+	// Format is text or json. Defaults to text.
+	Format string `mapstructure:"format"`
+	// Level is debug, info, warn or error. Defaults to info; --debug sets debug.
+	Level string `mapstructure:"level"`
+	// File receives the logs instead of stderr. It is rotated once it reaches
+	// MaxSizeMB (default 100), keeping MaxBackups files for MaxAgeDays (zero keeps them all).
+	File       string `mapstructure:"file"`
+	MaxSizeMB  int    `mapstructure:"max_size_mb"`
+	MaxBackups int    `mapstructure:"max_backups"`
+	MaxAgeDays int    `mapstructure:"max_age_days"`
+	// Components sets the level of the client, mcp, tools or conversation logs on their own.
+	Components map[string]string `mapstructure:"components"`
+}
+
+// Options converts the config into options for pkg/log.
+func (c LogConfig) Options() (clog.Options, error) {
+	if err := c.validate(); err != nil {
+		return clog.Options{}, err
+	}
+
+	opts := clog.Options{Format: c.Format}
+	opts.Level, _ = parseLevel(c.Level)
+	if len(c.Components) > 0 {
+		opts.Components = make(map[string]slog.Level, len(c.Components))
+		for name, level := range c.Components {
+			opts.Components[name], _ = parseLevel(level)
+		}
+	}
+	if c.File != "" {
+		opts.Output = clog.RotatingFile(c.File, c.MaxSizeMB, c.MaxBackups, c.MaxAgeDays)
+	}
+	return opts, nil
+}
+
+func (c LogConfig) validate() error {
+	v := &validator{}
+	c.validateInto(v, "log")
+	return v.err()
+}
+
+func (c LogConfig) validateInto(v *validator, path string) {
+	if c.Format != "" && c.Format != clog.FormatText && c.Format != clog.FormatJSON {
+		v.add(path+".format", "invalid format %q, want text or json", c.Format)
+	}
+	if _, err := parseLevel(c.Level); err != nil {
+		v.add(path+".level", "%v", err)
+	}
+	if c.MaxSizeMB < 0 || c.MaxBackups < 0 || c.MaxAgeDays < 0 {
+		v.add(path, "rotation limits must not be negative")
+	}
+	for name, level := range c.Components {
+		if !slices.Contains(clog.Components, name) {
+			v.add(path+".components."+name, "unknown component, want one of %s", strings.Join(clog.Components, ", "))
+		} else if _, err := parseLevel(level); err != nil {
+			v.add(path+".components."+name, "%v", err)
+		}
+	}
+}
+
+// parseLevel parses debug, info, warn or error; empty means info.
+func parseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid level %q, want debug, info, warn or error", s)
+	}
+	return level, nil
+}
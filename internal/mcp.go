@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/tools"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mcpServer is a connected MCP server and the toolset its tools are published in.
+type mcpServer struct {
+	cfg     MCPServerConfig
+	client  *mcp.Client
+	session *mcp.ClientSession
+	toolset *tools.Toolset
+	metrics *metrics
+
+	// refreshMu serialises tool list refreshes triggered by notifications.
+	refreshMu sync.Mutex
+}
+
+// connectMCPServers starts every configured MCP server and wraps the tools
+// they expose as function tools. Servers that fail to start are logged and skipped.
+func (app *App) connectMCPServers(ctx context.Context) error {
+	if err := app.setWorkspaceRoot(chorus.WorkspaceDir); err != nil {
+		return err
+	}
+
+	cfg := app.config()
+
+	var sampling *samplingHandler
+	if cfg.Sampling != nil {
+		var err error
+		sampling, err = newSamplingHandler(app.client, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, mcpCfg := range cfg.MCPServers {
+		server := &mcpServer{
+			cfg:     mcpCfg,
+			toolset: tools.NewToolset(mcpCfg.DisplayName()),
+			metrics: app.metrics,
+		}
+
+		opts := &mcp.ClientOptions{
+			ToolListChangedHandler: func(_ context.Context, req *mcp.ToolListChangedRequest) {
+				// Listing tools from inside a notification handler would block the
+				// session's message loop, so refresh asynchronously. The
+				// notification may arrive before Connect returns and sets
+				// server.session, so it is refreshed through its own session.
+				go func() {
+					if err := server.refreshTools(ctx, req.Session); err != nil {
+						mcpLog.Warn("Failed to refresh tools from MCP server", "server", mcpCfg.DisplayName(), "error", err)
+					}
+				}()
+			},
+		}
+		if sampling != nil {
+			opts.CreateMessageHandler = sampling.forServer(mcpCfg.DisplayName())
+		}
+
+		// Create a new client
+		client := mcp.NewClient(&mcp.Implementation{
+			Name:    "chorus",
+			Version: version,
+		}, opts)
+		client.AddRoots(app.roots()...)
+		server.client = client
+
+		// Connect to a server over stdin/stdout.
+		transport := &mcp.CommandTransport{
+			Command: exec.Command(mcpCfg.Command, mcpCfg.Args...),
+		}
+
+		session, err := client.Connect(ctx, transport, nil)
+		if err != nil {
+			mcpLog.Warn("Failed to connect to MCP server", "server", mcpCfg.DisplayName(), "error", err)
+			if app.failed == nil {
+				app.failed = make(map[string]error)
+			}
+			app.failed[mcpCfg.DisplayName()] = err
+			app.metrics.mcpFailed(mcpCfg.DisplayName())
+			continue
+		}
+		// Sessions are kept alive so the tools keep working; App.Close shuts them down.
+		server.session = session
+		app.metrics.mcpConnected(server)
+		app.servers = append(app.servers, server)
+
+		if err := server.refreshTools(ctx, session); err != nil {
+			mcpLog.Warn("Failed to list tools from MCP server", "server", mcpCfg.DisplayName(), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// refreshTools re-lists the tools of the server's session and publishes them
+// to its toolset. Agents holding the toolset swap the new tools in before
+// their next turn.
+func (s *mcpServer) refreshTools(ctx context.Context, session *mcp.ClientSession) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	var funcTools []tools.FunctionTool
+	for t, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return err
+		}
+		tool, err := mcpFunctionTool(s.cfg.DisplayName(), session, t)
+		if err != nil {
+			mcpLog.Warn("Failed to convert tool", "server", s.cfg.DisplayName(), "tool", t.Name, "error", err)
+			continue
+		}
+		funcTools = append(funcTools, tool)
+	}
+
+	s.toolset.Set(funcTools...)
+	s.metrics.mcpToolsListed(s.cfg.DisplayName(), len(funcTools))
+	mcpLog.Debug("MCP tools refreshed", "server", s.cfg.DisplayName(), "tools", len(funcTools))
+	return nil
+}
+
+// mcpFunctionTool wraps a tool exposed by an MCP session so agents can call it
+// like any other function tool. Calls are traced under the agent's tool call.
+func mcpFunctionTool(serverName string, session *mcp.ClientSession, t *mcp.Tool) (tools.FunctionTool, error) {
+	// Capture session and name for closure
+	toolName := t.Name
+
+	// Define the wrapper function
+	wrapper := func(ctx context.Context, args json.RawMessage) (result string, err error) {
+		ctx, span := tracer.Start(ctx, "tools/call "+toolName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.McpMethodNameToolsCall,
+			semconv.GenAIToolName(toolName),
+			mcpServerKey.String(serverName),
+		))
+		defer func() { chorus.EndSpan(span, err) }()
+
+		// unmarshal args to map[string]interface{}
+		var argsMap map[string]interface{}
+		if err := json.Unmarshal(args, &argsMap); err != nil {
+			return "", fmt.Errorf("invalid arguments: %v", err)
+		}
+
+		callParams := &mcp.CallToolParams{
+			Name:      toolName,
+			Arguments: argsMap,
+		}
+
+		res, err := session.CallTool(ctx, callParams)
+		if err != nil {
+			return "", err
+		}
+
+		if res.IsError {
+			return "", fmt.Errorf("tool execution failed")
+		}
+
+		// Combine content
+		var sb string
+		for _, c := range res.Content {
+			if textContent, ok := c.(*mcp.TextContent); ok {
+				sb += textContent.Text + "\n"
+			}
+		}
+		return sb, nil
+	}
+
+	// Convert InputSchema to openai.FunctionParameters by round-tripping through JSON.
+	schemaBytes, err := json.Marshal(t.InputSchema)
+	if err != nil {
+		return tools.FunctionTool{}, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	var params openai.FunctionParameters
+	if err := json.Unmarshal(schemaBytes, &params); err != nil {
+		return tools.FunctionTool{}, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	return tools.FunctionTool{
+		Name:        t.Name,
+		Description: t.Description,
+		Parameters:  params,
+		Func:        wrapper,
+	}, nil
+}
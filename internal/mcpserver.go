@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+type AskArgs struct {
+	Prompt string `json:"prompt" jsonschema:"The message to send to the agent"`
+}
+
+type RunConversationArgs struct {
+	Objective string `json:"objective" jsonschema:"The objective for the orchestrator to accomplish"`
+}
+
+// NewMCPServer exposes the configured agents over MCP: one ask_<agent> tool per
+// agent, which keeps that agent's history across calls, and run_conversation,
+// which runs a fresh multi-agent conversation for each objective.
+func (app *App) NewMCPServer() (*mcp.Server, error) {
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "chorus",
+		Version: version,
+	}, nil)
+
+	_, loaded := app.configVersion()
+	agents, err := app.NewAgents()
+	if err != nil {
+		return nil, err
+	}
+
+	// Agent names that differ only in case or punctuation make the same tool.
+	served := make(map[string]string, len(agents))
+	for _, agent := range agents {
+		name := askToolName(agent.Name)
+		if other, ok := served[name]; ok {
+			return nil, fmt.Errorf("agents %q and %q would both be served as the MCP tool %s; rename one of them", other, agent.Name, name)
+		}
+		served[name] = agent.Name
+	}
+
+	for _, agent := range agents {
+		// Tool calls may arrive concurrently; an agent's history must not.
+		var mu sync.Mutex
+		refreshed := loaded
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        askToolName(agent.Name),
+			Description: fmt.Sprintf("Send a message to the %s agent and return its reply. The agent remembers earlier messages.", agent.Name),
+		}, func(ctx context.Context, req *mcp.CallToolRequest, args AskArgs) (*mcp.CallToolResult, any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			v, err := app.refreshAgent(agent, refreshed)
+			if err != nil {
+				return nil, nil, err
+			}
+			refreshed = v
+
+			reply, err := agent.Respond(ctx, chorus.WithUserMessage(args.Prompt))
+			if err != nil {
+				return nil, nil, err
+			}
+			return textResult(reply), nil, nil
+		})
+	}
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "run_conversation",
+		Description: "Run a multi-agent conversation led by the orchestrator agent and return its final result.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, args RunConversationArgs) (*mcp.CallToolResult, any, error) {
+		result, err := app.RunConversation(ctx, args.Objective)
+		if err != nil {
+			return nil, nil, err
+		}
+		return textResult(result), nil, nil
+	})
+
+	return server, nil
+}
+
+// ServeMCP serves the chorus MCP server over t until the client disconnects
+// or ctx is cancelled.
+func (app *App) ServeMCP(ctx context.Context, t mcp.Transport) error {
+	server, err := app.NewMCPServer()
+	if err != nil {
+		return err
+	}
+	return server.Run(ctx, t)
+}
+
+// askToolName derives a valid MCP tool name from an agent name.
+func askToolName(agentName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, agentName)
+	return "ask_" + name
+}
+
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}
+}
@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMCPServer_DuplicateToolNames(t *testing.T) {
+	app := &App{
+		cfg: &Config{Agents: []AgentConfig{
+			{Name: "Lead Writer", Role: "orchestrator"},
+			{Name: "lead_writer"},
+		}},
+		client: &stubClient{},
+	}
+	_, err := app.NewMCPServer()
+	if err == nil || !strings.Contains(err.Error(), "ask_lead_writer") {
+		t.Errorf("Expected an error naming the shared tool, got %v", err)
+	}
+
+	app.cfg.Agents[1].Name = "Editor"
+	if _, err := app.NewMCPServer(); err != nil {
+		t.Errorf("NewMCPServer failed: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/client"
+	"github.com/standrze/chorus/pkg/log"
+)
+
+// MetricsConfig serves Prometheus metrics while a command runs, so batch runs
+// can be scraped too. chorus serve also exposes them on its own address.
+type MetricsConfig struct {
+	// Addr is where /metrics is served, e.g. "127.0.0.1:9464". Empty serves nothing.
+	Addr string `mapstructure:"addr"`
+}
+
+// metrics are the Prometheus metrics an App records. Every method is a no-op
+// on a nil *metrics.
+type metrics struct {
+	registry *prometheus.Registry
+
+	generationSeconds *prometheus.HistogramVec
+	generationErrors  *prometheus.CounterVec
+	tokens            *prometheus.CounterVec
+	toolCalls         *prometheus.CounterVec
+	toolErrors        *prometheus.CounterVec
+	conversations     *prometheus.CounterVec
+	activeRuns        prometheus.Gauge
+	turns             prometheus.Histogram
+	retries           prometheus.Counter
+	mcpUp             *prometheus.GaugeVec
+	mcpTools          *prometheus.GaugeVec
+}
+
+func newMetrics() *metrics {
+	agentLabels := []string{"agent", "role", "model"}
+	toolLabels := []string{"agent", "role", "tool"}
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		generationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chorus_generation_duration_seconds",
+			Help:    "How long requests to the model took.",
+			Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+		}, agentLabels),
+		generationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chorus_generation_errors_total",
+			Help: "Requests to the model that failed.",
+		}, agentLabels),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chorus_tokens_total",
+			Help: "Tokens sent to (input) and generated by (output) the model.",
+		}, append(agentLabels, "type")),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chorus_tool_calls_total",
+			Help: "Tool calls made by agents.",
+		}, toolLabels),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chorus_tool_call_errors_total",
+			Help: "Tool calls that failed or were vetoed.",
+		}, toolLabels),
+		conversations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chorus_conversation_runs_total",
+			Help: "Conversation runs that ended, by status.",
+		}, []string{"status"}),
+		activeRuns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chorus_active_conversations",
+			Help: "Conversations whose run is in progress.",
+		}),
+		turns: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "chorus_conversation_turns",
+			Help:    "Orchestrator turns each conversation run took.",
+			Buckets: []float64{1, 2, 3, 5, 8, 13, 20, 30, 50},
+		}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "chorus_client_retries_total",
+			Help: "Requests to the model API that the client retried.",
+		}),
+		mcpUp: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "chorus_mcp_server_up",
+			Help: "Whether the MCP server is connected (1) or not (0).",
+		}, []string{"server"}),
+		mcpTools: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "chorus_mcp_server_tools",
+			Help: "Tools the MCP server offers.",
+		}, []string{"server"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.generationSeconds, m.generationErrors, m.tokens, m.toolCalls, m.toolErrors,
+		m.conversations, m.activeRuns, m.turns, m.retries, m.mcpUp, m.mcpTools,
+	)
+	return m
+}
+
+// handler serves the metrics in the Prometheus exposition format.
+func (m *metrics) handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// countRetries is client middleware that counts retried requests.
+func (m *metrics) countRetries(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if n := req.Header.Get(client.RetryCountHeader); n != "" && n != "0" {
+		m.retries.Inc()
+	}
+	return next(req)
+}
+
+// instrumentAgent records the agent's generations and tool calls.
+func (m *metrics) instrumentAgent(agent *chorus.Agent) {
+	if m == nil {
+		return
+	}
+	agent.Events.Hook(chorus.Hooks{
+		AfterGenerate: func(ctx context.Context, call *chorus.GenerateCall) {
+			labels := []string{call.Agent.Name, string(call.Agent.Role), call.Params.Model}
+			m.generationSeconds.WithLabelValues(labels...).Observe(time.Since(call.Started).Seconds())
+			if call.Err != nil {
+				m.generationErrors.WithLabelValues(labels...).Inc()
+				return
+			}
+			usage := call.Completion.Usage
+			m.tokens.WithLabelValues(append(labels, "input")...).Add(float64(usage.PromptTokens))
+			m.tokens.WithLabelValues(append(labels, "output")...).Add(float64(usage.CompletionTokens))
+		},
+		AfterToolCall: func(ctx context.Context, call *chorus.ToolCall) {
+			labels := []string{call.Agent.Name, string(call.Agent.Role), call.Name}
+			m.toolCalls.WithLabelValues(labels...).Inc()
+			if call.Err != nil {
+				m.toolErrors.WithLabelValues(labels...).Inc()
+			}
+		},
+	})
+}
+
+// instrumentConversation records when the conversation's runs start and end,
+// and how many turns they took.
+func (m *metrics) instrumentConversation(conv *chorus.Conversation) {
+	if m == nil {
+		return
+	}
+	// The bus delivers one event at a time, so running needs no lock.
+	running := false
+	conv.Events().Subscribe(func(ev chorus.Event) {
+		switch ev.Type {
+		case chorus.EventTurnStarted:
+			if !running {
+				running = true
+				m.activeRuns.Inc()
+			}
+		case chorus.EventFinished, chorus.EventError:
+			if running {
+				running = false
+				m.activeRuns.Dec()
+			}
+			status := StatusCompleted
+			if ev.Type == chorus.EventError {
+				status = StatusFailed
+			}
+			m.conversations.WithLabelValues(status).Inc()
+			m.turns.Observe(float64(ev.Turn))
+		}
+	})
+}
+
+// mcpConnected marks the server up until its session ends.
+func (m *metrics) mcpConnected(server *mcpServer) {
+	if m == nil {
+		return
+	}
+	name := server.cfg.DisplayName()
+	m.mcpUp.WithLabelValues(name).Set(1)
+	go func() {
+		server.session.Wait()
+		m.mcpUp.WithLabelValues(name).Set(0)
+	}()
+}
+
+func (m *metrics) mcpFailed(name string) {
+	if m == nil {
+		return
+	}
+	m.mcpUp.WithLabelValues(name).Set(0)
+}
+
+func (m *metrics) mcpToolsListed(name string, n int) {
+	if m == nil {
+		return
+	}
+	m.mcpTools.WithLabelValues(name).Set(float64(n))
+}
+
+// serve serves /metrics on addr until the returned function shuts it down.
+func (m *metrics) serve(addr string) (shutdown func(context.Context) error, err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Metrics server failed", "error", err)
+		}
+	}()
+	log.Info("Serving metrics", "addr", listener.Addr().String())
+	return server.Shutdown, nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/standrze/chorus/pkg/client"
+)
+
+func TestMetrics_ConversationRun(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+	s.app.metrics = newMetrics()
+
+	var status ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{"objective":"teach"}`, &status); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, status.ID)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`chorus_generation_duration_seconds_count{agent="Teacher",model="ai/gpt-oss",role="orchestrator"} 1`,
+		`chorus_tool_calls_total{agent="Teacher",role="orchestrator",tool="Finish"} 1`,
+		`chorus_conversation_runs_total{status="completed"} 1`,
+		`chorus_conversation_turns_sum 1`,
+		`chorus_active_conversations 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the metrics, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_CountsRetries(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After-Ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	defer ts.Close()
+
+	m := newMetrics()
+	c := client.NewClient(ts.URL, "key", option.WithMiddleware(m.countRetries))
+	if _, err := c.ChatCompletion(context.Background(), openai.ChatCompletionNewParams{Model: "m"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "chorus_client_retries_total 1") {
+		t.Errorf("Expected one retry after %d attempts, got:\n%s", attempts, rec.Body.String())
+	}
+}
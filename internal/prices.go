@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PriceConfig is what a model costs, in dollars per million tokens.
+type PriceConfig struct {
+	Model  string  `mapstructure:"model"`
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+// Cost returns the dollars spent on the given prompt and completion tokens.
+func (p PriceConfig) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+// Price returns the configured price of model, ignoring case.
+func (c *Config) Price(model string) (PriceConfig, bool) {
+	for _, p := range c.Prices {
+		if strings.EqualFold(p.Model, model) {
+			return p, true
+		}
+	}
+	return PriceConfig{}, false
+}
+
+func validatePrices(v *validator, prices []PriceConfig) {
+	seen := make(map[string]int)
+	for i, p := range prices {
+		path := fmt.Sprintf("prices[%d]", i)
+		if p.Model == "" {
+			v.add(path+".model", "is required")
+		} else if j, ok := seen[strings.ToLower(p.Model)]; ok {
+			v.add(path+".model", "duplicate price for %q (also prices[%d])", p.Model, j)
+		} else {
+			seen[strings.ToLower(p.Model)] = i
+		}
+		if p.Input < 0 {
+			v.add(path+".input", "must not be negative")
+		}
+		if p.Output < 0 {
+			v.add(path+".output", "must not be negative")
+		}
+	}
+}
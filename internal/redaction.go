@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+// RedactionConfig controls what is masked in logs, traces and transcripts.
+// The built-in rules (API keys, tokens, emails) and the configured api_key
+// always apply unless Disabled is set.
+type RedactionConfig struct {
+	Disabled bool `mapstructure:"disabled"`
+	// Patterns are extra regular expressions whose matches are masked.
+	Patterns []string `mapstructure:"patterns"`
+	// Keywords are extra keys, such as "session_id", whose values are masked.
+	Keywords []string `mapstructure:"keywords"`
+}
+
+// Redactor builds the redactor the config asks for, or nil if redaction is
+// disabled. Patterns that don't compile are skipped; Validate reports them.
+func (c *Config) Redactor() *redact.Redactor {
+	if c.Redaction.Disabled {
+		return nil
+	}
+	rules := append([]redact.Rule(nil), redact.DefaultRules...)
+	for i, pattern := range c.Redaction.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, redact.Rule{Name: fmt.Sprintf("pattern %d", i), Pattern: re})
+	}
+	return redact.New(redact.Options{
+		Rules:    rules,
+		Keywords: append(append([]string(nil), redact.DefaultKeywords...), c.Redaction.Keywords...),
+		Secrets:  []string{c.APIKey},
+	})
+}
+
+func (c RedactionConfig) validateInto(v *validator, path string) {
+	for i, pattern := range c.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.add(fmt.Sprintf("%s.patterns[%d]", path, i), "%v", err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// registry holds values by ID and forgets the ones that have not been used
+// for longer than its TTL.
+type registry[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*registryEntry[T]
+	now     func() time.Time
+}
+
+type registryEntry[T any] struct {
+	value    T
+	added    time.Time
+	lastUsed time.Time
+}
+
+func newRegistry[T any](ttl time.Duration) *registry[T] {
+	return &registry[T]{
+		ttl:     ttl,
+		entries: make(map[string]*registryEntry[T]),
+		now:     time.Now,
+	}
+}
+
+func (r *registry[T]) add(id string, value T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.entries[id] = &registryEntry[T]{value: value, added: now, lastUsed: now}
+}
+
+// get looks up id and counts it as used.
+func (r *registry[T]) get(id string) (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	entry.lastUsed = r.now()
+	return entry.value, true
+}
+
+// touch counts id as used without looking it up.
+func (r *registry[T]) touch(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, ok := r.entries[id]; ok {
+		entry.lastUsed = r.now()
+	}
+}
+
+func (r *registry[T]) remove(id string) (T, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	delete(r.entries, id)
+	return entry.value, true
+}
+
+// list returns every value, oldest first.
+func (r *registry[T]) list() []T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*registryEntry[T], 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].added.Before(entries[j].added) })
+
+	values := make([]T, len(entries))
+	for i, entry := range entries {
+		values[i] = entry.value
+	}
+	return values
+}
+
+// evict removes the values unused for longer than the TTL for which idle
+// reports true, and returns them. A zero TTL keeps everything.
+func (r *registry[T]) evict(idle func(T) bool) []T {
+	if r.ttl <= 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var evicted []T
+	cutoff := r.now().Add(-r.ttl)
+	for id, entry := range r.entries {
+		if entry.lastUsed.Before(cutoff) && idle(entry.value) {
+			delete(r.entries, id)
+			evicted = append(evicted, entry.value)
+		}
+	}
+	return evicted
+}
+
+// newID returns a random identifier for a registry entry.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry_Evict(t *testing.T) {
+	now := time.Now()
+	r := newRegistry[string](time.Minute)
+	r.now = func() time.Time { return now }
+
+	r.add("old", "old")
+	r.add("busy", "busy")
+	now = now.Add(30 * time.Second)
+	r.add("new", "new")
+	now = now.Add(45 * time.Second)
+
+	evicted := r.evict(func(v string) bool { return v != "busy" })
+	if len(evicted) != 1 || evicted[0] != "old" {
+		t.Fatalf("Expected only old to be evicted, got %v", evicted)
+	}
+	if got := r.list(); len(got) != 2 || got[0] != "busy" || got[1] != "new" {
+		t.Errorf("Expected busy and new to remain in order, got %v", got)
+	}
+
+	// Using an entry restarts its TTL.
+	now = now.Add(20 * time.Second)
+	r.get("new")
+	now = now.Add(50 * time.Second)
+	if evicted := r.evict(func(string) bool { return true }); len(evicted) != 1 || evicted[0] != "busy" {
+		t.Errorf("Expected only busy to be evicted, got %v", evicted)
+	}
+}
+
+func TestRegistry_NoTTL(t *testing.T) {
+	now := time.Now()
+	r := newRegistry[int](0)
+	r.now = func() time.Time { return now }
+
+	r.add("a", 1)
+	now = now.Add(24 * time.Hour)
+	if evicted := r.evict(func(int) bool { return true }); len(evicted) != 0 {
+		t.Errorf("Expected nothing evicted without a TTL, got %v", evicted)
+	}
+	if v, ok := r.remove("a"); !ok || v != 1 {
+		t.Errorf("Expected to remove a, got %v, %v", v, ok)
+	}
+}
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/log"
+)
+
+// reloadDelay lets a burst of file events settle before reloading; editors
+// often save a file in several steps.
+const reloadDelay = 200 * time.Millisecond
+
+// WatchConfig reloads the config whenever the file at path, or a file it
+// includes, changes, until ctx is done. profile is applied as in LoadConfig.
+//
+// A reloaded config that fails validation is logged and ignored, keeping the
+// last good one. Agents pick up a reload between turns, see refreshAgent.
+// Settings used to connect (base_url, api_key, mcp_servers, sampling and
+// roots) only take effect on restart.
+func (app *App) WatchConfig(ctx context.Context, path, profile string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	_, files, err := loadConfig(path, profile)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	watched, err := watchFiles(watcher, files)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if watched[event.Name] && !event.Has(fsnotify.Chmod) {
+					timer.Reset(reloadDelay)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Error("Config watcher failed", "error", err)
+			case <-timer.C:
+				files, err := app.reload(path, profile)
+				if err != nil {
+					log.Error("Config reload rejected, keeping the last good config", "file", path, "error", err)
+				} else {
+					log.Info("Config reloaded", "file", path)
+				}
+				// Includes may have been added, so watch whatever was read this time.
+				if w, err := watchFiles(watcher, files); err == nil {
+					watched = w
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watchFiles watches the directories holding files, since editors often
+// replace a file rather than write to it, and returns the set of files.
+func watchFiles(watcher *fsnotify.Watcher, files []string) (map[string]bool, error) {
+	watched := make(map[string]bool, len(files))
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			return nil, err
+		}
+		watched[abs] = true
+	}
+	return watched, nil
+}
+
+// reload loads the config again and, if it is valid, makes it current. It
+// returns the files that were read.
+func (app *App) reload(path, profile string) ([]string, error) {
+	cfg, files, err := loadConfig(path, profile)
+	if err != nil {
+		return files, err
+	}
+
+	current := app.config()
+	if !sameConnections(current, cfg) {
+		log.Info("Changes to base_url, api_key, mcp_servers, sampling or roots take effect on restart")
+	}
+	cfg.BaseURL = current.BaseURL
+	cfg.APIKey = current.APIKey
+	cfg.MCPServers = current.MCPServers
+	cfg.Sampling = current.Sampling
+	cfg.Roots = current.Roots
+	cfg.Debug = current.Debug
+
+	if err := cfg.Validate(); err != nil {
+		return files, err
+	}
+	if errs := app.validateTools(cfg); len(errs) > 0 {
+		return files, errs
+	}
+
+	app.cfgMu.Lock()
+	app.cfg = cfg
+	app.cfgVersion++
+	app.cfgMu.Unlock()
+
+	return files, nil
+}
+
+// sameConnections reports whether a and b agree on the settings that are only
+// used when the app starts.
+func sameConnections(a, b *Config) bool {
+	return a.BaseURL == b.BaseURL &&
+		a.APIKey == b.APIKey &&
+		reflect.DeepEqual(a.MCPServers, b.MCPServers) &&
+		reflect.DeepEqual(a.Sampling, b.Sampling) &&
+		reflect.DeepEqual(a.Roots, b.Roots)
+}
+
+// refreshAgent brings an idle agent up to date with the current config if it
+// has been reloaded since version, keeping the agent's history. It returns the
+// config version the agent now reflects. Agents no longer in the config, or
+// without a name, are left as they are.
+func (app *App) refreshAgent(agent *chorus.Agent, version uint64) (uint64, error) {
+	cfg, current := app.configVersion()
+	if version == current {
+		return current, nil
+	}
+
+	for _, agentCfg := range cfg.Agents {
+		if agentCfg.Name == "" || agentCfg.Name != agent.Name {
+			continue
+		}
+		fresh, err := app.newAgent(cfg, agentCfg)
+		if err != nil {
+			return version, err
+		}
+		agent.Reconfigure(fresh)
+		break
+	}
+	return current, nil
+}
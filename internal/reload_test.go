@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", `
+base_url: http://localhost/v1
+agents:
+  - name: Teacher
+    model: model-a
+    system_message: You teach.
+`)
+	cfg, _, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{cfg: cfg, client: &stubClient{reply: "ok"}}
+
+	agent, err := app.NewAgent(cfg.Agents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Respond(context.Background(), chorus.WithUserMessage("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "config.yaml", `
+base_url: http://elsewhere/v1
+agents:
+  - name: Teacher
+    model: model-b
+    system_message: You teach tersely.
+`)
+	if _, err := app.reload(path, ""); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := app.config().BaseURL; got != "http://localhost/v1" {
+		t.Errorf("Expected base_url to wait for a restart, got %s", got)
+	}
+
+	version, err := app.refreshAgent(agent, 0)
+	if err != nil || version != 1 {
+		t.Fatalf("Expected refresh to version 1, got %d, %v", version, err)
+	}
+	if agent.Model != "model-b" || systemMessageOf(agent) != "You teach tersely." {
+		t.Errorf("Expected reloaded settings, got %s / %q", agent.Model, systemMessageOf(agent))
+	}
+	if len(agent.Messages) != 3 {
+		t.Errorf("Expected history to survive the reload, got %d messages", len(agent.Messages))
+	}
+}
+
+func TestReload_RejectsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", "agents:\n  - name: Teacher\n    model: model-a\n")
+	cfg, _, err := LoadConfig(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &App{cfg: cfg, client: &stubClient{}}
+
+	writeFile(t, dir, "config.yaml", "agents:\n  - name: Teacher\n    reasoning_effort: huge\n")
+	if _, err := app.reload(path, ""); err == nil || !strings.Contains(err.Error(), "agents[0].reasoning_effort") {
+		t.Errorf("Expected reload to be rejected, got %v", err)
+	}
+
+	cfg, version := app.configVersion()
+	if version != 0 || cfg.Agents[0].Model != "model-a" {
+		t.Errorf("Expected the last good config to be kept, got version %d", version)
+	}
+}
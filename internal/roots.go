@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// ErrWorkspaceBusy is returned by SetWorkspace while a run holds the MCP
+// servers' workspace root.
+var ErrWorkspaceBusy = errors.New("the MCP servers are serving another conversation's workspace")
+
+// NewConversation starts a conversation and creates its workspace. The MCP
+// servers are pointed at the workspace while the app runs the conversation,
+// see run.
+func (app *App) NewConversation(ctx context.Context, agents ...*chorus.Agent) (*chorus.Conversation, error) {
+	conv, err := chorus.NewConversation(ctx, agents...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(conv.Workspace(), 0755); err != nil {
+		conv.Close()
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	app.metrics.instrumentConversation(conv)
+	app.recordTranscript(conv)
+	for _, watch := range app.watchers {
+		watch(conv)
+	}
+
+	return conv, nil
+}
+
+// WatchConversations calls fn with each conversation the app creates from
+// now on, before it runs. Call it before starting any.
+func (app *App) WatchConversations(fn func(*chorus.Conversation)) {
+	app.watchers = append(app.watchers, fn)
+}
+
+// run runs conv towards objective with the MCP servers' workspace root
+// pointed at conv's workspace, so MCP tools and the built-in file tools share
+// one directory. The app has one session with each MCP server, so runs take
+// turns at the root: a run waits for the one holding it to end, or for ctx.
+func (app *App) run(ctx context.Context, conv *chorus.Conversation, objective string) (string, error) {
+	release, err := app.holdWorkspace(ctx, conv)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return conv.Run(objective)
+}
+
+// holdWorkspace waits until no run holds the workspace root, then advertises
+// conv's workspace to the MCP servers until release is called, when the
+// default workspace takes its place again.
+func (app *App) holdWorkspace(ctx context.Context, conv *chorus.Conversation) (release func(), err error) {
+	if len(app.servers) == 0 {
+		return func() {}, nil
+	}
+
+	lease := app.workspaceLease()
+	select {
+	case lease <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	app.rootsMu.Lock()
+	defer app.rootsMu.Unlock()
+	if err := app.setWorkspace(conv.Workspace()); err != nil {
+		<-lease
+		return nil, err
+	}
+	app.rootsHolder = conv.ID()
+
+	return func() {
+		app.rootsMu.Lock()
+		defer app.rootsMu.Unlock()
+		app.rootsHolder = ""
+		if err := app.setWorkspace(chorus.WorkspaceDir); err != nil {
+			mcpLog.Warn("Failed to reset the MCP workspace root", "error", err)
+		}
+		<-lease
+	}, nil
+}
+
+// workspaceLease is taken by the run holding the workspace root.
+func (app *App) workspaceLease() chan struct{} {
+	app.rootsMu.Lock()
+	defer app.rootsMu.Unlock()
+	if app.rootsLease == nil {
+		app.rootsLease = make(chan struct{}, 1)
+	}
+	return app.rootsLease
+}
+
+// SetWorkspace advertises dir to every MCP server as the workspace root,
+// replacing the previous one. Configured extra roots are left alone. It fails
+// with ErrWorkspaceBusy while a run holds the root.
+func (app *App) SetWorkspace(dir string) error {
+	app.rootsMu.Lock()
+	defer app.rootsMu.Unlock()
+	if app.rootsHolder != "" {
+		return fmt.Errorf("%w (%s)", ErrWorkspaceBusy, app.rootsHolder)
+	}
+	return app.setWorkspace(dir)
+}
+
+func (app *App) setWorkspace(dir string) error {
+	previous := app.workspaceRoot
+	if err := app.setWorkspaceRoot(dir); err != nil {
+		return err
+	}
+	if previous != nil && previous.URI == app.workspaceRoot.URI {
+		return nil
+	}
+
+	for _, server := range app.servers {
+		if previous != nil {
+			server.client.RemoveRoots(previous.URI)
+		}
+		server.client.AddRoots(app.workspaceRoot)
+	}
+	return nil
+}
+
+func (app *App) setWorkspaceRoot(dir string) error {
+	root, err := fileRoot("workspace", dir)
+	if err != nil {
+		return err
+	}
+	app.workspaceRoot = root
+	return nil
+}
+
+// roots lists the workspace root followed by the configured extra roots.
+func (app *App) roots() []*mcp.Root {
+	roots := []*mcp.Root{app.workspaceRoot}
+	for _, dir := range app.config().Roots {
+		root, err := fileRoot(filepath.Base(dir), dir)
+		if err != nil {
+			mcpLog.Warn("Skipping MCP root", "error", err)
+			continue
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// fileRoot builds an MCP root for a local directory.
+func fileRoot(name, dir string) (*mcp.Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root %s: %w", dir, err)
+	}
+	uri := &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	return &mcp.Root{Name: name, URI: uri.String()}, nil
+}
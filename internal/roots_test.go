@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/standrze/chorus/pkg/tools"
+)
+
+// testMCPServer stands in for a connected MCP server.
+func testMCPServer() *mcpServer {
+	return &mcpServer{
+		client:  mcp.NewClient(&mcp.Implementation{Name: "chorus"}, nil),
+		toolset: tools.NewToolset("files"),
+	}
+}
+
+func TestHoldWorkspace_WaitsForTheRoot(t *testing.T) {
+	// Conversations create their workspace under the working directory.
+	t.Chdir(t.TempDir())
+	app := &App{
+		cfg: &Config{Agents: []AgentConfig{
+			{Name: "Lead", Role: "orchestrator"},
+			{Name: "Writer"},
+		}},
+		client:  &stubClient{},
+		servers: []*mcpServer{testMCPServer()},
+	}
+	agents, err := app.NewAgents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	others, _ := app.NewAgents()
+
+	// Creating conversations doesn't take the root.
+	first, err := app.NewConversation(t.Context(), agents...)
+	if err != nil {
+		t.Fatalf("NewConversation failed: %v", err)
+	}
+	defer first.Close()
+	second, err := app.NewConversation(t.Context(), others...)
+	if err != nil {
+		t.Fatalf("Expected a second conversation to be created, got %v", err)
+	}
+	defer second.Close()
+
+	release, err := app.holdWorkspace(t.Context(), first)
+	if err != nil {
+		t.Fatalf("holdWorkspace failed: %v", err)
+	}
+	if !strings.HasSuffix(app.workspaceRoot.URI, first.ID()) {
+		t.Errorf("Expected the root to be the first conversation's workspace, got %s", app.workspaceRoot.URI)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := app.holdWorkspace(ctx, second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the second run to wait for the root, got %v", err)
+	}
+
+	held := make(chan func())
+	go func() {
+		release, err := app.holdWorkspace(t.Context(), second)
+		if err != nil {
+			t.Error(err)
+		}
+		held <- release
+	}()
+	release()
+	select {
+	case release := <-held:
+		if !strings.HasSuffix(app.workspaceRoot.URI, second.ID()) {
+			t.Errorf("Expected the root to be the second conversation's workspace, got %s", app.workspaceRoot.URI)
+		}
+		release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the second run to get the root once released")
+	}
+	if !strings.HasSuffix(app.workspaceRoot.URI, "/workspace") {
+		t.Errorf("Expected the default workspace root once released, got %s", app.workspaceRoot.URI)
+	}
+}
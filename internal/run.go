@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/log"
+)
+
+// RunConversation builds fresh agents from the config and runs an orchestrated
+// conversation for objective, applying the configured turn and token limits.
+func (app *App) RunConversation(ctx context.Context, objective string) (string, error) {
+	agents, err := app.NewAgents()
+	if err != nil {
+		return "", err
+	}
+
+	conv, err := app.newLimitedConversation(ctx, agents...)
+	if err != nil {
+		return "", err
+	}
+	defer conv.Close()
+
+	log.Debug("Running conversation", "conversation", conv.ID(), "workspace", conv.Workspace())
+
+	result, err := app.run(ctx, conv, objective)
+	if err != nil {
+		return "", fmt.Errorf("conversation %s: %w", conv.ID(), err)
+	}
+	return result, nil
+}
+
+// newLimitedConversation starts a conversation with the configured turn
+// strategy and turn and token limits.
+func (app *App) newLimitedConversation(ctx context.Context, agents ...*chorus.Agent) (*chorus.Conversation, error) {
+	conv, err := app.NewConversation(ctx, agents...)
+	if err != nil {
+		return nil, err
+	}
+	cfg := app.config()
+	if cfg.MaxTurns > 0 {
+		conv.SetMaxTurns(cfg.MaxTurns)
+	}
+	conv.SetTokenBudget(cfg.TokenBudget)
+	conv.SetStrategy(cfg.Strategy.TurnStrategy())
+	return conv, nil
+}
+
+// TurnStrategy builds the configured turn strategy.
+func (c StrategyConfig) TurnStrategy() chorus.TurnStrategy {
+	switch c.Type {
+	case chorus.StrategyRoundRobin:
+		return &chorus.RoundRobin{Agents: c.Agents, Rounds: c.Rounds}
+	case chorus.StrategyGroupChat:
+		return &chorus.GroupChat{Agents: c.Agents}
+	case chorus.StrategyPipeline:
+		return &chorus.Pipeline{Agents: c.Agents}
+	case chorus.StrategyDebate:
+		return &chorus.Debate{Agents: c.Agents, Rounds: c.Rounds, Judge: c.Judge}
+	}
+	return &chorus.Orchestrated{}
+}
+
+func (c StrategyConfig) validateInto(v *validator, path string, names map[string]int) {
+	if c.Type != "" && !slices.Contains(chorus.TurnStrategies, c.Type) {
+		v.add(path+".type", "must be one of %s, got %q", strings.Join(chorus.TurnStrategies, ", "), c.Type)
+	}
+	if c.Rounds < 0 {
+		v.add(path+".rounds", "must not be negative")
+	}
+	for i, name := range c.Agents {
+		if _, ok := names[name]; !ok {
+			v.add(fmt.Sprintf("%s.agents[%d]", path, i), "agent %q is not configured", name)
+		}
+	}
+	if c.Type == chorus.StrategyDebate && len(c.Agents) == 1 {
+		v.add(path+".agents", "a debate needs at least two agents")
+	}
+	if c.Judge != "" {
+		if c.Type != chorus.StrategyDebate {
+			v.add(path+".judge", "only a debate has a judge")
+		} else if _, ok := names[c.Judge]; !ok {
+			v.add(path+".judge", "agent %q is not configured", c.Judge)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/client"
+)
+
+// ApproveFunc decides whether a sampling request from the named MCP server may proceed.
+type ApproveFunc func(server string, params *mcp.CreateMessageParams) bool
+
+// samplingHandler fulfils MCP sampling/createMessage requests with a chorus agent.
+type samplingHandler struct {
+	client client.Client
+	cfg    SamplingConfig
+	// agentOpts apply the sampling agent's settings, if one is configured.
+	agentOpts []func(*chorus.Agent)
+	approve   ApproveFunc
+
+	// used counts the tokens spent on sampling, and reserved for requests
+	// still in progress, against MaxTotalTokens.
+	mu   sync.Mutex
+	used int64
+}
+
+func newSamplingHandler(c client.Client, cfg *Config) (*samplingHandler, error) {
+	h := &samplingHandler{
+		client: c,
+		cfg:    *cfg.Sampling,
+	}
+
+	if h.cfg.Agent != "" {
+		i := slices.IndexFunc(cfg.Agents, func(a AgentConfig) bool { return a.Name == h.cfg.Agent })
+		if i < 0 {
+			return nil, fmt.Errorf("sampling agent %q is not configured", h.cfg.Agent)
+		}
+		opts, err := cfg.generationOptions(cfg.Agents[i])
+		if err != nil {
+			return nil, fmt.Errorf("sampling agent %q: %w", h.cfg.Agent, err)
+		}
+		h.agentOpts = opts
+	}
+
+	switch h.cfg.Approval {
+	case SamplingApprovalAuto:
+		h.approve = func(string, *mcp.CreateMessageParams) bool { return true }
+	case SamplingApprovalDeny:
+		h.approve = func(string, *mcp.CreateMessageParams) bool { return false }
+	case SamplingApprovalPrompt, "":
+		// Asking needs a terminal; without one, say so now rather than deny
+		// every request.
+		tty, err := openTerminal()
+		if err != nil {
+			return nil, fmt.Errorf("sampling.approval is %q, but there is no terminal to ask on; set it to %q or %q", SamplingApprovalPrompt, SamplingApprovalAuto, SamplingApprovalDeny)
+		}
+		tty.Close()
+		h.approve = promptApproval
+	default:
+		return nil, fmt.Errorf("unknown sampling approval policy %q", h.cfg.Approval)
+	}
+
+	return h, nil
+}
+
+// forServer returns a CreateMessageHandler that attributes requests to the named server.
+func (h *samplingHandler) forServer(server string) func(context.Context, *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+	return func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+		return h.createMessage(ctx, server, req.Params)
+	}
+}
+
+func (h *samplingHandler) createMessage(ctx context.Context, server string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	if !h.approve(server, params) {
+		mcpLog.Info("Sampling request denied", "server", server)
+		return nil, fmt.Errorf("sampling request denied")
+	}
+
+	maxTokens, err := h.reserve(params.MaxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := h.newAgent(params, maxTokens)
+	if err != nil {
+		h.settle(maxTokens, 0)
+		return nil, err
+	}
+
+	mcpLog.Debug("Sampling", "server", server, "model", agent.Model, "max_tokens", maxTokens)
+
+	resp, err := agent.Generate(ctx)
+	if err != nil {
+		h.settle(maxTokens, 0)
+		return nil, fmt.Errorf("sampling failed: %w", err)
+	}
+	h.settle(maxTokens, resp.Usage.TotalTokens)
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("sampling failed: empty response")
+	}
+	choice := resp.Choices[0]
+
+	model := resp.Model
+	if model == "" {
+		model = agent.Model
+	}
+
+	return &mcp.CreateMessageResult{
+		Content:    &mcp.TextContent{Text: choice.Message.Content},
+		Model:      model,
+		Role:       "assistant",
+		StopReason: stopReason(choice.FinishReason),
+	}, nil
+}
+
+// reserve applies the per-request and lifetime token caps to a request for
+// requested tokens, returning how many tokens may be sampled. Against the
+// lifetime cap those tokens count as used until settle replaces them with
+// what the request really used, so concurrent requests can't all pass.
+func (h *samplingHandler) reserve(requested int64) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	allowed := requested
+	if h.cfg.MaxTokens > 0 && (allowed <= 0 || allowed > h.cfg.MaxTokens) {
+		allowed = h.cfg.MaxTokens
+	}
+
+	if h.cfg.MaxTotalTokens > 0 {
+		remaining := h.cfg.MaxTotalTokens - h.used
+		if remaining <= 0 {
+			return 0, fmt.Errorf("sampling token budget of %d exhausted", h.cfg.MaxTotalTokens)
+		}
+		if allowed <= 0 || allowed > remaining {
+			allowed = remaining
+		}
+		h.used += allowed
+	}
+
+	return allowed, nil
+}
+
+// settle replaces the tokens reserved for a request with the tokens it used.
+func (h *samplingHandler) settle(reserved, used int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cfg.MaxTotalTokens > 0 {
+		h.used -= reserved
+	}
+	h.used += used
+}
+
+// newAgent builds a tool-less agent for a single sampling request.
+func (h *samplingHandler) newAgent(params *mcp.CreateMessageParams, maxTokens int64) (*chorus.Agent, error) {
+	opts := slices.Clone(h.agentOpts)
+	opts = append(opts, chorus.WithName("sampling"), chorus.WithRole(chorus.RoleAgent))
+	if h.agentOpts == nil && h.cfg.Model != "" {
+		opts = append(opts, chorus.WithModel(h.cfg.Model))
+	}
+
+	if params.SystemPrompt != "" {
+		opts = append(opts, chorus.WithSystemMessage(params.SystemPrompt))
+	}
+	if maxTokens > 0 {
+		opts = append(opts, chorus.WithMaxTokens(maxTokens))
+	}
+	if params.Temperature != 0 {
+		opts = append(opts, chorus.WithTemperature(params.Temperature))
+	}
+	if len(params.StopSequences) > 0 {
+		opts = append(opts, chorus.WithStop(params.StopSequences...))
+	}
+
+	agent := chorus.NewAgent(h.client, opts...)
+
+	for _, m := range params.Messages {
+		text, ok := m.Content.(*mcp.TextContent)
+		if !ok {
+			return nil, fmt.Errorf("unsupported sampling content type %T", m.Content)
+		}
+		switch m.Role {
+		case "user":
+			agent.Messages = append(agent.Messages, openai.UserMessage(text.Text))
+		case "assistant":
+			agent.Messages = append(agent.Messages, openai.AssistantMessage(text.Text))
+		default:
+			return nil, fmt.Errorf("unsupported sampling role %q", m.Role)
+		}
+	}
+
+	return agent, nil
+}
+
+// stopReason maps an OpenAI finish reason onto the MCP vocabulary.
+func stopReason(finishReason string) string {
+	switch finishReason {
+	case "stop":
+		return "endTurn"
+	case "length":
+		return "maxTokens"
+	default:
+		return finishReason
+	}
+}
+
+var promptMu sync.Mutex
+
+// openTerminal opens the controlling terminal.
+var openTerminal = func() (*os.File, error) {
+	return os.OpenFile("/dev/tty", os.O_RDWR, 0)
+}
+
+// promptApproval asks on the controlling terminal whether a sampling request
+// may proceed. If the terminal has gone away the request is denied.
+func promptApproval(server string, params *mcp.CreateMessageParams) bool {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	tty, err := openTerminal()
+	if err != nil {
+		mcpLog.Warn("No terminal available to approve sampling request; denied", "server", server, "error", err)
+		return false
+	}
+	defer tty.Close()
+
+	return askApproval(tty, tty, server, params)
+}
+
+func askApproval(in io.Reader, out io.Writer, server string, params *mcp.CreateMessageParams) bool {
+	fmt.Fprintf(out, "MCP server %q requests sampling (%d messages, max %d tokens).\n", server, len(params.Messages), params.MaxTokens)
+	if len(params.Messages) > 0 {
+		if text, ok := params.Messages[len(params.Messages)-1].Content.(*mcp.TextContent); ok {
+			fmt.Fprintf(out, "Last message: %s\n", text.Text)
+		}
+	}
+	fmt.Fprint(out, "Allow? [y/N] ")
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
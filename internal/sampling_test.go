@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openai/openai-go/v3"
+)
+
+type stubClient struct {
+	reply  string
+	tokens int64
+	params []openai.ChatCompletionNewParams
+}
+
+func (s *stubClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	s.params = append(s.params, params)
+	return &openai.ChatCompletion{
+		Model: params.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "stop",
+			Message:      openai.ChatCompletionMessage{Role: "assistant", Content: s.reply},
+		}},
+		Usage: openai.CompletionUsage{TotalTokens: s.tokens},
+	}, nil
+}
+
+func samplingParams(text string, maxTokens int64) *mcp.CreateMessageParams {
+	return &mcp.CreateMessageParams{
+		MaxTokens: maxTokens,
+		Messages: []*mcp.SamplingMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text}},
+		},
+	}
+}
+
+func TestSampling_UsesConfiguredAgent(t *testing.T) {
+	stub := &stubClient{reply: "sampled", tokens: 10}
+	cfg := &Config{
+		Agents:   []AgentConfig{{Name: "Helper", Model: "local/model", SystemMessage: "Be brief.", ReasoningEffort: "low"}},
+		Sampling: &SamplingConfig{Agent: "Helper", Approval: SamplingApprovalAuto, MaxTokens: 50},
+	}
+
+	h, err := newSamplingHandler(stub, cfg)
+	if err != nil {
+		t.Fatalf("newSamplingHandler failed: %v", err)
+	}
+
+	res, err := h.createMessage(context.Background(), "test", samplingParams("hello", 500))
+	if err != nil {
+		t.Fatalf("createMessage failed: %v", err)
+	}
+
+	if text, ok := res.Content.(*mcp.TextContent); !ok || text.Text != "sampled" {
+		t.Errorf("Unexpected content: %+v", res.Content)
+	}
+	if res.Model != "local/model" || res.StopReason != "endTurn" {
+		t.Errorf("Unexpected result metadata: model=%s stop=%s", res.Model, res.StopReason)
+	}
+
+	params := stub.params[0]
+	if params.ReasoningEffort != "low" {
+		t.Errorf("Expected the agent's reasoning effort, got %q", params.ReasoningEffort)
+	}
+	if params.MaxCompletionTokens.Value != 50 {
+		t.Errorf("Expected max tokens capped to 50, got %d", params.MaxCompletionTokens.Value)
+	}
+	// system message + user message
+	if len(params.Messages) != 2 || params.Messages[0].OfSystem == nil {
+		t.Errorf("Expected system and user messages, got %d", len(params.Messages))
+	}
+}
+
+func TestSampling_Denied(t *testing.T) {
+	stub := &stubClient{}
+	h, err := newSamplingHandler(stub, &Config{Sampling: &SamplingConfig{Approval: SamplingApprovalDeny}})
+	if err != nil {
+		t.Fatalf("newSamplingHandler failed: %v", err)
+	}
+
+	if _, err := h.createMessage(context.Background(), "test", samplingParams("hello", 10)); err == nil {
+		t.Error("Expected denial error")
+	}
+	if len(stub.params) != 0 {
+		t.Error("Denied request should not reach the model")
+	}
+}
+
+func TestSampling_TotalBudget(t *testing.T) {
+	stub := &stubClient{reply: "ok", tokens: 100}
+	h, err := newSamplingHandler(stub, &Config{Sampling: &SamplingConfig{Approval: SamplingApprovalAuto, MaxTotalTokens: 100}})
+	if err != nil {
+		t.Fatalf("newSamplingHandler failed: %v", err)
+	}
+
+	if _, err := h.createMessage(context.Background(), "test", samplingParams("one", 10)); err != nil {
+		t.Fatalf("First request failed: %v", err)
+	}
+	_, err = h.createMessage(context.Background(), "test", samplingParams("two", 10))
+	if err == nil || !strings.Contains(err.Error(), "budget") {
+		t.Errorf("Expected budget error, got %v", err)
+	}
+}
+
+func TestSampling_ReservesBudget(t *testing.T) {
+	h, err := newSamplingHandler(&stubClient{}, &Config{Sampling: &SamplingConfig{Approval: SamplingApprovalAuto, MaxTotalTokens: 100}})
+	if err != nil {
+		t.Fatalf("newSamplingHandler failed: %v", err)
+	}
+
+	// A request in progress holds its tokens, so another can't overspend.
+	if allowed, err := h.reserve(80); err != nil || allowed != 80 {
+		t.Fatalf("Expected 80 tokens, got %d, %v", allowed, err)
+	}
+	if allowed, err := h.reserve(80); err != nil || allowed != 20 {
+		t.Errorf("Expected the 20 tokens left, got %d, %v", allowed, err)
+	}
+	h.settle(80, 30)
+	h.settle(20, 0)
+	if allowed, err := h.reserve(0); err != nil || allowed != 70 {
+		t.Errorf("Expected the unused reservations back, got %d, %v", allowed, err)
+	}
+}
+
+func TestSampling_PromptNeedsTerminal(t *testing.T) {
+	open := openTerminal
+	t.Cleanup(func() { openTerminal = open })
+	openTerminal = func() (*os.File, error) { return nil, errors.New("no tty") }
+
+	_, err := newSamplingHandler(&stubClient{}, &Config{Sampling: &SamplingConfig{}})
+	if err == nil || !strings.Contains(err.Error(), "sampling.approval") {
+		t.Errorf("Expected an error about the approval policy, got %v", err)
+	}
+}
+
+func TestSampling_UnknownAgent(t *testing.T) {
+	_, err := newSamplingHandler(&stubClient{}, &Config{Sampling: &SamplingConfig{Agent: "Missing"}})
+	if err == nil {
+		t.Error("Expected error for unknown sampling agent")
+	}
+}
+
+func TestAskApproval(t *testing.T) {
+	var out strings.Builder
+	if !askApproval(strings.NewReader("y\n"), &out, "files", samplingParams("hi", 10)) {
+		t.Error("Expected approval for 'y'")
+	}
+	if askApproval(strings.NewReader("\n"), &out, "files", samplingParams("hi", 10)) {
+		t.Error("Expected denial by default")
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Template is a starting point for a new chorus project.
+type Template struct {
+	Name        string
+	Description string
+}
+
+// templates lists the built-in templates; the first is the default. Each has
+// a directory of the same name under templates/.
+var templates = []Template{
+	{Name: "review", Description: "a teacher writes educational content and a professor reviews it"},
+	{Name: "coding", Description: "a planner, coder and reviewer build software in the workspace"},
+	{Name: "debate", Description: "two debaters argue a question and a moderator sums up"},
+	{Name: "research", Description: "a lead delegates web research over MCP and writes a report"},
+}
+
+// Templates returns the built-in project templates, the default first.
+func Templates() []Template {
+	return slices.Clone(templates)
+}
+
+// Scaffold writes the named template into dir: a config file, its prompts and
+// an empty workspace directory. An empty name picks the default template.
+// Existing files are only overwritten when force is set; otherwise nothing is
+// written. It returns the paths written.
+func Scaffold(name, dir string, force bool) ([]string, error) {
+	if name == "" {
+		name = templates[0].Name
+	}
+	if !slices.ContainsFunc(templates, func(t Template) bool { return t.Name == name }) {
+		return nil, fmt.Errorf("unknown template %q (see chorus init --list)", name)
+	}
+
+	root := path.Join("templates", name)
+	var files []string
+	err := fs.WalkDir(templateFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := p[len(root)+1:]
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !force {
+		for _, rel := range files {
+			if _, err := os.Stat(filepath.Join(dir, rel)); err == nil {
+				return nil, fmt.Errorf("%s already exists (use --force to overwrite)", filepath.Join(dir, rel))
+			}
+		}
+	}
+
+	var written []string
+	for _, rel := range files {
+		data, err := templateFS.ReadFile(path.Join(root, rel))
+		if err != nil {
+			return written, err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return written, err
+		}
+		written = append(written, target)
+	}
+
+	workspace := filepath.Join(dir, chorus.WorkspaceDir)
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return written, err
+	}
+	return append(written, workspace+string(filepath.Separator)), nil
+}
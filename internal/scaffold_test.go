@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+func TestScaffold_TemplatesAreValid(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			dir := t.TempDir()
+			written, err := Scaffold(tmpl.Name, dir, false)
+			if err != nil {
+				t.Fatalf("Scaffold failed: %v", err)
+			}
+			if len(written) < 3 {
+				t.Errorf("Expected a config, prompts and a workspace, got %v", written)
+			}
+
+			// Prompt files are resolved from the working directory.
+			t.Chdir(dir)
+			t.Setenv("CHORUS_BASE_URL", "")
+			cfg, _, err := LoadConfig("", "")
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			err = cfg.ValidateConversation()
+			for _, e := range paths(t, err) {
+				// The MCP server commands may not be installed here.
+				if !strings.HasPrefix(e, "mcp_servers[") {
+					t.Errorf("Template config is invalid:\n%v", err)
+					break
+				}
+			}
+			if info, err := os.Stat(chorus.WorkspaceDir); err != nil || !info.IsDir() {
+				t.Error("Expected a workspace directory")
+			}
+		})
+	}
+}
+
+func TestScaffold_KeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := writeFile(t, dir, "config.yaml", "mine")
+
+	if _, err := Scaffold("", dir, false); err == nil {
+		t.Fatal("Expected an error for an existing config")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "prompts")); err == nil {
+		t.Error("Expected nothing to be written")
+	}
+
+	if _, err := Scaffold("", dir, true); err != nil {
+		t.Fatalf("Scaffold with force failed: %v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) == "mine" {
+		t.Error("Expected force to overwrite the config")
+	}
+}
+
+func TestScaffold_UnknownTemplate(t *testing.T) {
+	if _, err := Scaffold("nope", t.TempDir(), false); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+)
+
+// schemaEnums lists the allowed values of settings that take one of a fixed
+// set of strings, keyed by struct type and field name.
+var schemaEnums = map[string][]any{
+	"AgentConfig.ReasoningEffort": func() []any {
+		values := make([]any, len(reasoningEfforts))
+		for i, effort := range reasoningEfforts {
+			values[i] = string(effort)
+		}
+		return values
+	}(),
+	"ResponseFormatConfig.Type": {ResponseFormatText, ResponseFormatJSONObject, ResponseFormatJSONSchema},
+	"SamplingConfig.Approval":   {SamplingApprovalAuto, SamplingApprovalPrompt, SamplingApprovalDeny},
+}
+
+// ConfigSchema returns a JSON Schema describing the config file, for editor
+// completion and validation.
+func ConfigSchema() map[string]any {
+	schema := typeSchema(reflect.TypeFor[Config]())
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Chorus configuration"
+	return schema
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			property := typeSchema(field.Type)
+			if enum, ok := schemaEnums[t.Name()+"."+field.Name]; ok {
+				property["enum"] = enum
+			}
+			properties[name] = property
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]any{}
+	}
+}
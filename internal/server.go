@@ -0,0 +1,635 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/log"
+)
+
+// Conversation statuses reported by the HTTP API.
+const (
+	StatusIdle      = "idle"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// maxRequestBody bounds the JSON bodies the HTTP API accepts.
+const maxRequestBody = 1 << 20
+
+// ServerOptions configures the HTTP API.
+type ServerOptions struct {
+	// Token, if set, must be sent as a bearer token with every request.
+	Token string
+	// TTL is how long an unused agent or finished conversation is kept. Zero
+	// keeps them until they are deleted.
+	TTL time.Duration
+}
+
+// Server serves the chorus HTTP API: agents to chat with and orchestrated
+// conversations that run in the background, both kept in memory by ID, and an
+// OpenAI-compatible endpoint that offers each configured team as a model.
+type Server struct {
+	app           *App
+	opts          ServerOptions
+	ctx           context.Context
+	agents        *registry[*agentSession]
+	conversations *registry[*conversationRun]
+}
+
+// AgentSession is an agent created through the API. It keeps its history
+// across messages.
+type AgentSession struct {
+	ID          string                                   `json:"id"`
+	Agent       string                                   `json:"agent"`
+	Model       string                                   `json:"model,omitempty"`
+	TotalTokens int64                                    `json:"total_tokens,omitempty"`
+	CreatedAt   time.Time                                `json:"created_at"`
+	Messages    []openai.ChatCompletionMessageParamUnion `json:"messages,omitempty"`
+}
+
+// ConversationStatus describes a conversation created through the API.
+type ConversationStatus struct {
+	ID          string       `json:"id"`
+	Status      string       `json:"status"`
+	Agents      []string     `json:"agents"`
+	Workspace   string       `json:"workspace"`
+	Objective   string       `json:"objective,omitempty"`
+	Result      string       `json:"result,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	TotalTokens int64        `json:"total_tokens"`
+	Plan        *chorus.Plan `json:"plan,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Transcript is the message history of every agent in a conversation.
+type Transcript struct {
+	ID     string            `json:"id"`
+	Agents []AgentTranscript `json:"agents"`
+}
+
+// AgentTranscript is one agent's messages, system messages included.
+type AgentTranscript struct {
+	Name     string                                   `json:"name"`
+	Messages []openai.ChatCompletionMessageParamUnion `json:"messages"`
+}
+
+type createAgentRequest struct {
+	Agent string `json:"agent"`
+}
+
+type createConversationRequest struct {
+	Agents    []string `json:"agents,omitempty"`
+	Objective string   `json:"objective,omitempty"`
+}
+
+type runRequest struct {
+	Objective string `json:"objective"`
+}
+
+type messageRequest struct {
+	Agent   string `json:"agent,omitempty"`
+	Content string `json:"content"`
+}
+
+type messageResponse struct {
+	Reply       string `json:"reply"`
+	TotalTokens int64  `json:"total_tokens"`
+}
+
+// agentSession is an agent created through the API.
+type agentSession struct {
+	id      string
+	created time.Time
+
+	// mu keeps messages to the agent one at a time.
+	mu      sync.Mutex
+	agent   *chorus.Agent
+	version uint64
+}
+
+// conversationRun is a conversation created through the API. While active,
+// a run or a message owns the agents and nothing else may touch them.
+type conversationRun struct {
+	conv   *chorus.Conversation
+	agents []*chorus.Agent
+	ctx    context.Context // done when the conversation is deleted or evicted
+	cancel context.CancelFunc
+	done   chan struct{} // closed when a started run ends
+	events *eventLog
+
+	mu        sync.Mutex
+	active    bool
+	status    string
+	objective string
+	result    string
+	err       string
+	tokens    int64
+	created   time.Time
+	updated   time.Time
+}
+
+// NewServer creates the HTTP API. Runs are cancelled when ctx is done, and
+// unused agents and finished conversations are evicted after opts.TTL.
+func (app *App) NewServer(ctx context.Context, opts ServerOptions) *Server {
+	s := &Server{
+		app:           app,
+		opts:          opts,
+		ctx:           ctx,
+		agents:        newRegistry[*agentSession](opts.TTL),
+		conversations: newRegistry[*conversationRun](opts.TTL),
+	}
+	if opts.TTL > 0 {
+		go s.evictLoop(ctx, min(opts.TTL/2, time.Minute))
+	}
+	return s
+}
+
+// Handler routes the API's endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/agents", s.listAgents)
+	mux.HandleFunc("POST /v1/agents", s.createAgent)
+	mux.HandleFunc("GET /v1/agents/{id}", s.getAgent)
+	mux.HandleFunc("DELETE /v1/agents/{id}", s.deleteAgent)
+	mux.HandleFunc("POST /v1/agents/{id}/messages", s.postAgentMessage)
+
+	mux.HandleFunc("GET /v1/conversations", s.listConversations)
+	mux.HandleFunc("POST /v1/conversations", s.createConversation)
+	mux.HandleFunc("GET /v1/conversations/{id}", s.getConversation)
+	mux.HandleFunc("DELETE /v1/conversations/{id}", s.deleteConversation)
+	mux.HandleFunc("POST /v1/conversations/{id}/messages", s.postConversationMessage)
+	mux.HandleFunc("POST /v1/conversations/{id}/run", s.runConversation)
+	mux.HandleFunc("POST /v1/conversations/{id}/cancel", s.cancelConversation)
+	mux.HandleFunc("GET /v1/conversations/{id}/transcript", s.getTranscript)
+	mux.HandleFunc("GET /v1/conversations/{id}/events", s.streamEvents)
+
+	mux.HandleFunc("GET /v1/models", s.listModels)
+	mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+
+	mux.Handle("GET /metrics", s.app.metrics.handler())
+
+	if s.opts.Token == "" {
+		return mux
+	}
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the configured bearer token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) evictLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evict()
+		}
+	}
+}
+
+// evict forgets agents and conversations unused for longer than the TTL.
+// Conversations are kept while a run or message is in progress.
+func (s *Server) evict() {
+	for _, session := range s.agents.evict(func(*agentSession) bool { return true }) {
+		log.Debug("Evicted agent", "id", session.id)
+	}
+	evicted := s.conversations.evict(func(run *conversationRun) bool {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		return !run.active
+	})
+	for _, run := range evicted {
+		go run.close()
+		log.Debug("Evicted conversation", "conversation", run.conv.ID())
+	}
+}
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	sessions := []AgentSession{}
+	for _, session := range s.agents.list() {
+		sessions = append(sessions, session.describe(false))
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (s *Server) createAgent(w http.ResponseWriter, r *http.Request) {
+	var req createAgentRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	cfg, version := s.app.configVersion()
+	i := slices.IndexFunc(cfg.Agents, func(a AgentConfig) bool { return a.Name == req.Agent })
+	if i < 0 {
+		writeError(w, http.StatusBadRequest, "agent %q not found", req.Agent)
+		return
+	}
+	agent, err := s.app.newAgent(cfg, cfg.Agents[i])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "creating agent: %v", err)
+		return
+	}
+
+	session := &agentSession{id: newID(), created: time.Now(), agent: agent, version: version}
+	s.agents.add(session.id, session)
+	writeJSON(w, http.StatusCreated, session.describe(false))
+}
+
+func (s *Server) getAgent(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.agents.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "agent %q not found", r.PathValue("id"))
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	s.writeRedacted(w, http.StatusOK, session.describe(true))
+}
+
+func (s *Server) deleteAgent(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.agents.remove(r.PathValue("id")); !ok {
+		writeError(w, http.StatusNotFound, "agent %q not found", r.PathValue("id"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) postAgentMessage(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.agents.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "agent %q not found", r.PathValue("id"))
+		return
+	}
+	var req messageRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	var err error
+	session.version, err = s.app.refreshAgent(session.agent, session.version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "refreshing agent: %v", err)
+		return
+	}
+	reply, err := session.agent.Respond(r.Context(), chorus.WithUserMessage(req.Content))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	s.writeRedacted(w, http.StatusOK, messageResponse{Reply: reply, TotalTokens: session.agent.TotalTokens})
+}
+
+// describe reports the session; the caller must hold its lock if messages
+// are included.
+func (session *agentSession) describe(messages bool) AgentSession {
+	desc := AgentSession{
+		ID:        session.id,
+		Agent:     session.agent.Name,
+		CreatedAt: session.created,
+	}
+	if messages {
+		desc.Model = session.agent.Model
+		desc.TotalTokens = session.agent.TotalTokens
+		desc.Messages = session.agent.Messages
+	}
+	return desc
+}
+
+func (s *Server) listConversations(w http.ResponseWriter, r *http.Request) {
+	statuses := []ConversationStatus{}
+	for _, run := range s.conversations.list() {
+		statuses = append(statuses, run.describe())
+	}
+	s.writeRedacted(w, http.StatusOK, statuses)
+}
+
+func (s *Server) createConversation(w http.ResponseWriter, r *http.Request) {
+	var req createConversationRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	agents, err := s.app.newAgentsNamed(req.Agents)
+	if errors.Is(err, ErrUnknownAgent) {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	conv, err := s.app.newLimitedConversation(ctx, agents...)
+	if err != nil {
+		cancel()
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	now := time.Now()
+	run := &conversationRun{
+		conv:    conv,
+		agents:  agents,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		events:  newEventLog(),
+		status:  StatusIdle,
+		created: now,
+		updated: now,
+	}
+	conv.Events().Subscribe(run.events.append)
+	s.conversations.add(conv.ID(), run)
+
+	if req.Objective == "" {
+		s.writeRedacted(w, http.StatusCreated, run.describe())
+		return
+	}
+	s.start(run, req.Objective)
+	s.writeRedacted(w, http.StatusAccepted, run.describe())
+}
+
+func (s *Server) getConversation(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	s.writeRedacted(w, http.StatusOK, run.describe())
+}
+
+func (s *Server) deleteConversation(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversations.remove(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "conversation %q not found", r.PathValue("id"))
+		return
+	}
+	go run.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) postConversationMessage(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	var req messageRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	i := slices.IndexFunc(run.agents, func(a *chorus.Agent) bool { return a.Name == req.Agent })
+	if i < 0 {
+		writeError(w, http.StatusBadRequest, "agent %q is not in this conversation", req.Agent)
+		return
+	}
+
+	if !run.acquire() {
+		writeError(w, http.StatusConflict, "conversation is busy")
+		return
+	}
+	reply, err := run.agents[i].Respond(r.Context(), chorus.WithUserMessage(req.Content))
+	tokens := run.conv.TotalTokens()
+	run.release(func() { run.tokens = tokens })
+
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "%v", err)
+		return
+	}
+	s.writeRedacted(w, http.StatusOK, messageResponse{Reply: reply, TotalTokens: tokens})
+}
+
+func (s *Server) runConversation(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	var req runRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Objective == "" {
+		writeError(w, http.StatusBadRequest, "objective is required")
+		return
+	}
+
+	if !s.start(run, req.Objective) {
+		writeError(w, http.StatusConflict, "conversation has already run or is busy")
+		return
+	}
+	s.writeRedacted(w, http.StatusAccepted, run.describe())
+}
+
+func (s *Server) cancelConversation(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	run.mu.Lock()
+	running := run.status == StatusRunning
+	run.mu.Unlock()
+	if !running {
+		writeError(w, http.StatusConflict, "conversation is not running")
+		return
+	}
+	run.cancel()
+	s.writeRedacted(w, http.StatusAccepted, run.describe())
+}
+
+func (s *Server) getTranscript(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.conversation(w, r)
+	if !ok {
+		return
+	}
+	if !run.acquire() {
+		writeError(w, http.StatusConflict, "conversation is busy; fetch the transcript when it is idle")
+		return
+	}
+	defer run.release(nil)
+
+	transcript := Transcript{ID: run.conv.ID(), Agents: make([]AgentTranscript, len(run.agents))}
+	for i, agent := range run.agents {
+		transcript.Agents[i] = AgentTranscript{Name: agent.Name, Messages: agent.Messages}
+	}
+	s.writeRedacted(w, http.StatusOK, transcript)
+}
+
+// conversation looks up the conversation named in the request path, replying
+// with 404 if there is none.
+func (s *Server) conversation(w http.ResponseWriter, r *http.Request) (*conversationRun, bool) {
+	run, ok := s.conversations.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "conversation %q not found", r.PathValue("id"))
+	}
+	return run, ok
+}
+
+// start runs the conversation for objective in the background. It reports
+// false if the conversation is busy.
+func (s *Server) start(run *conversationRun, objective string) bool {
+	run.mu.Lock()
+	if run.active || run.status != StatusIdle {
+		run.mu.Unlock()
+		return false
+	}
+	run.active = true
+	run.status = StatusRunning
+	run.objective = objective
+	run.updated = time.Now()
+	run.mu.Unlock()
+
+	id := run.conv.ID()
+	log.Info("Conversation started", "conversation", id, "workspace", run.conv.Workspace())
+
+	go func() {
+		defer close(run.done)
+
+		result, err := s.app.run(run.ctx, run.conv, objective)
+		tokens := run.conv.TotalTokens()
+
+		run.release(func() {
+			run.tokens = tokens
+			switch {
+			case err == nil:
+				run.status = StatusCompleted
+				run.result = result
+			case errors.Is(err, context.Canceled):
+				run.status = StatusCancelled
+				run.err = err.Error()
+			default:
+				run.status = StatusFailed
+				run.err = err.Error()
+			}
+		})
+		// The TTL counts from when the run ends.
+		s.conversations.touch(id)
+
+		if err != nil {
+			log.Error("Conversation failed", "conversation", id, "error", err)
+		} else {
+			log.Info("Conversation finished", "conversation", id, "tokens", tokens)
+		}
+	}()
+	return true
+}
+
+// close cancels the conversation and, once a started run has ended, closes
+// it so its agents stop reporting to it.
+func (run *conversationRun) close() {
+	run.cancel()
+	run.mu.Lock()
+	started := run.status != StatusIdle
+	run.mu.Unlock()
+	if started {
+		<-run.done
+	}
+	run.conv.Close()
+}
+
+// acquire takes ownership of the conversation's agents, reporting false if a
+// run or another message already has them.
+func (run *conversationRun) acquire() bool {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.active || run.status == StatusRunning {
+		return false
+	}
+	run.active = true
+	return true
+}
+
+// release gives up ownership of the agents after applying update, if any.
+func (run *conversationRun) release(update func()) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if update != nil {
+		update()
+	}
+	run.active = false
+	run.updated = time.Now()
+}
+
+func (run *conversationRun) describe() ConversationStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	status := ConversationStatus{
+		ID:          run.conv.ID(),
+		Status:      run.status,
+		Agents:      make([]string, len(run.agents)),
+		Workspace:   run.conv.Workspace(),
+		Objective:   run.objective,
+		Result:      run.result,
+		Error:       run.err,
+		TotalTokens: run.tokens,
+		Plan:        run.conv.Plan(),
+		CreatedAt:   run.created,
+		UpdatedAt:   run.updated,
+	}
+	for i, agent := range run.agents {
+		status.Agents[i] = agent.Name
+	}
+	return status
+}
+
+// readJSON decodes the request body into v, replying with 400 if it is not
+// valid. Unknown fields are rejected.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response", "error", err)
+	}
+}
+
+// writeRedacted writes v as JSON with its secrets masked, for responses that
+// carry what was said in a conversation.
+func (s *Server) writeRedacted(w http.ResponseWriter, code int, v any) {
+	data, err := s.app.redactor.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to encode response: %v", err)
+		return
+	}
+	writeJSON(w, code, json.RawMessage(data))
+}
+
+func writeError(w http.ResponseWriter, code int, format string, args ...any) {
+	writeJSON(w, code, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
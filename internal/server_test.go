@@ -0,0 +1,400 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/client"
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+// finishClient makes the orchestrator finish straight away, or with block
+// set, waits for the run to be cancelled.
+type finishClient struct {
+	block bool
+}
+
+func (c *finishClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if c.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "tool_calls",
+			Message: openai.ChatCompletionMessage{
+				Role: "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnion{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: "Finish", Arguments: `{"result":"done"}`},
+				}},
+			},
+		}},
+		Usage: openai.CompletionUsage{TotalTokens: 7},
+	}, nil
+}
+
+func newTestServer(t *testing.T, c client.Client, opts ServerOptions) *Server {
+	t.Helper()
+	// Conversations create their workspace under the working directory.
+	t.Chdir(t.TempDir())
+
+	cfg := &Config{Agents: []AgentConfig{
+		{Name: "Teacher", Role: "orchestrator", SystemMessage: "You lead."},
+		{Name: "Professor", SystemMessage: "You review."},
+	}, Teams: []TeamConfig{
+		{Name: "review", Agents: []string{"Teacher", "Professor"}},
+	}}
+	app := &App{cfg: cfg, client: c}
+	return app.NewServer(t.Context(), opts)
+}
+
+func request(t *testing.T, s *Server, method, path, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// waitForRun waits for the conversation's run to end.
+func waitForRun(t *testing.T, s *Server, id string) {
+	t.Helper()
+	run, ok := s.conversations.get(id)
+	if !ok {
+		t.Fatalf("conversation %s not found", id)
+	}
+	select {
+	case <-run.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("conversation %s did not finish", id)
+	}
+}
+
+func TestServer_RunConversation(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+
+	var created ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{}`, &created); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	if created.Status != StatusIdle || len(created.Agents) != 2 {
+		t.Fatalf("Expected an idle conversation with both agents, got %+v", created)
+	}
+
+	path := "/v1/conversations/" + created.ID
+	var started ConversationStatus
+	if code := request(t, s, "POST", path+"/run", `{"objective":"teach"}`, &started); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, created.ID)
+
+	var status ConversationStatus
+	request(t, s, "GET", path, "", &status)
+	if status.Status != StatusCompleted || status.Result != "done" || status.TotalTokens != 7 {
+		t.Errorf("Expected a completed run, got %+v", status)
+	}
+
+	if code := request(t, s, "POST", path+"/run", `{"objective":"again"}`, nil); code != http.StatusConflict {
+		t.Errorf("Expected a second run to conflict, got %d", code)
+	}
+
+	var transcript Transcript
+	request(t, s, "GET", path+"/transcript", "", &transcript)
+	if len(transcript.Agents) != 2 || transcript.Agents[0].Name != "Teacher" {
+		t.Fatalf("Expected both agents in the transcript, got %+v", transcript)
+	}
+	// System message, objective, the Finish call and its result.
+	if got := len(transcript.Agents[0].Messages); got != 4 {
+		t.Errorf("Expected 4 orchestrator messages, got %d", got)
+	}
+}
+
+func TestServer_ConversationsShareMCPServers(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+	s.app.servers = []*mcpServer{testMCPServer()}
+
+	var idle, running ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{}`, &idle); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	if code := request(t, s, "POST", "/v1/conversations", `{"objective":"teach"}`, &running); code != http.StatusAccepted {
+		t.Fatalf("Expected a second conversation to start, got %d", code)
+	}
+	waitForRun(t, s, running.ID)
+	if code := request(t, s, "POST", "/v1/conversations/"+idle.ID+"/run", `{"objective":"teach"}`, nil); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, idle.ID)
+
+	for _, id := range []string{idle.ID, running.ID} {
+		var status ConversationStatus
+		request(t, s, "GET", "/v1/conversations/"+id, "", &status)
+		if status.Status != StatusCompleted {
+			t.Errorf("Expected conversation %s to complete, got %+v", id, status)
+		}
+	}
+}
+
+func TestServer_TranscriptRedacted(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+	s.app.redactor = redact.Default()
+
+	var status ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{"objective":"mail bob@example.com"}`, &status); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, status.ID)
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/v1/conversations/"+status.ID+"/transcript", nil))
+	body := rec.Body.String()
+	if strings.Contains(body, "bob@example.com") || !strings.Contains(body, "mail [REDACTED]") {
+		t.Errorf("Expected the email to be redacted, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/v1/conversations/"+status.ID, nil))
+	if body := rec.Body.String(); strings.Contains(body, "bob@example.com") {
+		t.Errorf("Expected the email to be redacted from the status, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/v1/conversations/"+status.ID+"/events", nil))
+	if events := rec.Body.String(); strings.Contains(events, "bob@example.com") || !strings.Contains(events, "mail [REDACTED]") {
+		t.Errorf("Expected the email to be redacted from the events, got %s", events)
+	}
+}
+
+func TestServer_AgentRedacted(t *testing.T) {
+	s := newTestServer(t, &stubClient{reply: "Sent to bob@example.com."}, ServerOptions{})
+	s.app.redactor = redact.Default()
+
+	var session AgentSession
+	if code := request(t, s, "POST", "/v1/agents", `{"agent":"Professor"}`, &session); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	path := "/v1/agents/" + session.ID
+
+	var reply messageResponse
+	if code := request(t, s, "POST", path+"/messages", `{"content":"mail bob@example.com"}`, &reply); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if reply.Reply != "Sent to [REDACTED]." {
+		t.Errorf("Expected the reply to be redacted, got %q", reply.Reply)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	if body := rec.Body.String(); strings.Contains(body, "bob@example.com") || !strings.Contains(body, "mail [REDACTED]") {
+		t.Errorf("Expected the email to be redacted from the history, got %s", body)
+	}
+}
+
+func TestServer_CancelConversation(t *testing.T) {
+	s := newTestServer(t, &finishClient{block: true}, ServerOptions{})
+
+	var status ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{"objective":"teach"}`, &status); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	path := "/v1/conversations/" + status.ID
+
+	if code := request(t, s, "GET", path+"/transcript", "", nil); code != http.StatusConflict {
+		t.Errorf("Expected the transcript to wait for the run, got %d", code)
+	}
+	if code := request(t, s, "POST", path+"/cancel", "", nil); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, status.ID)
+
+	request(t, s, "GET", path, "", &status)
+	if status.Status != StatusCancelled {
+		t.Errorf("Expected a cancelled run, got %+v", status)
+	}
+
+	if code := request(t, s, "DELETE", path, "", nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", code)
+	}
+	if code := request(t, s, "GET", path, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", code)
+	}
+}
+
+func TestServer_ConversationErrors(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+	// A configured agent that can't be built is the server's problem.
+	s.app.cfg.Agents = append(s.app.cfg.Agents, AgentConfig{Name: "Broken", ReasoningEffort: "huge"})
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"agents":["Nobody"]}`, http.StatusBadRequest},
+		{`{"agents":["Teacher","Broken"]}`, http.StatusInternalServerError},
+		{`{"agents":["Professor"]}`, http.StatusBadRequest},
+		{`{"objective":1}`, http.StatusBadRequest},
+		{`{"unknown":true}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := request(t, s, "POST", "/v1/conversations", tt.body, nil); code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, code)
+		}
+	}
+}
+
+func TestServer_AgentMessages(t *testing.T) {
+	s := newTestServer(t, &stubClient{reply: "hello back", tokens: 3}, ServerOptions{})
+
+	var session AgentSession
+	if code := request(t, s, "POST", "/v1/agents", `{"agent":"Professor"}`, &session); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	path := "/v1/agents/" + session.ID
+
+	var reply messageResponse
+	if code := request(t, s, "POST", path+"/messages", `{"content":"hello"}`, &reply); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if reply.Reply != "hello back" || reply.TotalTokens != 3 {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+
+	request(t, s, "GET", path, "", &session)
+	if len(session.Messages) != 3 {
+		t.Errorf("Expected the system message, the message and the reply, got %d messages", len(session.Messages))
+	}
+
+	if code := request(t, s, "POST", "/v1/agents", `{"agent":"Nobody"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown agent, got %d", code)
+	}
+	if code := request(t, s, "POST", "/v1/agents/missing/messages", `{"content":"hi"}`, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing agent, got %d", code)
+	}
+}
+
+func TestServer_ConversationMessages(t *testing.T) {
+	s := newTestServer(t, &stubClient{reply: "noted", tokens: 2}, ServerOptions{})
+
+	var status ConversationStatus
+	request(t, s, "POST", "/v1/conversations", `{}`, &status)
+	path := "/v1/conversations/" + status.ID
+
+	var reply messageResponse
+	if code := request(t, s, "POST", path+"/messages", `{"agent":"Professor","content":"hi"}`, &reply); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if reply.Reply != "noted" || reply.TotalTokens != 2 {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+	if code := request(t, s, "POST", path+"/messages", `{"agent":"Nobody","content":"hi"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an agent outside the conversation, got %d", code)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{Token: "secret"})
+
+	if code := request(t, s, "GET", "/v1/conversations", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/conversations", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with the token, got %d", rec.Code)
+	}
+}
+
+func TestServer_Evict(t *testing.T) {
+	s := newTestServer(t, &finishClient{block: true}, ServerOptions{})
+	now := time.Now()
+	s.conversations.ttl = time.Minute
+	s.conversations.now = func() time.Time { return now }
+
+	var idle, running ConversationStatus
+	request(t, s, "POST", "/v1/conversations", `{}`, &idle)
+	request(t, s, "POST", "/v1/conversations", `{"objective":"teach"}`, &running)
+
+	now = now.Add(2 * time.Minute)
+	s.evict()
+
+	if _, ok := s.conversations.get(idle.ID); ok {
+		t.Error("Expected the idle conversation to be evicted")
+	}
+	run, ok := s.conversations.get(running.ID)
+	if !ok {
+		t.Fatal("Expected the running conversation to be kept")
+	}
+	run.cancel()
+	waitForRun(t, s, running.ID)
+}
+
+func TestServer_Events(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+
+	var status ConversationStatus
+	request(t, s, "POST", "/v1/conversations", `{"objective":"teach"}`, &status)
+	waitForRun(t, s, status.ID)
+
+	stream := func(lastEventID string) string {
+		req := httptest.NewRequest("GET", "/v1/conversations/"+status.ID+"/events", nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %d %s: %s", rec.Code, ct, rec.Body)
+		}
+		return rec.Body.String()
+	}
+
+	// The objective, run_started, turn_started, generated, the Finish call as
+	// a message, tool_call and tool_result, the tool message and finished.
+	all := stream("")
+	if strings.Count(all, "event: ") != 9 || !strings.Contains(all, "id: 9\nevent: finished\ndata: {") {
+		t.Errorf("Unexpected stream:\n%s", all)
+	}
+	if resumed := stream("8"); strings.Count(resumed, "event: ") != 1 || !strings.Contains(resumed, `"text":"done"`) {
+		t.Errorf("Expected only the finished event after resuming, got:\n%s", resumed)
+	}
+}
+
+func TestEventLog_DropsDeltas(t *testing.T) {
+	l := newEventLog()
+	for i, ev := range []chorus.Event{
+		{Type: chorus.EventDelta, Agent: "Writer", Text: "hel"},
+		{Type: chorus.EventDelta, Agent: "Lead", Text: "pla"},
+		{Type: chorus.EventDelta, Agent: "Writer", Text: "lo"},
+		{Type: chorus.EventMessage, Agent: "Writer"},
+	} {
+		ev.Seq = int64(i + 1)
+		l.append(ev)
+	}
+
+	events, _, _ := l.after(0)
+	if len(events) != 2 || events[0].Agent != "Lead" || events[1].Type != chorus.EventMessage {
+		t.Errorf("Expected the Writer's deltas replaced by its message, got %+v", events)
+	}
+
+	l.append(chorus.Event{Seq: 5, Type: chorus.EventFinished})
+	if events, _, _ := l.after(0); len(events) != 2 || events[0].Type != chorus.EventMessage {
+		t.Errorf("Expected no deltas once the run ended, got %+v", events)
+	}
+}
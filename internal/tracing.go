@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/standrze/chorus/pkg/redact"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+)
+
+var tracer = otel.Tracer("github.com/standrze/chorus/internal")
+
+// mcpServerKey names the MCP server a traced tool call went to.
+const mcpServerKey = attribute.Key("chorus.mcp.server")
+
+// TracingConfig writes OpenTelemetry spans to a file in the OTLP JSON format,
+// so runs can be traced without a collector. Each line holds one export
+// request and can be replayed into any OTLP/HTTP JSON endpoint.
+type TracingConfig struct {
+	// File receives the spans; "-" writes them to stderr, keeping stdout for
+	// results and the MCP protocol. Empty disables tracing.
+	File string `mapstructure:"file"`
+}
+
+// startTracing installs a global tracer provider that exports to the
+// configured file. The returned function flushes and closes it.
+func startTracing(cfg TracingConfig, redactor *redact.Redactor) (shutdown func(context.Context) error, err error) {
+	var w io.Writer = os.Stderr
+	var closer io.Closer
+	if cfg.File != "-" {
+		f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		w, closer = f, f
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("chorus"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&otlpFileExporter{w: w, closer: closer, redactor: redactor}),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// otlpFileExporter writes each batch of spans as a line of OTLP JSON, the
+// encoding of an ExportTraceServiceRequest.
+type otlpFileExporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // nil for stderr
+	// redactor masks secrets in span attributes, events and statuses.
+	redactor *redact.Redactor
+}
+
+func (e *otlpFileExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	req := otlpRequest(spans)
+	req.redact(e.redactor)
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+func (e *otlpFileExporter) Shutdown(ctx context.Context) error {
+	if e.closer == nil {
+		return nil
+	}
+	return e.closer.Close()
+}
+
+// The OTLP JSON encoding differs from plain protobuf JSON: ids are hex,
+// 64-bit integers are strings and enums are numbers.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+		SchemaURL  string           `json:"schemaUrl,omitempty"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope     otlpScope  `json:"scope"`
+		Spans     []otlpSpan `json:"spans"`
+		SchemaURL string     `json:"schemaUrl,omitempty"`
+	}
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		TraceState        string         `json:"traceState,omitempty"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Events            []otlpEvent    `json:"events,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string     `json:"stringValue,omitempty"`
+		BoolValue   *bool       `json:"boolValue,omitempty"`
+		IntValue    *string     `json:"intValue,omitempty"`
+		DoubleValue *float64    `json:"doubleValue,omitempty"`
+		ArrayValue  *otlpValues `json:"arrayValue,omitempty"`
+	}
+	otlpValues struct {
+		Values []otlpValue `json:"values"`
+	}
+)
+
+// redact masks the secrets in the spans. Resource attributes describe the
+// process and are left alone.
+func (t *otlpTraces) redact(r *redact.Redactor) {
+	if r == nil {
+		return
+	}
+	for _, rs := range t.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for i := range ss.Spans {
+				span := &ss.Spans[i]
+				redactAttributes(r, span.Attributes)
+				for _, ev := range span.Events {
+					redactAttributes(r, ev.Attributes)
+				}
+				span.Status.Message = r.String(span.Status.Message)
+			}
+		}
+	}
+}
+
+func redactAttributes(r *redact.Redactor, attrs []otlpKeyValue) {
+	for i := range attrs {
+		kv := &attrs[i]
+		if r.SecretKey(kv.Key) {
+			mask := redact.Mask
+			kv.Value = otlpValue{StringValue: &mask}
+			continue
+		}
+		if s := kv.Value.StringValue; s != nil {
+			redacted := r.String(*s)
+			kv.Value.StringValue = &redacted
+		}
+		if arr := kv.Value.ArrayValue; arr != nil {
+			for j := range arr.Values {
+				if s := arr.Values[j].StringValue; s != nil {
+					redacted := r.String(*s)
+					arr.Values[j].StringValue = &redacted
+				}
+			}
+		}
+	}
+}
+
+// otlpRequest groups spans by resource and instrumentation scope.
+func otlpRequest(spans []sdktrace.ReadOnlySpan) otlpTraces {
+	var req otlpTraces
+	resources := map[*resource.Resource]int{}
+	scopes := map[*resource.Resource]map[instrumentation.Scope]int{}
+
+	for _, span := range spans {
+		res := span.Resource()
+		r, ok := resources[res]
+		if !ok {
+			r = len(req.ResourceSpans)
+			resources[res] = r
+			scopes[res] = map[instrumentation.Scope]int{}
+			req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+				Resource:  otlpResource{Attributes: otlpAttributes(res.Attributes())},
+				SchemaURL: res.SchemaURL(),
+			})
+		}
+
+		rs := &req.ResourceSpans[r]
+		scope := span.InstrumentationScope()
+		s, ok := scopes[res][scope]
+		if !ok {
+			s = len(rs.ScopeSpans)
+			scopes[res][scope] = s
+			rs.ScopeSpans = append(rs.ScopeSpans, otlpScopeSpans{
+				Scope:     otlpScope{Name: scope.Name, Version: scope.Version},
+				SchemaURL: scope.SchemaURL,
+			})
+		}
+		rs.ScopeSpans[s].Spans = append(rs.ScopeSpans[s].Spans, otlpSpanOf(span))
+	}
+	return req
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) otlpSpan {
+	sc := span.SpanContext()
+	out := otlpSpan{
+		TraceID:           sc.TraceID().String(),
+		SpanID:            sc.SpanID().String(),
+		TraceState:        sc.TraceState().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()), // trace.SpanKind numbers kinds as OTLP does
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        otlpAttributes(span.Attributes()),
+		Status:            otlpStatus{Message: span.Status().Description},
+	}
+	if parent := span.Parent(); parent.HasSpanID() {
+		out.ParentSpanID = parent.SpanID().String()
+	}
+	// OTLP orders the codes unset, ok, error.
+	switch span.Status().Code {
+	case codes.Ok:
+		out.Status.Code = 1
+	case codes.Error:
+		out.Status.Code = 2
+	}
+	for _, ev := range span.Events() {
+		out.Events = append(out.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(ev.Time.UnixNano(), 10),
+			Name:         ev.Name,
+			Attributes:   otlpAttributes(ev.Attributes),
+		})
+	}
+	return out
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		out = append(out, otlpKeyValue{Key: string(kv.Key), Value: otlpValueOf(kv.Value)})
+	}
+	return out
+}
+
+func otlpValueOf(v attribute.Value) otlpValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpValue{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		return otlpArray(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return otlpArray(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return otlpArray(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return otlpArray(v.AsStringSlice(), attribute.StringValue)
+	default:
+		s := v.Emit()
+		return otlpValue{StringValue: &s}
+	}
+}
+
+func otlpArray[T any](values []T, value func(T) attribute.Value) otlpValue {
+	arr := &otlpValues{Values: make([]otlpValue, 0, len(values))}
+	for _, v := range values {
+		arr.Values = append(arr.Values, otlpValueOf(value(v)))
+	}
+	return otlpValue{ArrayValue: arr}
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/redact"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPFileExporter(t *testing.T) {
+	var buf bytes.Buffer
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(&otlpFileExporter{w: &buf}))
+	tr := provider.Tracer("test")
+
+	ctx, parent := tr.Start(context.Background(), "parent")
+	_, child := tr.Start(ctx, "child", trace.WithSpanKind(trace.SpanKindClient))
+	child.SetAttributes(mcpServerKey.String("fs"), attribute.Int64("count", 7))
+	chorus.EndSpan(child, errors.New("boom"))
+	parent.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	// WithSyncer exports each span as it ends, one line each.
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+	var got otlpTraces
+	if err := json.Unmarshal(lines[0], &got); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	scope := got.ResourceSpans[0].ScopeSpans[0]
+	span := scope.Spans[0]
+	if scope.Scope.Name != "test" || span.Name != "child" {
+		t.Fatalf("Unexpected span %+v in %+v", span, scope.Scope)
+	}
+	if span.TraceID != parent.SpanContext().TraceID().String() || span.ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("Expected hex ids under the parent, got trace %s, parent %s", span.TraceID, span.ParentSpanID)
+	}
+	if span.Kind != 3 || span.Status.Code != 2 || span.Status.Message != "boom" {
+		t.Errorf("Expected a failed client span, got kind %d, status %+v", span.Kind, span.Status)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Errorf("Expected the error as an exception event, got %+v", span.Events)
+	}
+
+	attrs := map[string]otlpValue{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["chorus.mcp.server"]; v.StringValue == nil || *v.StringValue != "fs" {
+		t.Errorf("Expected the server name, got %+v", span.Attributes)
+	}
+	if v := attrs["count"]; v.IntValue == nil || *v.IntValue != "7" {
+		t.Errorf("Expected the int attribute as a string, got %+v", span.Attributes)
+	}
+}
+
+func TestOTLPFileExporter_Redacts(t *testing.T) {
+	var buf bytes.Buffer
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(&otlpFileExporter{w: &buf, redactor: redact.Default()}))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "call")
+	span.SetAttributes(
+		attribute.String("args", `{"to":"bob@example.com"}`),
+		attribute.String("api_key", "short"),
+		attribute.Int64("max_tokens", 5),
+	)
+	chorus.EndSpan(span, errors.New("denied for bob@example.com"))
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "bob@example.com") || strings.Contains(out, "short") {
+		t.Errorf("Expected the email and key to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, `"intValue":"5"`) {
+		t.Errorf("Expected max_tokens to be kept, got %s", out)
+	}
+}
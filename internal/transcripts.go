@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/log"
+	"github.com/standrze/chorus/pkg/transcript"
+)
+
+// TranscriptFile is the name of the transcript saved in the workspace of
+// each conversation when a run ends.
+const TranscriptFile = "transcript.json"
+
+// recordTranscript records conv and saves its transcript, redacted, to its
+// workspace whenever a run ends.
+func (app *App) recordTranscript(conv *chorus.Conversation) {
+	rec := transcript.Record(conv)
+	conv.Events().Subscribe(func(ev chorus.Event) {
+		if ev.Type != chorus.EventFinished && ev.Type != chorus.EventError {
+			return
+		}
+		path := filepath.Join(conv.Workspace(), TranscriptFile)
+		if err := app.saveTranscript(path, rec.Transcript()); err != nil {
+			log.Warn("Failed to save transcript", "conversation", conv.ID(), "error", err)
+		}
+	})
+}
+
+func (app *App) saveTranscript(path string, t *transcript.Transcript) error {
+	t.Redact(app.redactor)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := transcript.WriteJSON(f, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadTranscript reads a saved run. run is the transcript file, the
+// conversation's workspace or its ID under ./workspace.
+func LoadTranscript(run string) (*transcript.Transcript, error) {
+	path := run
+	if info, err := os.Stat(run); err != nil {
+		if !os.IsNotExist(err) || filepath.Base(run) != run {
+			return nil, err
+		}
+		path = filepath.Join(chorus.WorkspaceDir, run, TranscriptFile)
+	} else if info.IsDir() {
+		path = filepath.Join(run, TranscriptFile)
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved transcript for %s at %s", run, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return transcript.Read(f)
+}
@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+func TestTranscript_SavedWhenRunEnds(t *testing.T) {
+	s := newTestServer(t, &finishClient{}, ServerOptions{})
+	s.app.redactor = redact.Default()
+
+	var status ConversationStatus
+	if code := request(t, s, "POST", "/v1/conversations", `{"objective":"mail bob@example.com"}`, &status); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	waitForRun(t, s, status.ID)
+
+	for _, run := range []string{status.ID, filepath.Join("workspace", status.ID)} {
+		tr, err := LoadTranscript(run)
+		if err != nil {
+			t.Fatalf("LoadTranscript(%q) failed: %v", run, err)
+		}
+		if tr.Conversation != status.ID || len(tr.Runs) != 1 || tr.Runs[0].Objective != "mail [REDACTED]" || tr.Runs[0].Result != "done" {
+			t.Errorf("Unexpected transcript %+v", tr)
+		}
+	}
+
+	if _, err := LoadTranscript("missing"); err == nil {
+		t.Error("Expected an error for a run that wasn't saved")
+	}
+}
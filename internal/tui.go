@@ -0,0 +1,520 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/transcript"
+)
+
+// maxPaneText bounds the text kept for each pane; older text scrolls away.
+const maxPaneText = 64 << 10
+
+// maxToolLog bounds the entries kept in the tool-call log.
+const maxToolLog = 200
+
+// Keys the TUI handles besides printable runes.
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBackspace = 8
+	keyTab       = '\t'
+	keyEnter     = '\r'
+	keyCtrlP     = 16
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// errDenied is returned to an agent whose tool call was refused in the TUI.
+var errDenied = errors.New("the user denied this tool call")
+
+// TUI is a full-screen terminal view of agents at work: a pane per agent
+// streaming its output, a log of tool calls, the orchestrator's plan and
+// token and cost counters. From it the user can pause the agents, approve
+// each tool call before it runs and slip a message into an agent's history.
+//
+// Watch what it should show with WatchConversation or WatchAgents, then
+// call Run.
+type TUI struct {
+	app   *App
+	title string
+
+	// chat is set by Input: typing goes to the chat rather than to keys.
+	chat  bool
+	lines chan string
+
+	mu        sync.Mutex
+	panes     []*tuiPane
+	selected  int
+	tools     []*tuiToolLine
+	plan      *chorus.Plan
+	tokens    int64
+	cost      float64
+	priced    bool
+	started   time.Time
+	ended     time.Time
+	status    string
+	notice    string
+	paused    bool
+	resume    chan struct{}
+	approving bool
+	approvals []*tuiApproval
+	injected  map[string][]string
+	editing   bool
+	input     []rune
+	console   tuiText
+}
+
+type tuiPane struct {
+	name, role, model string
+
+	text     tuiText
+	tokens   int64
+	cost     float64
+	activity string
+	// streamed records whether the reply in progress arrived as deltas.
+	streamed bool
+}
+
+type tuiToolLine struct {
+	time  time.Time
+	agent string
+	tool  string
+	id    string
+	args  string
+	// state is "…" while the call runs, then "✓" or "✗"; "!" marks a log line.
+	state  string
+	result string
+}
+
+type tuiApproval struct {
+	agent, tool, args string
+	answer            chan bool
+}
+
+// NewTUI returns a TUI titled title. Costs use the prices in the config.
+func (app *App) NewTUI(title string) *TUI {
+	return &TUI{
+		app:      app,
+		title:    title,
+		started:  time.Now(),
+		status:   "running",
+		injected: make(map[string][]string),
+	}
+}
+
+// WatchConversation shows conv's agents and events in the TUI and applies
+// its pause, approval and message controls to them.
+func (t *TUI) WatchConversation(conv *chorus.Conversation) {
+	t.addPanes(conv.Agents())
+	conv.Events().Subscribe(t.handle)
+	conv.Events().Hook(t.hooks())
+}
+
+// WatchAgents is WatchConversation for agents outside a conversation, such
+// as those of a chat.
+func (t *TUI) WatchAgents(agents ...*chorus.Agent) {
+	t.addPanes(agents)
+	for _, agent := range agents {
+		agent.Events.Subscribe(t.handle)
+		agent.Events.Hook(t.hooks())
+	}
+}
+
+// Input turns the TUI's input line into the returned reader, for a chat to
+// read lines from. It reaches EOF when the user quits.
+func (t *TUI) Input() io.Reader {
+	t.chat = true
+	t.lines = make(chan string, 64)
+	return &tuiInput{lines: t.lines}
+}
+
+// Console returns a writer whose text is shown under the panes, for a chat's
+// output.
+func (t *TUI) Console() io.Writer {
+	return tuiConsole{t}
+}
+
+func (t *TUI) addPanes(agents []*chorus.Agent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, agent := range agents {
+		if t.pane(agent.Name) == nil {
+			t.panes = append(t.panes, &tuiPane{name: agent.Name, role: string(agent.Role), model: agent.Model})
+		}
+	}
+}
+
+// pane returns the named agent's pane, or nil. t.mu must be held.
+func (t *TUI) pane(name string) *tuiPane {
+	for _, p := range t.panes {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (t *TUI) hooks() chorus.Hooks {
+	return chorus.Hooks{
+		BeforeGenerate: t.beforeGenerate,
+		BeforeToolCall: t.beforeToolCall,
+	}
+}
+
+// handle updates the view for an event.
+func (t *TUI) handle(ev chorus.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.pane(ev.Agent)
+	switch ev.Type {
+	case chorus.EventRunStarted:
+		t.started, t.ended = ev.Time, time.Time{}
+		t.status = "running"
+		t.plan = nil
+	case chorus.EventDelta:
+		if p != nil {
+			p.text.write(ev.Text)
+			p.streamed = true
+			p.activity = "writing"
+		}
+	case chorus.EventMessage:
+		if p != nil && ev.Message != nil {
+			t.message(p, *ev.Message)
+		}
+	case chorus.EventGenerated:
+		t.tokens += ev.Tokens
+		var cost float64
+		if price, ok := t.app.config().Price(ev.Model); ok {
+			cost = price.Cost(ev.PromptTokens, ev.CompletionTokens)
+			t.cost += cost
+			t.priced = true
+		}
+		if p != nil {
+			p.tokens += ev.Tokens
+			p.cost += cost
+			p.model = ev.Model
+			p.activity = ""
+			if ev.Error != "" {
+				p.text.write("\n✗ " + ev.Error + "\n")
+			}
+		}
+	case chorus.EventToolCall:
+		t.tools = append(t.tools, &tuiToolLine{time: ev.Time, agent: ev.Agent, tool: ev.Tool, id: ev.ToolCallID, args: ev.Arguments, state: "…"})
+		if len(t.tools) > maxToolLog {
+			t.tools = slices.Delete(t.tools, 0, len(t.tools)-maxToolLog)
+		}
+		if p != nil {
+			p.activity = "calling " + ev.Tool
+		}
+	case chorus.EventToolResult:
+		for _, line := range slices.Backward(t.tools) {
+			if line.state == "…" && line.agent == ev.Agent && line.id == ev.ToolCallID && line.tool == ev.Tool {
+				line.state, line.result = "✓", ev.Text
+				if ev.Error != "" {
+					line.state, line.result = "✗", ev.Error
+				}
+				break
+			}
+		}
+		if p != nil {
+			p.activity = ""
+		}
+	case chorus.EventPlanCreated, chorus.EventPlanUpdated:
+		t.plan = ev.Plan.Clone()
+	case chorus.EventFinished:
+		t.ended, t.status = ev.Time, "finished"
+	case chorus.EventError:
+		t.ended, t.status = ev.Time, "failed: "+ev.Error
+	}
+}
+
+// message shows a message added to an agent's history. Replies that were
+// streamed are already on screen; system and tool messages aren't shown.
+func (t *TUI) message(p *tuiPane, msg openai.ChatCompletionMessageParamUnion) {
+	switch {
+	case msg.OfUser != nil:
+		p.text.write("\n» " + transcript.MessageText(msg) + "\n\n")
+	case msg.OfAssistant != nil:
+		if !p.streamed {
+			p.text.write(transcript.MessageText(msg))
+		}
+		p.streamed = false
+		p.text.write("\n")
+	}
+}
+
+// beforeGenerate holds generation while paused, and adds the messages the
+// user queued for the agent to its history and to the request.
+func (t *TUI) beforeGenerate(ctx context.Context, call *chorus.GenerateCall) error {
+	if err := t.wait(ctx); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	queued := t.injected[call.Agent.Name]
+	delete(t.injected, call.Agent.Name)
+	if p := t.pane(call.Agent.Name); p != nil {
+		p.activity = "thinking"
+	}
+	t.mu.Unlock()
+
+	for _, text := range queued {
+		call.Agent.UserMessage(text)
+		call.Params.Messages = append(call.Params.Messages, openai.UserMessage(text))
+	}
+	return nil
+}
+
+// beforeToolCall holds tool calls while paused and, when approval is on,
+// until the user allows or denies them.
+func (t *TUI) beforeToolCall(ctx context.Context, call *chorus.ToolCall) error {
+	if err := t.wait(ctx); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if !t.approving {
+		t.mu.Unlock()
+		return nil
+	}
+	a := &tuiApproval{agent: call.Agent.Name, tool: call.Name, args: call.Arguments, answer: make(chan bool, 1)}
+	t.approvals = append(t.approvals, a)
+	t.mu.Unlock()
+
+	select {
+	case ok := <-a.answer:
+		if !ok {
+			return errDenied
+		}
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.approvals = slices.DeleteFunc(t.approvals, func(x *tuiApproval) bool { return x == a })
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// wait returns once the TUI isn't paused.
+func (t *TUI) wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		paused, resume := t.paused, t.resume
+		t.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		select {
+		case <-resume:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setPaused pauses or resumes the agents. t.mu must be held.
+func (t *TUI) setPaused(paused bool) {
+	if paused == t.paused {
+		return
+	}
+	t.paused = paused
+	if paused {
+		t.resume = make(chan struct{})
+	} else {
+		close(t.resume)
+	}
+}
+
+// press handles a key and reports whether the user asked to quit.
+func (t *TUI) press(key rune) (quit bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.approvals) > 0 {
+		switch key {
+		case 'y', 'Y', 'n', 'N':
+			a := t.approvals[0]
+			t.approvals = t.approvals[1:]
+			a.answer <- key == 'y' || key == 'Y'
+			return false
+		}
+	}
+
+	switch key {
+	case keyCtrlC:
+		return true
+	case keyTab:
+		if len(t.panes) > 0 {
+			t.selected = (t.selected + 1) % len(t.panes)
+		}
+		return false
+	case keyCtrlP:
+		t.setPaused(!t.paused)
+		return false
+	case keyCtrlA:
+		t.approving = !t.approving
+		return false
+	}
+
+	if t.chat || t.editing {
+		return t.edit(key)
+	}
+
+	switch key {
+	case 'q':
+		return true
+	case 'p':
+		t.setPaused(!t.paused)
+	case 'a':
+		t.approving = !t.approving
+	case 'i':
+		if len(t.panes) > 0 {
+			t.editing, t.notice = true, ""
+		}
+	}
+	return false
+}
+
+// edit handles a key typed into the input line. t.mu must be held.
+func (t *TUI) edit(key rune) (quit bool) {
+	switch key {
+	case keyEnter:
+		text := strings.TrimSpace(string(t.input))
+		t.input = nil
+		if t.chat {
+			select {
+			case t.lines <- text:
+				t.console.write(text + "\n")
+			default:
+				t.notice = "Still busy, try again in a moment."
+			}
+			return false
+		}
+		t.editing = false
+		if text != "" {
+			name := t.panes[t.selected].name
+			t.injected[name] = append(t.injected[name], text)
+			t.notice = fmt.Sprintf("Message queued for %s's next turn.", name)
+		}
+	case keyEscape:
+		t.input = nil
+		t.editing = false
+	case keyBackspace, keyDelete:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case keyCtrlD:
+		return t.chat && len(t.input) == 0
+	default:
+		if key >= ' ' {
+			t.input = append(t.input, key)
+		}
+	}
+	return false
+}
+
+// finish records how the work the TUI watched ended.
+func (t *TUI) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ended.IsZero() {
+		t.ended = time.Now()
+		t.status = "finished"
+		if err != nil {
+			t.status = "failed: " + err.Error()
+		}
+	}
+	t.notice = "Done. Press q to quit."
+}
+
+// log adds a log line to the tool-call log.
+func (t *TUI) log(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tools = append(t.tools, &tuiToolLine{time: time.Now(), state: "!", result: line})
+	if len(t.tools) > maxToolLog {
+		t.tools = slices.Delete(t.tools, 0, len(t.tools)-maxToolLog)
+	}
+}
+
+// tuiText is text that keeps only its last maxPaneText bytes, with control
+// characters that would upset the terminal removed.
+type tuiText struct {
+	s string
+}
+
+func (t *tuiText) write(s string) {
+	t.s += strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case r == '\t':
+			return ' '
+		case r < ' ' || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			return -1
+		}
+		return r
+	}, s)
+	if len(t.s) > maxPaneText {
+		cut := len(t.s) - maxPaneText
+		if i := strings.IndexByte(t.s[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+		t.s = t.s[cut:]
+	}
+}
+
+// tuiInput reads the lines typed into a chat TUI.
+type tuiInput struct {
+	lines chan string
+	buf   string
+}
+
+func (in *tuiInput) Read(p []byte) (int, error) {
+	if in.buf == "" {
+		line, ok := <-in.lines
+		if !ok {
+			return 0, io.EOF
+		}
+		in.buf = line + "\n"
+	}
+	n := copy(p, in.buf)
+	in.buf = in.buf[n:]
+	return n, nil
+}
+
+type tuiConsole struct {
+	t *TUI
+}
+
+func (c tuiConsole) Write(p []byte) (int, error) {
+	c.t.mu.Lock()
+	defer c.t.mu.Unlock()
+	c.t.console.write(string(p))
+	return len(p), nil
+}
+
+// tuiLog passes log lines to the TUI's tool-call log.
+type tuiLog struct {
+	t *TUI
+}
+
+func (l tuiLog) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimSpace(string(p)), "\n") {
+		if line != "" {
+			l.t.log(line)
+		}
+	}
+	return len(p), nil
+}
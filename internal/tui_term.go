@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/standrze/chorus/pkg/log"
+	"golang.org/x/term"
+)
+
+// tuiRefresh is how often the screen is redrawn.
+const tuiRefresh = 100 * time.Millisecond
+
+// Run takes over the terminal on in and out and shows the TUI while work
+// runs. A run's final state stays on screen until the user quits; a chat's
+// TUI closes when the chat ends. Quitting early cancels work's context and
+// waits for it to return. Logs written to the terminal are shown in the
+// tool-call log instead while the TUI is up.
+func (t *TUI) Run(ctx context.Context, in, out *os.File, work func(context.Context) error) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("the terminal UI needs an interactive terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	out.WriteString("\x1b[?1049h\x1b[?25l")
+	defer out.WriteString("\x1b[?25h\x1b[?1049l")
+
+	if logs := log.Output(); logs == os.Stderr || logs == os.Stdout {
+		log.SetOutput(tuiLog{t})
+		defer log.SetOutput(logs)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan rune, 64)
+	go readKeys(in, keys)
+	done := make(chan error, 1)
+	go func() { done <- work(ctx) }()
+
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+
+	var (
+		frame    string
+		running  = true
+		quitting bool
+		result   error
+	)
+	for {
+		frame = t.draw(out, frame)
+
+		select {
+		case key := <-keys:
+			if quitting || !t.press(key) {
+				continue
+			}
+			if !running {
+				return result
+			}
+			quitting = true
+			cancel()
+			if t.chat {
+				close(t.lines)
+			}
+		case result = <-done:
+			running = false
+			if t.chat || quitting {
+				return result
+			}
+			t.finish(result)
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw writes the screen to out unless it is unchanged from the last frame,
+// and returns what it drew.
+func (t *TUI) draw(out *os.File, last string) string {
+	width, height, err := term.GetSize(int(out.Fd()))
+	if err != nil {
+		return last
+	}
+	frame := strings.Join(t.render(width, height), "\x1b[K\r\n")
+	if frame != last {
+		out.WriteString("\x1b[H" + frame + "\x1b[K\x1b[J")
+	}
+	return frame
+}
+
+// readKeys sends the runes typed on in to keys. Escape sequences, such as
+// arrow keys, are dropped; a lone escape is sent as keyEscape.
+func readKeys(in *os.File, keys chan<- rune) {
+	buf := make([]byte, 256)
+	var pending []byte
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		b := append(pending, buf[:n]...)
+		pending = nil
+		for len(b) > 0 {
+			if b[0] == keyEscape && len(b) > 1 && (b[1] == '[' || b[1] == 'O') {
+				i := 2
+				for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+					i++
+				}
+				b = b[min(i+1, len(b)):]
+				continue
+			}
+			if !utf8.FullRune(b) {
+				pending = b
+				break
+			}
+			r, size := utf8.DecodeRune(b)
+			b = b[size:]
+			keys <- r
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// toolClient answers with each of its tool calls in turn, then with text.
+type toolClient struct {
+	mu     sync.Mutex
+	calls  [][2]string
+	params []openai.ChatCompletionNewParams
+}
+
+func (c *toolClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.params = append(c.params, params)
+
+	msg := openai.ChatCompletionMessage{Role: "assistant", Content: "an essay"}
+	if len(c.calls) > 0 {
+		call := c.calls[0]
+		c.calls = c.calls[1:]
+		msg = openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ChatCompletionMessageToolCallUnion{{
+			ID:       "call_" + call[0],
+			Type:     "function",
+			Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: call[0], Arguments: call[1]},
+		}}}
+	}
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{Message: msg}},
+		Usage:   openai.CompletionUsage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500},
+	}, nil
+}
+
+func newTestTUI(t *testing.T, lead *toolClient) (*TUI, *chorus.Conversation) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	app := &App{cfg: &Config{Prices: []PriceConfig{{Model: "big", Input: 2, Output: 8}}}}
+	orchestrator := chorus.NewAgent(lead, chorus.WithName("Lead"), chorus.WithRole(chorus.RoleOrchestrator), chorus.WithModel("big"))
+	writer := chorus.NewAgent(&toolClient{}, chorus.WithName("Writer"), chorus.WithModel("small"))
+	conv, err := chorus.NewConversation(t.Context(), orchestrator, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tui := app.NewTUI("write an essay")
+	tui.WatchConversation(conv)
+	return tui, conv
+}
+
+var ansi = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// screen renders tui as plain text.
+func screen(tui *TUI) string {
+	return ansi.ReplaceAllString(strings.Join(tui.render(120, 30), "\n"), "")
+}
+
+func TestTUI_ShowsRun(t *testing.T) {
+	tui, conv := newTestTUI(t, &toolClient{calls: [][2]string{
+		{"CreatePlan", `{"steps":["research",{"description":"write","depends_on":["1"]}]}`},
+		{"UpdatePlanStep", `{"step_id":"1","status":"done"}`},
+		{"DelegateTask", `{"agent_name":"Writer","instructions":"write it","step_id":"2"}`},
+		{"Finish", `{"result":"done"}`},
+	}})
+
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	got := screen(tui)
+	for _, want := range []string{
+		"chorus · write an essay",
+		"finished",
+		"7,500 tokens",
+		"$0.0240",
+		"Lead · orchestrator · big · 6,000 tokens",
+		"Writer · agent · small · 1,500 tokens",
+		"» Task: write it",
+		"an essay",
+		"Plan (2/2 done)",
+		"✓ 1. research",
+		"✓ 2. write · Writer",
+		"Lead DelegateTask",
+		`{"agent_name":"Writer",`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q on screen:\n%s", want, got)
+		}
+	}
+
+	for _, line := range tui.render(120, 30) {
+		if n := len([]rune(ansi.ReplaceAllString(line, ""))); n != 120 {
+			t.Errorf("Expected lines of 120 columns, got %d: %q", n, line)
+		}
+	}
+}
+
+func TestTUI_Controls(t *testing.T) {
+	lead := &toolClient{calls: [][2]string{
+		{"CreatePlan", `{"steps":["research"]}`},
+		{"Finish", `{"result":"done"}`},
+	}}
+	tui, conv := newTestTUI(t, lead)
+
+	// Queue a message for the lead and pause before the run starts.
+	for _, key := range "ihurry\rp" {
+		tui.press(key)
+	}
+	tui.press('a')
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := conv.Run("write an essay")
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	lead.mu.Lock()
+	generated := len(lead.params)
+	lead.mu.Unlock()
+	if generated != 0 {
+		t.Fatalf("Expected nothing to be generated while paused, got %d requests", generated)
+	}
+	tui.press('p')
+
+	// Deny the plan, then allow Finish.
+	for _, answer := range "ny" {
+		waitFor(t, func() bool {
+			tui.mu.Lock()
+			defer tui.mu.Unlock()
+			return len(tui.approvals) > 0
+		})
+		if !strings.Contains(screen(tui), "Allow Lead to call") {
+			t.Errorf("Expected an approval prompt:\n%s", screen(tui))
+		}
+		tui.press(answer)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(lead.params) != 2 || !strings.Contains(lastUserText(lead.params[0]), "hurry") {
+		t.Errorf("Expected the queued message in the first request, got %+v", lead.params)
+	}
+	if call := tui.tools[0]; call.tool != "CreatePlan" || call.state != "✗" || !strings.Contains(call.result, errDenied.Error()) {
+		t.Errorf("Expected the plan to be denied, got %+v", call)
+	}
+	if got := screen(tui); !strings.Contains(got, "No plan yet") {
+		t.Errorf("Expected no plan:\n%s", got)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func lastUserText(params openai.ChatCompletionNewParams) string {
+	for i := len(params.Messages) - 1; i >= 0; i-- {
+		if msg := params.Messages[i]; msg.OfUser != nil {
+			return msg.OfUser.Content.OfString.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// consoleHeight is the number of lines a chat's output gets under the panes.
+const consoleHeight = 6
+
+// stepMarks show the status of plan steps.
+var stepMarks = map[chorus.StepStatus]string{
+	chorus.StepPending:    "·",
+	chorus.StepInProgress: "▸",
+	chorus.StepDone:       "✓",
+	chorus.StepFailed:     "✗",
+}
+
+// render lays the TUI out on a width by height screen. Lines carry ANSI
+// styles but never run past width. Every helper below returns lines already
+// fitted to their width, so styling them doesn't upset the layout.
+func (t *TUI) render(width, height int) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if width < 20 || height < 6 {
+		return []string{fit("Terminal too small", width)}
+	}
+
+	footer := []string{t.prompt(width), style("2", fit(t.help(), width))}
+	console := 0
+	if t.chat {
+		console = consoleHeight + 1
+	}
+	body := height - 1 - len(footer) - console
+	if body < 3 {
+		body, console = height-1-len(footer), 0
+	}
+
+	lines := make([]string, 0, height)
+	lines = append(lines, style("7", t.header(width)))
+
+	left, right := width, 0
+	if width >= 60 {
+		right = width / 3
+		left = width - right - 1
+	}
+	panes := t.paneLines(left, body)
+	var side []string
+	if right > 0 {
+		side = t.sideLines(right, body)
+	}
+	for i := range body {
+		line := panes[i]
+		if right > 0 {
+			line += style("2", "│") + side[i]
+		}
+		lines = append(lines, line)
+	}
+
+	if console > 0 {
+		lines = append(lines, style("1", fit("Chat", width)))
+		lines = append(lines, tail(wrap(t.console.s, width), consoleHeight, width)...)
+	}
+	return append(lines, footer...)
+}
+
+func (t *TUI) header(width int) string {
+	title := " chorus"
+	if t.title != "" {
+		title += " · " + oneLine(t.title)
+	}
+
+	end := t.ended
+	if end.IsZero() {
+		end = time.Now()
+	}
+	status := t.status
+	if t.paused {
+		status = "paused"
+	}
+	info := []string{status, clock(end.Sub(t.started)), thousands(t.tokens) + " tokens"}
+	if t.priced {
+		info = append(info, fmt.Sprintf("$%.4f", t.cost))
+	}
+	if t.approving {
+		info = append(info, "approving tools")
+	}
+	right := strings.Join(info, " · ") + " "
+
+	if n := width - utf8.RuneCountInString(right); n > 0 {
+		return fit(title, n) + right
+	}
+	return fit(right, width)
+}
+
+// paneLines stacks the agents' panes in height lines, as many as fit with the
+// selected one among them.
+func (t *TUI) paneLines(width, height int) []string {
+	if len(t.panes) == 0 {
+		return blank(height, width)
+	}
+
+	count := min(len(t.panes), max(height/3, 1))
+	start := 0
+	if t.selected >= count {
+		start = t.selected - count + 1
+	}
+
+	lines := make([]string, 0, height)
+	for i := range count {
+		n := height / count
+		if i < height%count {
+			n++
+		}
+		p := t.panes[start+i]
+
+		info := []string{p.name}
+		if p.role != "" {
+			info = append(info, p.role)
+		}
+		if p.model != "" {
+			info = append(info, p.model)
+		}
+		info = append(info, thousands(p.tokens)+" tokens")
+		if p.cost > 0 {
+			info = append(info, fmt.Sprintf("$%.4f", p.cost))
+		}
+		if p.activity != "" {
+			info = append(info, p.activity+"…")
+		}
+		title := fit(" "+strings.Join(info, " · "), width)
+		if start+i == t.selected {
+			lines = append(lines, style("1;7", title))
+		} else {
+			lines = append(lines, style("1", title))
+		}
+		lines = append(lines, tail(wrap(strings.TrimLeft(p.text.s, "\n"), width), n-1, width)...)
+	}
+	return lines
+}
+
+// sideLines shows the plan above the tool-call log, which gives each call a
+// line saying who called what and another with its arguments and result.
+func (t *TUI) sideLines(width, height int) []string {
+	lines := make([]string, 0, height)
+
+	title := " Plan"
+	var steps []string
+	if t.plan == nil {
+		steps = append(steps, style("2", fit(" No plan yet", width)))
+	} else {
+		done, total := t.plan.Progress()
+		title += fmt.Sprintf(" (%d/%d done)", done, total)
+	}
+	if t.plan != nil {
+		for _, step := range t.plan.Steps {
+			text := fmt.Sprintf(" %s %s. %s", stepMarks[step.Status], step.ID, step.Description)
+			if step.Assignee != "" {
+				text += " · " + step.Assignee
+			}
+			text = fit(text, width)
+			switch step.Status {
+			case chorus.StepInProgress:
+				text = style("1", text)
+			case chorus.StepDone:
+				text = style("2", text)
+			case chorus.StepFailed:
+				text = style("31", text)
+			}
+			steps = append(steps, text)
+		}
+	}
+	planHeight := min(max(len(steps), 1)+1, height/2)
+	lines = append(lines, style("1", fit(title, width)))
+	lines = append(lines, head(steps, planHeight-1, width)...)
+
+	lines = append(lines, style("1", fit(" Tool calls", width)))
+	var calls []string
+	for _, call := range t.tools {
+		if call.state == "!" {
+			calls = append(calls, style("33", fit(" ! "+call.result, width)))
+			continue
+		}
+		summary := fit(fmt.Sprintf(" %s %s %s %s", call.state, call.time.Format("15:04:05"), call.agent, call.tool), width)
+		if call.state == "✗" {
+			summary = style("31", summary)
+		}
+		detail := "   " + oneLine(call.args)
+		if call.result != "" {
+			detail += " → " + oneLine(call.result)
+		}
+		calls = append(calls, summary, style("2", fit(detail, width)))
+	}
+	return append(lines, tail(calls, height-len(lines), width)...)
+}
+
+// prompt is the line above the key help: a pending approval, the message
+// being typed or the latest notice.
+func (t *TUI) prompt(width int) string {
+	switch {
+	case len(t.approvals) > 0:
+		a := t.approvals[0]
+		question := fmt.Sprintf("Allow %s to call %s %s? [y/n]", a.agent, a.tool, oneLine(a.args))
+		if len(t.approvals) > 1 {
+			question += fmt.Sprintf(" (%d more waiting)", len(t.approvals)-1)
+		}
+		return style("1;33", fit(question, width))
+	case t.chat:
+		return fit("> "+string(t.input)+"▏", width)
+	case t.editing:
+		return fit(fmt.Sprintf("Message for %s: %s▏", t.panes[t.selected].name, string(t.input)), width)
+	default:
+		return fit(t.notice, width)
+	}
+}
+
+func (t *TUI) help() string {
+	pause := "pause"
+	if t.paused {
+		pause = "resume"
+	}
+	approve := "approve tool calls"
+	if t.approving {
+		approve = "stop approving"
+	}
+	switch {
+	case t.chat:
+		return fmt.Sprintf(" ^P %s · ^A %s · tab next pane · enter send · ^C quit", pause, approve)
+	case t.editing:
+		return " enter queue message · esc cancel"
+	default:
+		return fmt.Sprintf(" p %s · a %s · tab next pane · i message agent · q quit", pause, approve)
+	}
+}
+
+func style(code, s string) string {
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// fit cuts s to width runes, or pads it with spaces to width.
+func fit(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// wrap breaks text into lines of width runes, padding short ones.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			cut := width
+			if i := lastSpace(runes[:width]); i > width/2 {
+				cut = i + 1
+			}
+			lines = append(lines, fit(string(runes[:cut]), width))
+			runes = runes[cut:]
+		}
+		lines = append(lines, fit(string(runes), width))
+	}
+	return lines
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
+
+// head returns the first n lines, padded with blank ones of width.
+func head(lines []string, n, width int) []string {
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return append(lines[:len(lines):len(lines)], blank(n-len(lines), width)...)
+}
+
+// tail returns the last n lines, padded with blank ones of width.
+func tail(lines []string, n, width int) []string {
+	if n <= 0 {
+		return nil
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append(lines[:len(lines):len(lines)], blank(n-len(lines), width)...)
+}
+
+func blank(n, width int) []string {
+	lines := make([]string, max(n, 0))
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", width)
+	}
+	return lines
+}
+
+// oneLine folds s onto a single line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func clock(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// thousands formats n with comma separators.
+func thousands(n int64) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
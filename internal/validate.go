@@ -0,0 +1,301 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// ValidationError is a problem with a single setting. Path locates it in the
+// config, e.g. agents[2].model.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is every problem found in a config, one per line.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the collected problems, or nil if there were none.
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Validate checks the config without connecting to anything. The returned
+// error is a ValidationErrors listing every problem found.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.BaseURL != "" {
+		if u, err := url.Parse(c.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			v.add("base_url", "invalid URL %q", c.BaseURL)
+		}
+	}
+	if c.MaxTurns < 0 {
+		v.add("max_turns", "must not be negative")
+	}
+	if c.TokenBudget < 0 {
+		v.add("token_budget", "must not be negative")
+	}
+	validatePrices(v, c.Prices)
+	c.Log.validateInto(v, "log")
+	c.Redaction.validateInto(v, "redaction")
+
+	names := make(map[string]int)
+	for i, agentCfg := range c.Agents {
+		path := fmt.Sprintf("agents[%d]", i)
+		if agentCfg.Name != "" {
+			if j, ok := names[agentCfg.Name]; ok {
+				v.add(path+".name", "duplicate agent name %q (also agents[%d])", agentCfg.Name, j)
+			} else {
+				names[agentCfg.Name] = i
+			}
+		}
+		c.validateAgent(v, path, agentCfg)
+	}
+	c.Strategy.validateInto(v, "strategy", names)
+
+	teams := make(map[string]int)
+	for i, team := range c.Teams {
+		path := fmt.Sprintf("teams[%d]", i)
+		if team.Name == "" {
+			v.add(path+".name", "is required")
+		} else if j, ok := teams[team.Name]; ok {
+			v.add(path+".name", "duplicate team name %q (also teams[%d])", team.Name, j)
+		} else {
+			teams[team.Name] = i
+		}
+		c.validateTeam(v, path, team, names)
+	}
+
+	servers := make(map[string]int)
+	for i, server := range c.MCPServers {
+		path := fmt.Sprintf("mcp_servers[%d]", i)
+		if server.Command == "" {
+			v.add(path+".command", "is required")
+		}
+		if j, ok := servers[server.DisplayName()]; ok {
+			v.add(path+".name", "duplicate MCP server name %q (also mcp_servers[%d])", server.DisplayName(), j)
+		} else {
+			servers[server.DisplayName()] = i
+		}
+	}
+
+	if s := c.Sampling; s != nil {
+		if s.Agent != "" {
+			if _, ok := names[s.Agent]; !ok {
+				v.add("sampling.agent", "agent %q is not configured", s.Agent)
+			}
+		}
+		switch s.Approval {
+		case "", SamplingApprovalAuto, SamplingApprovalPrompt, SamplingApprovalDeny:
+		default:
+			v.add("sampling.approval", "must be %s, %s or %s, got %q",
+				SamplingApprovalAuto, SamplingApprovalPrompt, SamplingApprovalDeny, s.Approval)
+		}
+		if s.MaxTokens < 0 {
+			v.add("sampling.max_tokens", "must not be negative")
+		}
+		if s.MaxTotalTokens < 0 {
+			v.add("sampling.max_total_tokens", "must not be negative")
+		}
+	}
+
+	for i, root := range c.Roots {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			v.add(fmt.Sprintf("roots[%d]", i), "%s is not a directory", root)
+		}
+	}
+
+	return v.err()
+}
+
+func (c *Config) validateAgent(v *validator, path string, agentCfg AgentConfig) {
+	if agentCfg.ReasoningEffort != "" && !slices.Contains(reasoningEfforts, openai.ReasoningEffort(agentCfg.ReasoningEffort)) {
+		v.add(path+".reasoning_effort", "invalid reasoning effort %q", agentCfg.ReasoningEffort)
+	}
+	if t := agentCfg.Temperature; t != nil && (*t < 0 || *t > 2) {
+		v.add(path+".temperature", "must be between 0 and 2")
+	}
+	if p := agentCfg.TopP; p != nil && (*p < 0 || *p > 1) {
+		v.add(path+".top_p", "must be between 0 and 1")
+	}
+	if agentCfg.MaxTokens < 0 {
+		v.add(path+".max_tokens", "must not be negative")
+	}
+	if agentCfg.Context.MaxMessages < 0 {
+		v.add(path+".context.max_messages", "must not be negative")
+	}
+	if agentCfg.ResponseFormat != nil {
+		if _, err := responseFormat(*agentCfg.ResponseFormat); err != nil {
+			v.add(path+".response_format", "%v", err)
+		}
+	}
+	if _, err := c.systemMessage(agentCfg); err != nil {
+		field := ".system_message"
+		if agentCfg.SystemMessageFile != "" {
+			field = ".system_message_file"
+		}
+		v.add(path+field, "%v", err)
+	}
+	for j, name := range agentCfg.MCPServers {
+		if !slices.ContainsFunc(c.MCPServers, func(s MCPServerConfig) bool { return s.DisplayName() == name }) {
+			v.add(fmt.Sprintf("%s.mcp_servers[%d]", path, j), "MCP server %q is not configured", name)
+		}
+	}
+}
+
+// validateTeam checks that a team's agents exist and can hold an orchestrated
+// conversation. names maps agent names to their index in c.Agents.
+func (c *Config) validateTeam(v *validator, path string, team TeamConfig, names map[string]int) {
+	orchestrators := 0
+	for j, name := range team.Agents {
+		i, ok := names[name]
+		if !ok {
+			v.add(fmt.Sprintf("%s.agents[%d]", path, j), "agent %q is not configured", name)
+			continue
+		}
+		if chorus.Role(c.Agents[i].Role) == chorus.RoleOrchestrator {
+			orchestrators++
+		}
+	}
+	switch {
+	case orchestrators != 1:
+		v.add(path+".agents", "a team needs exactly one agent with role %q, got %d", chorus.RoleOrchestrator, orchestrators)
+	case len(team.Agents) < 2:
+		v.add(path+".agents", "a team needs at least one worker besides the orchestrator")
+	}
+}
+
+// ValidateConversation runs Validate and also checks that the agents can hold
+// an orchestrated conversation: exactly one orchestrator and at least one worker.
+func (c *Config) ValidateConversation() error {
+	v := &validator{}
+	if err := c.Validate(); err != nil {
+		v.errs = append(v.errs, err.(ValidationErrors)...)
+	}
+
+	orchestrator := -1
+	for i, agentCfg := range c.Agents {
+		if chorus.Role(agentCfg.Role) != chorus.RoleOrchestrator {
+			continue
+		}
+		if orchestrator >= 0 {
+			v.add(fmt.Sprintf("agents[%d].role", i), "only one orchestrator is allowed (also agents[%d])", orchestrator)
+			continue
+		}
+		orchestrator = i
+	}
+	if orchestrator < 0 {
+		v.add("agents", "no agent has role %q", chorus.RoleOrchestrator)
+	} else if len(c.Agents) < 2 {
+		v.add("agents", "a conversation needs at least one worker besides the orchestrator")
+	}
+
+	return v.err()
+}
+
+// ValidateCommands checks that the command of every MCP server can be found.
+// Startup doesn't check this: a server that fails to start is skipped and
+// reported as failed, so only config validate treats it as a problem.
+func (c *Config) ValidateCommands() error {
+	v := &validator{}
+	for i, server := range c.MCPServers {
+		if server.Command == "" {
+			continue
+		}
+		if _, err := exec.LookPath(server.Command); err != nil {
+			v.add(fmt.Sprintf("mcp_servers[%d].command", i), "command %q not found", server.Command)
+		}
+	}
+	return v.err()
+}
+
+// Validate checks what can only be checked once the MCP servers are running:
+// that every server connected and that every tool an agent names exists.
+func (app *App) Validate() error {
+	cfg := app.config()
+	v := &validator{}
+	for i, serverCfg := range cfg.MCPServers {
+		if app.server(serverCfg.DisplayName()) == nil {
+			v.add(fmt.Sprintf("mcp_servers[%d]", i), "failed to connect to %s", serverCfg.DisplayName())
+		}
+	}
+	v.errs = append(v.errs, app.validateTools(cfg)...)
+	return v.err()
+}
+
+// validateTools reports tool names in cfg that none of an agent's servers
+// provide. Agents using a server that failed to connect are skipped, as their
+// tools are unknown.
+func (app *App) validateTools(cfg *Config) ValidationErrors {
+	v := &validator{}
+	for i, agentCfg := range cfg.Agents {
+		if len(agentCfg.Tools) == 0 {
+			continue
+		}
+
+		available := make(map[string]bool)
+		complete := true
+		for _, name := range cfg.serverNames(agentCfg) {
+			server := app.server(name)
+			if server == nil {
+				complete = false
+				break
+			}
+			funcTools, _ := server.toolset.Snapshot()
+			for _, t := range funcTools {
+				available[t.Name] = true
+			}
+		}
+		if !complete {
+			continue
+		}
+
+		for j, name := range agentCfg.Tools {
+			if !available[name] {
+				v.add(fmt.Sprintf("agents[%d].tools[%d]", i, j), "no MCP server provides tool %q", name)
+			}
+		}
+	}
+	return v.errs
+}
+
+// server returns the connected MCP server with the given name, or nil.
+func (app *App) server(name string) *mcpServer {
+	for _, server := range app.servers {
+		if server.cfg.DisplayName() == name {
+			return server
+		}
+	}
+	return nil
+}
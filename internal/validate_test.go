@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/standrze/chorus/pkg/tools"
+)
+
+// paths returns the config paths of the problems in err.
+func paths(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Path)
+	}
+	return out
+}
+
+func TestConfig_Validate(t *testing.T) {
+	temperature := 3.0
+	cfg := &Config{
+		BaseURL: "not a url",
+		Agents: []AgentConfig{
+			{Name: "Teacher", ReasoningEffort: "huge"},
+			{Name: "Professor", MCPServers: []string{"missing"}},
+			{Name: "Teacher", Temperature: &temperature},
+		},
+		Teams: []TeamConfig{
+			{Name: "solo", Agents: []string{"Teacher"}},
+			{Name: "solo", Agents: []string{"Nobody", "Professor"}},
+		},
+		MCPServers: []MCPServerConfig{{Name: "empty"}},
+		Sampling:   &SamplingConfig{Agent: "Nobody", Approval: "maybe"},
+		Prices:     []PriceConfig{{Model: "gpt", Input: -1}, {Model: "GPT"}},
+		Log:        LogConfig{Format: "xml", Components: map[string]string{"db": "debug"}},
+		Redaction:  RedactionConfig{Patterns: []string{"(unclosed"}},
+		Strategy:   StrategyConfig{Type: "free_for_all", Agents: []string{"Teacher", "Nobody"}, Rounds: -1, Judge: "Professor"},
+	}
+
+	want := []string{
+		"base_url",
+		"prices[0].input",
+		"prices[1].model",
+		"log.format",
+		"log.components.db",
+		"redaction.patterns[0]",
+		"agents[0].reasoning_effort",
+		"agents[1].mcp_servers[0]",
+		"agents[2].name",
+		"agents[2].temperature",
+		"strategy.type",
+		"strategy.rounds",
+		"strategy.agents[1]",
+		"strategy.judge",
+		"teams[0].agents",
+		"teams[1].name",
+		"teams[1].agents[0]",
+		"teams[1].agents",
+		"mcp_servers[0].command",
+		"sampling.agent",
+		"sampling.approval",
+	}
+	if got := paths(t, cfg.Validate()); !slices.Equal(got, want) {
+		t.Errorf("Expected problems at\n%v\ngot\n%v", want, got)
+	}
+}
+
+func TestConfig_ValidateCommands(t *testing.T) {
+	cfg := &Config{MCPServers: []MCPServerConfig{
+		{Name: "empty"},
+		{Name: "go", Command: "go"},
+		{Name: "missing", Command: "chorus-no-such-command"},
+	}}
+	if err := cfg.Validate(); err == nil || slices.Contains(paths(t, err), "mcp_servers[2].command") {
+		t.Errorf("Expected startup checks to leave missing commands alone, got %v", err)
+	}
+	if got := paths(t, cfg.ValidateCommands()); !slices.Equal(got, []string{"mcp_servers[2].command"}) {
+		t.Errorf("Expected the missing command, got %v", got)
+	}
+}
+
+func TestConfig_ValidateValid(t *testing.T) {
+	cfg := &Config{
+		BaseURL: "http://localhost:8080/v1",
+		Agents: []AgentConfig{
+			{Name: "Teacher", Role: "orchestrator", SystemMessage: "You are {{.Name}}."},
+			{Name: "Professor", ReasoningEffort: "high"},
+		},
+		Teams:    []TeamConfig{{Name: "review", Agents: []string{"Teacher", "Professor"}}},
+		Strategy: StrategyConfig{Type: "round_robin", Agents: []string{"Professor", "Teacher"}, Rounds: 2},
+	}
+	if err := cfg.ValidateConversation(); err != nil {
+		t.Errorf("Expected valid config, got:\n%v", err)
+	}
+}
+
+func TestConfig_ValidateConversation(t *testing.T) {
+	tests := []struct {
+		name   string
+		agents []AgentConfig
+		want   []string
+	}{
+		{"no orchestrator", []AgentConfig{{Name: "A"}, {Name: "B"}}, []string{"agents"}},
+		{"two orchestrators", []AgentConfig{{Name: "A", Role: "orchestrator"}, {Name: "B", Role: "orchestrator"}}, []string{"agents[1].role"}},
+		{"no worker", []AgentConfig{{Name: "A", Role: "orchestrator"}}, []string{"agents"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Agents: tt.agents}
+			if got := paths(t, cfg.ValidateConversation()); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected problems at %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApp_ValidateTools(t *testing.T) {
+	type NoArgs struct{}
+	search := tools.FunctionTool{
+		Name: "search",
+		Func: func(args NoArgs) (string, error) { return "", nil },
+	}
+
+	serverCfg := MCPServerConfig{Name: "web", Command: "web-server"}
+	app := &App{
+		cfg: &Config{
+			Agents: []AgentConfig{
+				{Name: "Teacher", MCPServers: []string{"web"}, Tools: []string{"search", "browse"}},
+				{Name: "Professor", MCPServers: []string{"offline"}, Tools: []string{"anything"}},
+			},
+			MCPServers: []MCPServerConfig{serverCfg, {Name: "offline", Command: "offline-server"}},
+		},
+		servers: []*mcpServer{{cfg: serverCfg, toolset: tools.NewToolset("web", search)}},
+	}
+
+	want := []string{"mcp_servers[1]", "agents[0].tools[1]"}
+	if got := paths(t, app.Validate()); !slices.Equal(got, want) {
+		t.Errorf("Expected problems at %v, got %v", want, got)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+
+	agents := schema["properties"].(map[string]any)["agents"].(map[string]any)
+	agent := agents["items"].(map[string]any)["properties"].(map[string]any)
+
+	effort := agent["reasoning_effort"].(map[string]any)
+	if !slices.Contains(effort["enum"].([]any), any("high")) {
+		t.Errorf("Expected reasoning_effort enum, got %v", effort)
+	}
+	if _, ok := agent["system_message_file"]; !ok {
+		t.Error("Expected system_message_file in the agent schema")
+	}
+	if _, ok := schema["properties"].(map[string]any)["Debug"]; ok {
+		t.Error("Fields without a config key should not be in the schema")
+	}
+}
@@ -5,12 +5,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/standrze/chorus/pkg/client"
 	"github.com/standrze/chorus/pkg/log"
 	"github.com/standrze/chorus/pkg/tools"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	convLog  = log.Component(log.ComponentConversation)
+	toolsLog = log.Component(log.ComponentTools)
 )
 
 type Role string
@@ -29,18 +38,153 @@ type Agent struct {
 	Tools           []openai.ChatCompletionToolUnionParam
 	ReasoningEffort openai.ReasoningEffort
 	Seed            param.Opt[int64]
+	Temperature     param.Opt[float64]
+	TopP            param.Opt[float64]
+	MaxTokens       param.Opt[int64]
+	Stop            []string
+	ResponseFormat  openai.ChatCompletionNewParamsResponseFormatUnion
+	ContextPolicy   ContextPolicy
+	// Events carries what the agent does to subscribers and runs its hooks.
+	Events *Bus
+	// Deprecated: Subscribe to Events instead.
+	Callbacks Callbacks
+	// Token usage accumulated over every generation
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
 	// local registry
 	functions map[string]any
+	toolsets  []*toolsetBinding
+	// toolOwners records which toolset added each function; tools added
+	// directly have no entry.
+	toolOwners map[string]*toolsetBinding
+	// note is sent after the history with the next request only, see withNote.
+	note string
+}
+
+// Callbacks let callers watch an agent while it works. Any of them may be nil.
+//
+// Deprecated: Subscribe to the agent's Events instead, which carry the same
+// and more.
+type Callbacks struct {
+	// OnDelta receives reply text as it arrives. Setting it makes Generate
+	// stream when the client supports streaming.
+	OnDelta func(delta string)
+	// OnToolCall is called before Respond runs a tool the model asked for.
+	OnToolCall func(name, args string)
+	// OnToolResult is called with the outcome of each tool Respond runs.
+	OnToolResult func(name, result string, err error)
+}
+
+// toolsetBinding tracks which tools an agent last took from a Toolset.
+type toolsetBinding struct {
+	set     *tools.Toolset
+	allow   map[string]bool // nil allows every tool
+	version uint64
+	added   []*openai.ChatCompletionFunctionToolParam // nil until the first sync
+}
+
+// ContextPolicy controls how much of the history is sent with each request.
+// The full history is always kept on the agent.
+type ContextPolicy struct {
+	// MaxMessages sends only the most recent messages, plus the leading
+	// system messages. Zero sends everything.
+	MaxMessages int
+}
+
+// apply trims messages according to the policy.
+func (p ContextPolicy) apply(messages []openai.ChatCompletionMessageParamUnion) []openai.ChatCompletionMessageParamUnion {
+	if p.MaxMessages <= 0 {
+		return messages
+	}
+
+	system := 0
+	for system < len(messages) && messages[system].OfSystem != nil {
+		system++
+	}
+	if len(messages)-system <= p.MaxMessages {
+		return messages
+	}
+
+	start := len(messages) - p.MaxMessages
+	// A tool result is meaningless without the call that produced it.
+	for start < len(messages) && messages[start].OfTool != nil {
+		start++
+	}
+
+	window := make([]openai.ChatCompletionMessageParamUnion, 0, system+len(messages)-start)
+	window = append(window, messages[:system]...)
+	return append(window, messages[start:]...)
 }
 
 type SendOption func(*Agent)
 
 func (a *Agent) SystemMessage(message string) {
-	a.Messages = append(a.Messages, openai.SystemMessage(message))
+	a.appendMessage(openai.SystemMessage(message))
 }
 
 func (a *Agent) UserMessage(message string) {
-	a.Messages = append(a.Messages, openai.UserMessage(message))
+	a.appendMessage(openai.UserMessage(message))
+}
+
+// appendMessage adds msg to the history and tells subscribers.
+func (a *Agent) appendMessage(msg openai.ChatCompletionMessageParamUnion) {
+	a.Messages = append(a.Messages, msg)
+	a.Events.publish(Event{Type: EventMessage, Agent: a.Name, Message: &msg})
+}
+
+// SetSystemMessage replaces the leading system message, or inserts one if
+// the history doesn't start with one. The rest of the history is kept.
+func (a *Agent) SetSystemMessage(message string) {
+	if len(a.Messages) > 0 && a.Messages[0].OfSystem != nil {
+		a.Messages[0] = openai.SystemMessage(message)
+		return
+	}
+	a.Messages = append([]openai.ChatCompletionMessageParamUnion{openai.SystemMessage(message)}, a.Messages...)
+}
+
+// Reset clears the history, keeping only the leading system messages.
+func (a *Agent) Reset() {
+	n := 0
+	for n < len(a.Messages) && a.Messages[n].OfSystem != nil {
+		n++
+	}
+	a.Messages = a.Messages[:n:n]
+}
+
+// Reconfigure takes over the settings, tools and system message of fresh,
+// typically an agent built from a reloaded config, while keeping this agent's
+// history, token usage and event bus. Tools added to this agent directly are
+// dropped. It must not be called while the agent is generating.
+func (a *Agent) Reconfigure(fresh *Agent) {
+	n := 0
+	for n < len(a.Messages) && a.Messages[n].OfSystem != nil {
+		n++
+	}
+	system := 0
+	for system < len(fresh.Messages) && fresh.Messages[system].OfSystem != nil {
+		system++
+	}
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, system+len(a.Messages)-n)
+	messages = append(messages, fresh.Messages[:system]...)
+	a.Messages = append(messages, a.Messages[n:]...)
+
+	a.Name = fresh.Name
+	a.Role = fresh.Role
+	a.Client = fresh.Client
+	a.Model = fresh.Model
+	a.ReasoningEffort = fresh.ReasoningEffort
+	a.Seed = fresh.Seed
+	a.Temperature = fresh.Temperature
+	a.TopP = fresh.TopP
+	a.MaxTokens = fresh.MaxTokens
+	a.Stop = fresh.Stop
+	a.ResponseFormat = fresh.ResponseFormat
+	a.ContextPolicy = fresh.ContextPolicy
+	a.Tools = fresh.Tools
+	a.functions = fresh.functions
+	a.toolsets = fresh.toolsets
+	a.toolOwners = fresh.toolOwners
 }
 
 func (a *Agent) Generate(ctx context.Context, options ...SendOption) (*openai.ChatCompletion, error) {
@@ -48,17 +192,116 @@ func (a *Agent) Generate(ctx context.Context, options ...SendOption) (*openai.Ch
 		opt(a)
 	}
 
-	log.Debug("Agent Generating", "agent", a.Name, "model", a.Model, "msg_count", len(a.Messages))
+	ctx = log.WithAttrs(ctx, "agent", a.Name)
+	ctx, span := tracer.Start(ctx, "chat "+a.Model, trace.WithSpanKind(trace.SpanKindClient))
+
+	a.syncToolsets()
+
+	convLog.DebugContext(ctx, "Agent generating", "model", a.Model, "msg_count", len(a.Messages))
+
+	params := &openai.ChatCompletionNewParams{
+		Model:               a.Model,
+		Messages:            a.ContextPolicy.apply(a.Messages),
+		ReasoningEffort:     a.ReasoningEffort,
+		Seed:                a.Seed,
+		Temperature:         a.Temperature,
+		TopP:                a.TopP,
+		MaxCompletionTokens: a.MaxTokens,
+		Stop:                openai.ChatCompletionNewParamsStopUnion{OfStringArray: a.Stop},
+		ResponseFormat:      a.ResponseFormat,
+		Tools:               a.Tools,
+	}
+
+	if a.note != "" {
+		params.Messages = append(slices.Clip(params.Messages), openai.UserMessage(a.note))
+		a.note = ""
+	}
+
+	call := &GenerateCall{Agent: a, Params: params}
+	if err := a.Events.beforeGenerate(ctx, call); err != nil {
+		EndSpan(span, err)
+		return nil, err
+	}
+	// Hooks may have changed the request.
+	span.SetName("chat " + params.Model)
+	span.SetAttributes(generateAttributes(a, params)...)
+
+	// Stream only when someone is watching the deltas arrive.
+	call.Started = time.Now()
+	if streamer, ok := a.Client.(client.StreamingClient); ok && (a.Events.listening() || a.Callbacks.OnDelta != nil) {
+		call.Completion, call.Err = streamer.ChatCompletionStream(ctx, *params, func(chunk openai.ChatCompletionChunk) {
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				if a.Callbacks.OnDelta != nil {
+					a.Callbacks.OnDelta(chunk.Choices[0].Delta.Content)
+				}
+				a.Events.publish(Event{Type: EventDelta, Agent: a.Name, Text: chunk.Choices[0].Delta.Content})
+			}
+		})
+	} else {
+		call.Completion, call.Err = a.Client.ChatCompletion(ctx, *params)
+	}
+
+	ev := Event{Type: EventGenerated, Agent: a.Name, Model: params.Model}
+	if call.Err == nil {
+		a.recordUsage(call.Completion.Usage)
+		ev.Tokens = call.Completion.Usage.TotalTokens
+		ev.PromptTokens = call.Completion.Usage.PromptTokens
+		ev.CompletionTokens = call.Completion.Usage.CompletionTokens
+		span.SetAttributes(completionAttributes(call.Completion)...)
+	} else {
+		ev.Error = call.Err.Error()
+	}
+	EndSpan(span, call.Err)
+	a.Events.afterGenerate(ctx, call)
+	a.Events.publish(ev)
+
+	return call.Completion, call.Err
+}
+
+func (a *Agent) recordUsage(usage openai.CompletionUsage) {
+	a.PromptTokens += usage.PromptTokens
+	a.CompletionTokens += usage.CompletionTokens
+	a.TotalTokens += usage.TotalTokens
+}
+
+// maxToolRounds bounds how many consecutive tool-call rounds Respond will run
+// before giving up on a final answer.
+const maxToolRounds = 10
+
+// Respond generates a reply and executes any tool calls the model makes,
+// feeding the results back until it answers without calling a tool.
+// The assistant and tool messages are appended to the agent's history.
+func (a *Agent) Respond(ctx context.Context, options ...SendOption) (string, error) {
+	for _, opt := range options {
+		opt(a)
+	}
+
+	for i := 0; i < maxToolRounds; i++ {
+		resp, err := a.Generate(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("agent %s: empty response", a.Name)
+		}
 
-	result, err := a.Client.ChatCompletion(ctx, openai.ChatCompletionNewParams{
-		Model:           a.Model,
-		Messages:        a.Messages,
-		ReasoningEffort: a.ReasoningEffort,
-		Seed:            a.Seed,
-		Tools:           a.Tools,
-	})
+		msg := resp.Choices[0].Message
+		a.appendMessage(msg.ToParam())
 
-	return result, err
+		if len(msg.ToolCalls) == 0 {
+			return msg.Content, nil
+		}
+
+		for _, toolCall := range msg.ToolCalls {
+			res, err := a.callTool(ctx, toolCall)
+			if err != nil {
+				res = fmt.Sprintf("Error: %v", err)
+			}
+			a.appendMessage(openai.ToolMessage(res, toolCall.ID))
+		}
+	}
+
+	return "", fmt.Errorf("agent %s: exceeded %d tool rounds", a.Name, maxToolRounds)
 }
 
 /*func logTokenUsage(a *Agent) {
@@ -78,10 +321,64 @@ func (a *Agent) Generate(ctx context.Context, options ...SendOption) (*openai.Ch
 }
 */
 
+// callTool runs a tool call the model asked for, through the hooks, and
+// tells subscribers about it.
+func (a *Agent) callTool(ctx context.Context, toolCall openai.ChatCompletionMessageToolCallUnion) (string, error) {
+	call := &ToolCall{
+		Agent:     a,
+		ID:        toolCall.ID,
+		Name:      toolCall.Function.Name,
+		Arguments: toolCall.Function.Arguments,
+	}
+
+	vetoErr := a.Events.beforeToolCall(ctx, call)
+	if a.Callbacks.OnToolCall != nil {
+		a.Callbacks.OnToolCall(call.Name, call.Arguments)
+	}
+	a.Events.publish(Event{Type: EventToolCall, Agent: a.Name, Tool: call.Name, ToolCallID: call.ID, Arguments: call.Arguments})
+	call.Started = time.Now()
+	if vetoErr != nil {
+		call.Err = vetoErr
+	} else {
+		call.Result, call.Err = a.callFunction(ctx, call.ID, call.Name, call.Arguments)
+	}
+	a.Events.afterToolCall(ctx, call)
+	if a.Callbacks.OnToolResult != nil {
+		a.Callbacks.OnToolResult(call.Name, call.Result, call.Err)
+	}
+	a.Events.publish(toolResultEvent(a.Name, call))
+
+	return call.Result, call.Err
+}
+
 // CallFunction executes a registered tool function by name, unmarshaling the JSON arguments.
 // It returns the result as a string or an error.
 func (a *Agent) CallFunction(name string, argsJSON string) (string, error) {
-	log.Debug("Calling Function", "agent", a.Name, "tool", name, "args", argsJSON)
+	return a.CallFunctionContext(context.Background(), name, argsJSON)
+}
+
+// CallFunctionContext is CallFunction with a context, which is passed on to
+// functions that take one and carries the span the call is traced in.
+func (a *Agent) CallFunctionContext(ctx context.Context, name string, argsJSON string) (string, error) {
+	return a.callFunction(ctx, "", name, argsJSON)
+}
+
+// callFunction runs a tool function in a span of its own. id is the tool
+// call the model made, if any.
+func (a *Agent) callFunction(ctx context.Context, id, name string, argsJSON string) (result string, err error) {
+	ctx = log.WithAttrs(ctx, "agent", a.Name)
+	ctx, span := tracer.Start(ctx, "execute_tool "+name, trace.WithAttributes(
+		semconv.GenAIOperationNameExecuteTool,
+		semconv.GenAIAgentName(a.Name),
+		semconv.GenAIToolName(name),
+		semconv.GenAIToolType("function"),
+	))
+	if id != "" {
+		span.SetAttributes(semconv.GenAIToolCallID(id))
+	}
+	defer func() { EndSpan(span, err) }()
+
+	toolsLog.DebugContext(ctx, "Calling function", "tool", name, "args", argsJSON)
 	if a.functions == nil {
 		return "", fmt.Errorf("no functions registered")
 	}
@@ -93,14 +390,12 @@ func (a *Agent) CallFunction(name string, argsJSON string) (string, error) {
 
 	// Reflection magic to call the function
 	fnVal := reflect.ValueOf(fn)
-	fnType := fnVal.Type()
-
-	if fnType.NumIn() != 1 {
-		return "", fmt.Errorf("function %s must accept exactly one argument", name)
+	argType, takesContext, err := tools.ArgumentType(fn)
+	if err != nil {
+		return "", fmt.Errorf("function %s: %w", name, err)
 	}
 
 	// Create a new instance of the argument type
-	argType := fnType.In(0)
 	argPtr := reflect.New(argType)
 
 	// Unmarshal JSON into the pointer
@@ -109,7 +404,11 @@ func (a *Agent) CallFunction(name string, argsJSON string) (string, error) {
 	}
 
 	// Call the function
-	ret := fnVal.Call([]reflect.Value{argPtr.Elem()})
+	in := []reflect.Value{argPtr.Elem()}
+	if takesContext {
+		in = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, in...)
+	}
+	ret := fnVal.Call(in)
 
 	// Handle return values
 	// Expected: (string, error) or just (error) or just (string)?
@@ -154,7 +453,21 @@ func (a *Agent) CallFunction(name string, argsJSON string) (string, error) {
 	return resStr, nil
 }
 
+// AddFunctionTool registers tool. It takes the place of a toolset's tool of
+// the same name.
 func (a *Agent) AddFunctionTool(tool tools.FunctionTool) {
+	if owner := a.toolOwners[tool.Name]; owner != nil {
+		i := slices.IndexFunc(owner.added, func(def *openai.ChatCompletionFunctionToolParam) bool { return def.Function.Name == tool.Name })
+		def := owner.added[i]
+		owner.added = slices.Delete(owner.added, i, i+1)
+		a.Tools = slices.DeleteFunc(slices.Clone(a.Tools), func(t openai.ChatCompletionToolUnionParam) bool { return t.OfFunction == def })
+		delete(a.toolOwners, tool.Name)
+	}
+	a.addFunctionTool(tool)
+}
+
+// addFunctionTool registers tool and returns its definition in a.Tools.
+func (a *Agent) addFunctionTool(tool tools.FunctionTool) *openai.ChatCompletionFunctionToolParam {
 	if tool.Parameters == nil && tool.Func != nil {
 		schema, err := tools.GenerateSchema(tool.Func)
 		if err != nil {
@@ -163,32 +476,93 @@ func (a *Agent) AddFunctionTool(tool tools.FunctionTool) {
 		tool.Parameters = schema
 	}
 
-	a.Tools = append(a.Tools, openai.ChatCompletionToolUnionParam{
-		OfFunction: &openai.ChatCompletionFunctionToolParam{
-			Function: openai.FunctionDefinitionParam{
-				Name:        tool.Name,
-				Description: openai.String(tool.Description),
-				Parameters:  tool.Parameters,
-			},
-			Type: "function",
+	def := &openai.ChatCompletionFunctionToolParam{
+		Function: openai.FunctionDefinitionParam{
+			Name:        tool.Name,
+			Description: openai.String(tool.Description),
+			Parameters:  tool.Parameters,
 		},
-	})
+		Type: "function",
+	}
+	a.Tools = append(a.Tools, openai.ChatCompletionToolUnionParam{OfFunction: def})
 
 	if a.functions == nil {
 		a.functions = make(map[string]interface{})
 	}
 	a.functions[tool.Name] = tool.Func
+	return def
+}
+
+// removeToolsetTools unregisters the tools b added. Tools of the same name
+// from anywhere else are kept.
+func (a *Agent) removeToolsetTools(b *toolsetBinding) {
+	kept := make([]openai.ChatCompletionToolUnionParam, 0, len(a.Tools))
+	for _, t := range a.Tools {
+		if t.OfFunction != nil && slices.Contains(b.added, t.OfFunction) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+	a.Tools = kept
+
+	for _, def := range b.added {
+		if name := def.Function.Name; a.toolOwners[name] == b {
+			delete(a.functions, name)
+			delete(a.toolOwners, name)
+		}
+	}
+}
+
+// syncToolsets swaps in the current contents of any toolset that changed
+// since the agent last looked. It runs before each generation, so tool
+// changes only ever land between turns.
+func (a *Agent) syncToolsets() {
+	for _, b := range a.toolsets {
+		current, version := b.set.Snapshot()
+		if version == b.version && b.added != nil {
+			continue
+		}
+
+		a.removeToolsetTools(b)
+		b.added = make([]*openai.ChatCompletionFunctionToolParam, 0, len(current))
+		for _, tool := range current {
+			if b.allow != nil && !b.allow[tool.Name] {
+				continue
+			}
+			// The first tool to take a name keeps it.
+			if _, taken := a.functions[tool.Name]; taken {
+				toolsLog.Warn("Tool name already taken, skipping", "agent", a.Name, "toolset", b.set.Name(), "tool", tool.Name)
+				continue
+			}
+			b.added = append(b.added, a.addFunctionTool(tool))
+			if a.toolOwners == nil {
+				a.toolOwners = make(map[string]*toolsetBinding)
+			}
+			a.toolOwners[tool.Name] = b
+		}
+		b.version = version
+
+		toolsLog.Debug("Synced toolset", "agent", a.Name, "toolset", b.set.Name(), "tools", len(current))
+	}
 }
 
 func WithUserMessage(prompt string) SendOption {
 	return func(a *Agent) {
-		a.Messages = append(a.Messages, openai.UserMessage(prompt))
+		a.appendMessage(openai.UserMessage(prompt))
+	}
+}
+
+// withNote adds text to the next request as a user message without keeping
+// it in the history.
+func withNote(text string) SendOption {
+	return func(a *Agent) {
+		a.note = text
 	}
 }
 
 func WithSystemMessage(prompt string) SendOption {
 	return func(a *Agent) {
-		a.Messages = append(a.Messages, openai.SystemMessage(prompt))
+		a.appendMessage(openai.SystemMessage(prompt))
 	}
 }
 
@@ -216,6 +590,42 @@ func WithSeed(seed int) func(*Agent) {
 	}
 }
 
+func WithTemperature(temperature float64) func(*Agent) {
+	return func(a *Agent) {
+		a.Temperature = openai.Float(temperature)
+	}
+}
+
+func WithTopP(topP float64) func(*Agent) {
+	return func(a *Agent) {
+		a.TopP = openai.Float(topP)
+	}
+}
+
+func WithMaxTokens(maxTokens int64) func(*Agent) {
+	return func(a *Agent) {
+		a.MaxTokens = openai.Int(maxTokens)
+	}
+}
+
+func WithStop(stop ...string) func(*Agent) {
+	return func(a *Agent) {
+		a.Stop = stop
+	}
+}
+
+func WithResponseFormat(format openai.ChatCompletionNewParamsResponseFormatUnion) func(*Agent) {
+	return func(a *Agent) {
+		a.ResponseFormat = format
+	}
+}
+
+func WithContextPolicy(policy ContextPolicy) func(*Agent) {
+	return func(a *Agent) {
+		a.ContextPolicy = policy
+	}
+}
+
 func WithFunctionTools(funcTools ...tools.FunctionTool) func(*Agent) {
 	union := []openai.ChatCompletionToolUnionParam{}
 
@@ -251,6 +661,23 @@ func WithFunctionTools(funcTools ...tools.FunctionTool) func(*Agent) {
 	}
 }
 
+// WithToolset gives the agent the tools in set, and keeps it in step with
+// later changes to the set. If names are given, only those tools are taken.
+func WithToolset(set *tools.Toolset, names ...string) func(*Agent) {
+	binding := &toolsetBinding{set: set}
+	if len(names) > 0 {
+		binding.allow = make(map[string]bool, len(names))
+		for _, name := range names {
+			binding.allow[name] = true
+		}
+	}
+
+	return func(a *Agent) {
+		a.toolsets = append(a.toolsets, binding)
+		a.syncToolsets()
+	}
+}
+
 func WithRole(role Role) func(*Agent) {
 	return func(a *Agent) {
 		a.Role = role
@@ -267,6 +694,7 @@ func NewAgent(client client.Client, options ...func(*Agent)) *Agent {
 		Model:           "ai/gpt-oss",
 		ReasoningEffort: openai.ReasoningEffortLow,
 		Seed:            openai.Int(0),
+		Events:          &Bus{},
 	}
 
 	for _, option := range options {
@@ -1,12 +1,53 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/openai/openai-go/v3"
 	"github.com/standrze/chorus/pkg/tools"
 )
 
+// mockClient replays canned completions in order and records the params it was called with.
+type mockClient struct {
+	responses []*openai.ChatCompletion
+	calls     []openai.ChatCompletionNewParams
+}
+
+func (m *mockClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	m.calls = append(m.calls, params)
+	if len(m.calls) > len(m.responses) {
+		return nil, fmt.Errorf("unexpected call %d", len(m.calls))
+	}
+	return m.responses[len(m.calls)-1], nil
+}
+
+func textCompletion(content string) *openai.ChatCompletion {
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "stop",
+			Message:      openai.ChatCompletionMessage{Role: "assistant", Content: content},
+		}},
+	}
+}
+
+func toolCallCompletion(id, name, args string) *openai.ChatCompletion {
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "tool_calls",
+			Message: openai.ChatCompletionMessage{
+				Role: "assistant",
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnion{{
+					ID:       id,
+					Type:     "function",
+					Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: name, Arguments: args},
+				}},
+			},
+		}},
+	}
+}
+
 func TestCallFunction(t *testing.T) {
 	type EchoArgs struct {
 		Message string `json:"message"`
@@ -70,3 +111,220 @@ func TestCallFunction_Void(t *testing.T) {
 		t.Errorf("Expected 'Success', got '%s'", res)
 	}
 }
+
+func TestRespond_ToolLoop(t *testing.T) {
+	type EchoArgs struct {
+		Message string `json:"message"`
+	}
+
+	echoTool := tools.FunctionTool{
+		Name: "Echo",
+		Func: func(args EchoArgs) (string, error) {
+			return "Echo: " + args.Message, nil
+		},
+	}
+
+	mock := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "Echo", `{"message": "Hi"}`),
+		textCompletion("done"),
+	}}
+	agent := NewAgent(mock, WithFunctionTools(echoTool))
+
+	reply, err := agent.Respond(context.Background(), WithUserMessage("say hi"))
+	if err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if reply != "done" {
+		t.Errorf("Expected 'done', got '%s'", reply)
+	}
+
+	// user, assistant tool call, tool result, assistant reply
+	if len(agent.Messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(agent.Messages))
+	}
+	toolMsg := agent.Messages[2].OfTool
+	if toolMsg == nil || toolMsg.Content.OfString.Value != "Echo: Hi" {
+		t.Errorf("Expected tool result 'Echo: Hi', got %+v", agent.Messages[2])
+	}
+	if len(mock.calls) != 2 {
+		t.Errorf("Expected 2 completions, got %d", len(mock.calls))
+	}
+}
+
+func TestWithToolset_SyncsBetweenTurns(t *testing.T) {
+	type NoArgs struct{}
+	tool := func(name string) tools.FunctionTool {
+		return tools.FunctionTool{
+			Name: name,
+			Func: func(args NoArgs) (string, error) { return name, nil },
+		}
+	}
+
+	set := tools.NewToolset("server", tool("First"))
+	mock := &mockClient{responses: []*openai.ChatCompletion{textCompletion("ok")}}
+	agent := NewAgent(mock, WithToolset(set))
+
+	if len(agent.Tools) != 1 {
+		t.Fatalf("Expected 1 tool after binding, got %d", len(agent.Tools))
+	}
+
+	set.Set(tool("Second"), tool("Third"))
+
+	// The change is not visible until the next generation.
+	if _, err := agent.CallFunction("First", `{}`); err != nil {
+		t.Errorf("Old tool should still be callable mid-turn: %v", err)
+	}
+
+	if _, err := agent.Generate(context.Background()); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	sent := mock.calls[0].Tools
+	if len(sent) != 2 || sent[0].OfFunction.Function.Name != "Second" {
+		t.Errorf("Expected refreshed tools to be sent, got %d tools", len(sent))
+	}
+	if _, err := agent.CallFunction("First", `{}`); err == nil {
+		t.Error("Expected removed tool to be gone")
+	}
+	if res, err := agent.CallFunction("Third", `{}`); err != nil || res != "Third" {
+		t.Errorf("Expected new tool to be callable, got %q, %v", res, err)
+	}
+}
+
+func TestWithToolset_FiltersByName(t *testing.T) {
+	type NoArgs struct{}
+	tool := func(name string) tools.FunctionTool {
+		return tools.FunctionTool{
+			Name: name,
+			Func: func(args NoArgs) (string, error) { return name, nil },
+		}
+	}
+
+	set := tools.NewToolset("server", tool("Search"), tool("Delete"))
+	agent := NewAgent(nil, WithToolset(set, "Search"))
+
+	if len(agent.Tools) != 1 || agent.Tools[0].OfFunction.Function.Name != "Search" {
+		t.Fatalf("Expected only Search to be bound, got %d tools", len(agent.Tools))
+	}
+	if _, err := agent.CallFunction("Delete", `{}`); err == nil {
+		t.Error("Expected filtered tool to be unavailable")
+	}
+}
+
+func TestWithToolset_KeepsOtherSourcesTools(t *testing.T) {
+	type NoArgs struct{}
+	tool := func(name, result string) tools.FunctionTool {
+		return tools.FunctionTool{
+			Name: name,
+			Func: func(args NoArgs) (string, error) { return result, nil },
+		}
+	}
+
+	files := tools.NewToolset("files", tool("Search", "files"))
+	web := tools.NewToolset("web", tool("Search", "web"), tool("Fetch", "web"))
+	agent := NewAgent(nil, WithToolset(files), WithToolset(web))
+	// A tool added directly takes the place of the web's.
+	agent.AddFunctionTool(tool("Fetch", "own"))
+	if res, err := agent.CallFunction("Fetch", `{}`); err != nil || res != "own" || len(agent.Tools) != 2 {
+		t.Errorf("Expected the agent's own Fetch alone, got %q, %v and %d tools", res, err, len(agent.Tools))
+	}
+	agent.AddFunctionTool(tool("ReadBoard", "board"))
+
+	// The web's Search is refused while the files' is there, and removing
+	// the web's tools leaves the files' and the agent's own alone.
+	web.Set()
+	agent.syncToolsets()
+	if res, err := agent.CallFunction("Search", `{}`); err != nil || res != "files" {
+		t.Errorf("Expected the files' Search to stay, got %q, %v", res, err)
+	}
+	if res, err := agent.CallFunction("Fetch", `{}`); err != nil || res != "own" {
+		t.Errorf("Expected the agent's own Fetch to stay, got %q, %v", res, err)
+	}
+	if len(agent.Tools) != 3 {
+		t.Errorf("Expected Search, Fetch and ReadBoard, got %d tools", len(agent.Tools))
+	}
+
+	files.Set()
+	web.Set(tool("Search", "web"))
+	agent.syncToolsets()
+	if res, err := agent.CallFunction("Search", `{}`); err != nil || res != "web" {
+		t.Errorf("Expected the web's Search once the files' is gone, got %q, %v", res, err)
+	}
+	if res, err := agent.CallFunction("ReadBoard", `{}`); err != nil || res != "board" {
+		t.Errorf("Expected the agent's own tool to stay, got %q, %v", res, err)
+	}
+}
+
+func TestContextPolicy_KeepsSystemAndRecent(t *testing.T) {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("system"),
+		openai.UserMessage("one"),
+		openai.AssistantMessage("two"),
+		openai.ToolMessage("result", "call_1"),
+		openai.UserMessage("three"),
+		openai.AssistantMessage("four"),
+	}
+
+	window := ContextPolicy{MaxMessages: 3}.apply(messages)
+
+	// The orphaned tool result is dropped along with its call.
+	if len(window) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(window))
+	}
+	if window[0].OfSystem == nil {
+		t.Error("Expected the system message to be kept")
+	}
+	if window[1].OfUser == nil || window[1].OfUser.Content.OfString.Value != "three" {
+		t.Error("Expected the window to start at the most recent user message")
+	}
+
+	if got := (ContextPolicy{}).apply(messages); len(got) != len(messages) {
+		t.Errorf("Expected zero policy to keep everything, got %d", len(got))
+	}
+}
+
+func TestReconfigure_KeepsHistory(t *testing.T) {
+	agent := NewAgent(nil, WithName("Teacher"), WithModel("old"), WithSystemMessage("Old prompt."))
+	agent.Messages = append(agent.Messages, openai.UserMessage("hello"), openai.AssistantMessage("hi"))
+	agent.TotalTokens = 42
+
+	fresh := NewAgent(nil, WithName("Teacher"), WithModel("new"), WithSystemMessage("New prompt."), WithTemperature(0.1))
+	agent.Reconfigure(fresh)
+
+	if agent.Model != "new" || agent.Temperature.Value != 0.1 {
+		t.Errorf("Expected new settings, got model %s", agent.Model)
+	}
+	if len(agent.Messages) != 3 || agent.Messages[0].OfSystem.Content.OfString.Value != "New prompt." {
+		t.Fatalf("Expected new system message followed by the history, got %d messages", len(agent.Messages))
+	}
+	if agent.Messages[1].OfUser == nil || agent.Messages[2].OfAssistant == nil {
+		t.Error("Expected history to be kept")
+	}
+	if agent.TotalTokens != 42 {
+		t.Errorf("Expected token usage to be kept, got %d", agent.TotalTokens)
+	}
+}
+
+func TestReconfigure_FollowsToolset(t *testing.T) {
+	type NoArgs struct{}
+	tool := func(name string) tools.FunctionTool {
+		return tools.FunctionTool{
+			Name: name,
+			Func: func(args NoArgs) (string, error) { return name, nil },
+		}
+	}
+
+	set := tools.NewToolset("server", tool("Search"), tool("Fetch"))
+	agent := NewAgent(nil, WithToolset(set))
+	agent.Reconfigure(NewAgent(nil, WithToolset(set)))
+
+	// The server drops a tool after the reload.
+	set.Set(tool("Search"))
+	agent.syncToolsets()
+	if _, err := agent.CallFunction("Fetch", `{}`); err == nil {
+		t.Error("Expected the dropped tool to be gone")
+	}
+	if len(agent.Tools) != 1 {
+		t.Errorf("Expected only Search to be sent, got %d tools", len(agent.Tools))
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Post is a message on a conversation's board. From is an agent's name, or
+// "user" for the objective. To is empty for posts to everyone.
+type Post struct {
+	From string    `json:"from"`
+	To   string    `json:"to,omitempty"`
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+}
+
+func (p Post) String() string {
+	if p.To == "" {
+		return fmt.Sprintf("%s: %s", p.From, p.Text)
+	}
+	return fmt.Sprintf("%s → %s: %s", p.From, p.To, p.Text)
+}
+
+// Board is the message board every agent of a conversation shares. Runs post
+// their objective to it, the orchestrator's delegations and the workers'
+// replies go on it, and in the other turn strategies it is what the agents
+// speak from.
+type Board struct {
+	mu    sync.Mutex
+	posts []Post
+}
+
+// Posts returns what has been posted so far, oldest first.
+func (b *Board) Posts() []Post {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Post(nil), b.posts...)
+}
+
+func (b *Board) post(p Post) {
+	p.Time = time.Now()
+	b.mu.Lock()
+	b.posts = append(b.posts, p)
+	b.mu.Unlock()
+}
+
+// last returns the most recent post by an agent, if there is one.
+func (b *Board) last() (Post, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := len(b.posts) - 1; i >= 0; i-- {
+		if b.posts[i].From != userPoster {
+			return b.posts[i], true
+		}
+	}
+	return Post{}, false
+}
+
+// view is the history agent speaks from: its leading system messages, then
+// its own posts as its replies and everyone else's as messages to it.
+func (b *Board) view(agent *Agent) []openai.ChatCompletionMessageParamUnion {
+	messages := systemMessages(agent)
+	for _, p := range b.Posts() {
+		switch p.From {
+		case agent.Name:
+			messages = append(messages, openai.AssistantMessage(p.Text))
+		case userPoster:
+			messages = append(messages, openai.UserMessage(p.Text))
+		default:
+			messages = append(messages, openai.UserMessage(p.String()))
+		}
+	}
+	return messages
+}
+
+// systemMessages returns a copy of the system messages agent's history
+// starts with.
+func systemMessages(agent *Agent) []openai.ChatCompletionMessageParamUnion {
+	system := 0
+	for system < len(agent.Messages) && agent.Messages[system].OfSystem != nil {
+		system++
+	}
+	return append([]openai.ChatCompletionMessageParamUnion(nil), agent.Messages[:system]...)
+}
+
+// userPoster is who the objective is posted by.
+const userPoster = "user"
+
+type ReadBoardArgs struct {
+	Last int `json:"last,omitempty" description:"Read only this many of the most recent posts; all of them if zero"`
+}
+
+func (c *Conversation) readBoard(args ReadBoardArgs) (string, error) {
+	posts := c.board.Posts()
+	if args.Last > 0 && args.Last < len(posts) {
+		posts = posts[len(posts)-args.Last:]
+	}
+	if len(posts) == 0 {
+		return "The board is empty.", nil
+	}
+	lines := make([]string, len(posts))
+	for i, p := range posts {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n\n"), nil
+}
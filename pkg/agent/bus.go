@@ -0,0 +1,314 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Hooks intercept an agent as it works. Any of them may be nil. Hooks run
+// synchronously, in the order they were added, an agent's own before those
+// of the buses it forwards to.
+type Hooks struct {
+	// BeforeGenerate may change the request before it is sent. An error
+	// aborts Generate with that error.
+	BeforeGenerate func(ctx context.Context, call *GenerateCall) error
+	// AfterGenerate sees the completion, or the error, of every request.
+	AfterGenerate func(ctx context.Context, call *GenerateCall)
+	// BeforeToolCall may rewrite the arguments. An error vetoes the call: the
+	// tool is not run and the error is reported to the model instead.
+	BeforeToolCall func(ctx context.Context, call *ToolCall) error
+	// AfterToolCall may rewrite the result or the error.
+	AfterToolCall func(ctx context.Context, call *ToolCall)
+}
+
+// GenerateCall is a request an agent makes to its model.
+type GenerateCall struct {
+	Agent      *Agent
+	Params     *openai.ChatCompletionNewParams
+	Started    time.Time              // set when the request is sent
+	Completion *openai.ChatCompletion // set after the request
+	Err        error                  // set after the request
+}
+
+// ToolCall is a tool call an agent's model asked for.
+type ToolCall struct {
+	Agent     *Agent
+	ID        string
+	Name      string
+	Arguments string
+	Started   time.Time // set when the tool runs
+	Result    string    // set after the call
+	Err       error     // set after the call
+}
+
+// Bus delivers an agent's or a conversation's events to subscribers and runs
+// its hooks. Each bus numbers the events it delivers from 1. The zero value
+// is ready to use; a nil bus drops everything.
+type Bus struct {
+	// mu guards the subscriber, hook and forward lists.
+	mu      sync.RWMutex
+	subs    []*subscriber
+	hooks   []*Hooks
+	forward []*Bus
+
+	// queueMu guards the events waiting to be delivered. One publisher at a
+	// time delivers them, in order, without holding a lock, so subscribers
+	// may publish to the bus themselves.
+	queueMu    sync.Mutex
+	queue      []Event
+	delivering bool
+	seq        int64
+
+	// decorate fills in details the publisher doesn't know, such as the
+	// conversation an agent's event belongs to.
+	decorate func(*Event)
+}
+
+type subscriber struct {
+	fn    func(Event)
+	queue *eventQueue // nil for synchronous subscribers
+}
+
+// Subscribe calls fn with every event, in order, on the goroutine publishing
+// it, usually the one that caused it and before work carries on. While one
+// goroutine delivers, events published meanwhile are queued for it to
+// deliver too. fn must not block for long. It may publish, for instance by
+// adding a message to an agent; that event is delivered once fn returns. It
+// returns a function that cancels the subscription.
+func (b *Bus) Subscribe(fn func(Event)) (cancel func()) {
+	return b.add(&subscriber{fn: fn})
+}
+
+// SubscribeAsync calls fn with every event, in order, on a goroutine of its
+// own, so a slow subscriber never holds up the agents. Events are queued
+// until fn is ready for them. Cancelling delivers what is already queued and
+// then stops.
+func (b *Bus) SubscribeAsync(fn func(Event)) (cancel func()) {
+	q := newEventQueue()
+	go q.run(fn)
+	remove := b.add(&subscriber{fn: fn, queue: q})
+	return func() {
+		remove()
+		q.close()
+	}
+}
+
+func (b *Bus) add(sub *subscriber) func() {
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		b.subs = slices.DeleteFunc(b.subs, func(s *subscriber) bool { return s == sub })
+		b.mu.Unlock()
+	}
+}
+
+// Hook adds hooks to the bus and returns a function that removes them.
+func (b *Bus) Hook(hooks Hooks) (remove func()) {
+	h := &hooks
+	b.mu.Lock()
+	b.hooks = append(b.hooks, h)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		b.hooks = slices.DeleteFunc(b.hooks, func(x *Hooks) bool { return x == h })
+		b.mu.Unlock()
+	}
+}
+
+// Forward passes every event on to another bus, and runs that bus's hooks
+// after this one's. A conversation forwards its agents' buses to its own.
+// It returns a function that stops forwarding.
+func (b *Bus) Forward(to *Bus) (remove func()) {
+	b.mu.Lock()
+	b.forward = append(b.forward, to)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		b.forward = slices.DeleteFunc(b.forward, func(x *Bus) bool { return x == to })
+		b.mu.Unlock()
+	}
+}
+
+// listening reports whether anything subscribes to the bus or to a bus it
+// forwards to.
+func (b *Bus) listening() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.subs) > 0 {
+		return true
+	}
+	for _, to := range b.forward {
+		if to.listening() {
+			return true
+		}
+	}
+	return false
+}
+
+// publish numbers ev and queues it for delivery. Unless another publisher is
+// already delivering, including a subscriber publishing from within a
+// delivery, it then delivers the queue before returning.
+func (b *Bus) publish(ev Event) {
+	if b == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.queueMu.Lock()
+	if b.decorate != nil {
+		b.decorate(&ev)
+	}
+	b.seq++
+	ev.Seq = b.seq
+	b.queue = append(b.queue, ev)
+	if b.delivering {
+		b.queueMu.Unlock()
+		return
+	}
+	b.delivering = true
+	for len(b.queue) > 0 {
+		ev := b.queue[0]
+		b.queue = b.queue[1:]
+		b.queueMu.Unlock()
+		b.deliver(ev)
+		b.queueMu.Lock()
+	}
+	b.delivering = false
+	b.queueMu.Unlock()
+}
+
+// deliver hands ev to the subscribers and passes it on to the forwarded buses.
+func (b *Bus) deliver(ev Event) {
+	b.mu.RLock()
+	subs := slices.Clone(b.subs)
+	forward := slices.Clone(b.forward)
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		if sub.queue != nil {
+			sub.queue.push(ev)
+		} else {
+			sub.fn(ev)
+		}
+	}
+	for _, to := range forward {
+		to.publish(ev)
+	}
+}
+
+// hookList returns the bus's hooks followed by those of the buses it
+// forwards to.
+func (b *Bus) hookList() []*Hooks {
+	if b == nil {
+		return nil
+	}
+	b.mu.RLock()
+	hooks := slices.Clone(b.hooks)
+	forward := slices.Clone(b.forward)
+	b.mu.RUnlock()
+
+	for _, to := range forward {
+		hooks = append(hooks, to.hookList()...)
+	}
+	return hooks
+}
+
+func (b *Bus) beforeGenerate(ctx context.Context, call *GenerateCall) error {
+	for _, h := range b.hookList() {
+		if h.BeforeGenerate != nil {
+			if err := h.BeforeGenerate(ctx, call); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Bus) afterGenerate(ctx context.Context, call *GenerateCall) {
+	for _, h := range b.hookList() {
+		if h.AfterGenerate != nil {
+			h.AfterGenerate(ctx, call)
+		}
+	}
+}
+
+func (b *Bus) beforeToolCall(ctx context.Context, call *ToolCall) error {
+	for _, h := range b.hookList() {
+		if h.BeforeToolCall != nil {
+			if err := h.BeforeToolCall(ctx, call); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Bus) afterToolCall(ctx context.Context, call *ToolCall) {
+	for _, h := range b.hookList() {
+		if h.AfterToolCall != nil {
+			h.AfterToolCall(ctx, call)
+		}
+	}
+}
+
+// eventQueue is an unbounded queue feeding an asynchronous subscriber.
+type eventQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	events []Event
+	closed bool
+}
+
+func newEventQueue() *eventQueue {
+	q := &eventQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *eventQueue) push(ev Event) {
+	q.mu.Lock()
+	if !q.closed {
+		q.events = append(q.events, ev)
+	}
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+func (q *eventQueue) run(fn func(Event)) {
+	for {
+		q.mu.Lock()
+		for len(q.events) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.events) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		ev := q.events[0]
+		q.events = q.events[1:]
+		q.mu.Unlock()
+
+		fn(ev)
+	}
+}
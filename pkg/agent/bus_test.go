@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/standrze/chorus/pkg/tools"
+)
+
+func TestBus_SubscribeAsync(t *testing.T) {
+	var bus Bus
+	release := make(chan struct{})
+	got := make(chan int64, 10)
+
+	cancel := bus.SubscribeAsync(func(ev Event) {
+		<-release
+		got <- ev.Seq
+	})
+	// The subscriber is stuck, yet publishing carries on.
+	for i := 0; i < 5; i++ {
+		bus.publish(Event{Type: EventDelta})
+	}
+	cancel()
+	bus.publish(Event{Type: EventDelta})
+	close(release)
+
+	// Cancelling still delivers what was queued, in order.
+	for want := int64(1); want <= 5; want++ {
+		select {
+		case seq := <-got:
+			if seq != want {
+				t.Fatalf("Expected event %d, got %d", want, seq)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for event %d", want)
+		}
+	}
+}
+
+func TestBus_ForwardAndCancel(t *testing.T) {
+	var agentBus, convBus Bus
+	agentBus.Forward(&convBus)
+
+	var agentSeen, convSeen []EventType
+	cancel := agentBus.Subscribe(func(ev Event) { agentSeen = append(agentSeen, ev.Type) })
+	convBus.Subscribe(func(ev Event) { convSeen = append(convSeen, ev.Type) })
+
+	agentBus.publish(Event{Type: EventToolCall})
+	cancel()
+	agentBus.publish(Event{Type: EventToolResult})
+	convBus.publish(Event{Type: EventFinished})
+
+	if len(agentSeen) != 1 {
+		t.Errorf("Expected one event before cancelling, got %v", agentSeen)
+	}
+	if len(convSeen) != 3 {
+		t.Errorf("Expected forwarded and own events, got %v", convSeen)
+	}
+	if !agentBus.listening() {
+		t.Error("Expected a bus forwarding to a subscribed bus to be listening")
+	}
+}
+
+func TestBus_SubscriberPublishes(t *testing.T) {
+	agent := NewAgent(nil, WithName("Writer"))
+	var seen []EventType
+	agent.Events.Subscribe(func(ev Event) {
+		seen = append(seen, ev.Type)
+		// Answering a tool call from a subscriber publishes to the same bus.
+		if ev.Type == EventToolCall {
+			agent.UserMessage("noted")
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		agent.Events.publish(Event{Type: EventToolCall})
+		agent.Events.publish(Event{Type: EventToolResult})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publishing from a subscriber deadlocked")
+	}
+	if want := []EventType{EventToolCall, EventMessage, EventToolResult}; !slices.Equal(seen, want) {
+		t.Errorf("Expected %v, got %v", want, seen)
+	}
+}
+
+func TestConversation_Close(t *testing.T) {
+	worker := NewAgent(&mockClient{}, WithName("Worker"))
+	orchestrator := NewAgent(&mockClient{}, WithName("Lead"), WithRole(RoleOrchestrator))
+	first, _ := NewConversation(t.Context(), orchestrator, worker)
+	var heard []EventType
+	first.Events().Subscribe(func(ev Event) { heard = append(heard, ev.Type) })
+	first.Close()
+
+	second, _ := NewConversation(t.Context(), orchestrator, worker)
+	defer second.Close()
+	worker.UserMessage("hello")
+	if !slices.Equal(heard, []EventType{EventClosed}) {
+		t.Errorf("Expected a closed conversation to hear nothing more, got %v", heard)
+	}
+}
+
+func TestRespond_ToolHooks(t *testing.T) {
+	client := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "Echo", `{"text":"hello"}`),
+		toolCallCompletion("call_2", "Echo", `{"text":"blocked"}`),
+		textCompletion("done"),
+	}}
+	agent := NewAgent(client, WithFunctionTools(tools.FunctionTool{
+		Name: "Echo",
+		Func: func(args struct {
+			Text string `json:"text"`
+		}) (string, error) {
+			return args.Text, nil
+		},
+	}))
+
+	remove := agent.Events.Hook(Hooks{
+		BeforeToolCall: func(ctx context.Context, call *ToolCall) error {
+			if call.Arguments == `{"text":"blocked"}` {
+				return errors.New("not allowed")
+			}
+			call.Arguments = `{"text":"rewritten"}`
+			return nil
+		},
+		AfterToolCall: func(ctx context.Context, call *ToolCall) {
+			if call.Err == nil {
+				call.Result += "!"
+			}
+		},
+	})
+	defer remove()
+
+	var results []Event
+	agent.Events.Subscribe(func(ev Event) {
+		if ev.Type == EventToolResult {
+			results = append(results, ev)
+		}
+	})
+
+	if _, err := agent.Respond(context.Background(), WithUserMessage("go")); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Text != "rewritten!" || results[1].Error != "not allowed" {
+		t.Errorf("Expected a rewritten result and a veto, got %+v", results)
+	}
+	if got := agent.Messages[2].OfTool.Content.OfString.Value; got != "rewritten!" {
+		t.Errorf("Expected the rewritten result in the history, got %q", got)
+	}
+}
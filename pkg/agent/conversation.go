@@ -2,17 +2,54 @@ package agent
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-
-	"github.com/openai/openai-go/v3"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/standrze/chorus/pkg/client"
+	"github.com/standrze/chorus/pkg/log"
 	"github.com/standrze/chorus/pkg/tools"
 )
 
+var (
+	// ErrMaxTurns is returned by Run when the run doesn't finish within the
+	// turn limit, or can't, as when a strategy needs more turns than it allows.
+	ErrMaxTurns = errors.New("max turns reached")
+	// ErrBudgetExceeded is returned by Run when the agents use more tokens than the budget allows.
+	ErrBudgetExceeded = errors.New("token budget exceeded")
+)
+
 type Conversation struct {
+	id           string
 	ctx          context.Context
 	agents       map[string]*Agent
+	order        []*Agent // the agents in the order they were given
 	orchestrator *Agent
 	maxTurns     int
+	tokenBudget  int64
+	workspace    Workspace
+	events       Bus
+	unforward    []func()     // stops the agents' buses forwarding, see Close
+	turn         atomic.Int64 // the turn Run is on, read by events from other goroutines
+	strategy     TurnStrategy
+	board        Board
+
+	planMu sync.Mutex
+	plan   *Plan
+}
+
+// newConversationID returns a sortable, unique run identifier.
+func newConversationID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
 }
 
 func NewConversation(ctx context.Context, agents ...*Agent) (*Conversation, error) {
@@ -40,11 +77,25 @@ func NewConversation(ctx context.Context, agents ...*Agent) (*Conversation, erro
 		return nil, fmt.Errorf("conversation requires at least 2 agents (1 orchestrator + 1 worker)")
 	}
 
+	id := newConversationID()
 	conv := &Conversation{
+		id:           id,
 		ctx:          ctx,
 		agents:       agentMap,
+		order:        slices.Clone(agents),
 		orchestrator: orchestrator,
+		strategy:     &Orchestrated{},
 		maxTurns:     20,
+		workspace:    Workspace{Dir: filepath.Join(WorkspaceDir, id)},
+	}
+	conv.events.decorate = conv.decorate
+
+	// The conversation hears from, and hooks into, all of its agents.
+	for _, agent := range agentMap {
+		if agent.Events == nil {
+			agent.Events = &Bus{}
+		}
+		conv.unforward = append(conv.unforward, agent.Events.Forward(&conv.events))
 	}
 
 	// Inject standard tools into all agents
@@ -52,36 +103,131 @@ func NewConversation(ctx context.Context, agents ...*Agent) (*Conversation, erro
 	// We'll assume all agents share the same client or at least the orchestrator has one we can use.
 	// In the current NewAgent setup, each agent has its own client.
 	// Let's use the orchestrator's client for the Summarize tool factory.
-	client := orchestrator.Client
+	standardTools := conv.standardTools(orchestrator.Client)
+
+	for _, agent := range agentMap {
+		for _, tool := range standardTools {
+			agent.AddFunctionTool(tool)
+		}
+	}
 
-	standardTools := []tools.FunctionTool{
+	return conv, nil
+}
+
+// standardTools are given to every agent in the conversation.
+func (c *Conversation) standardTools(client client.Client) []tools.FunctionTool {
+	return []tools.FunctionTool{
 		{
 			Name:        "WriteToFile",
 			Description: "Writes content to a file in the workspace. Overwrites if exists.",
-			Func:        WriteToFile,
+			Func:        c.writeToFile,
 		},
 		{
 			Name:        "ReadFromFile",
 			Description: "Reads content from a file in the workspace.",
-			Func:        ReadFromFile,
+			Func:        c.readFromFile,
 		},
 		{
 			Name:        "Summarize",
 			Description: "Summarizes the provided text.",
 			Func:        NewSummarizeTool(client),
 		},
+		{
+			Name:        "ReadBoard",
+			Description: "Reads the board the conversation's agents share: the objective, the tasks handed out and the replies posted.",
+			Func:        c.readBoard,
+		},
 	}
+}
 
-	for _, agent := range agentMap {
-		for _, tool := range standardTools {
-			agent.AddFunctionTool(tool)
+// BuiltinTools returns the tools a conversation gives an agent with the given
+// role, so they can be inspected. They are not bound to a conversation and
+// must not be called.
+func BuiltinTools(role Role) []tools.FunctionTool {
+	c := &Conversation{}
+	builtin := c.standardTools(nil)
+	if role == RoleOrchestrator {
+		builtin = append(builtin, c.orchestratorTools(nil)...)
+	}
+	return builtin
+}
+
+// ID identifies this conversation run.
+func (c *Conversation) ID() string {
+	return c.id
+}
+
+// Agents returns the conversation's agents, the orchestrator first and the
+// workers by name.
+func (c *Conversation) Agents() []*Agent {
+	agents := []*Agent{c.orchestrator}
+	for _, name := range slices.Sorted(maps.Keys(c.agents)) {
+		if a := c.agents[name]; a != c.orchestrator {
+			agents = append(agents, a)
 		}
 	}
+	return agents
+}
 
-	return conv, nil
+// Close detaches the conversation from its agents, so an agent that goes on
+// to another conversation no longer reports to this one or runs its hooks.
+// The agents keep their history. Close must not be called during Run.
+func (c *Conversation) Close() {
+	if c.unforward == nil {
+		return
+	}
+	c.emit(Event{Type: EventClosed})
+	for _, stop := range c.unforward {
+		stop()
+	}
+	c.unforward = nil
+}
+
+// Workspace returns the directory this run's file tools operate in.
+// By default each run gets its own directory under ./workspace.
+func (c *Conversation) Workspace() string {
+	return c.workspace.Dir
+}
+
+// SetWorkspace moves the run's file tools to dir.
+func (c *Conversation) SetWorkspace(dir string) {
+	c.workspace = Workspace{Dir: dir}
+}
+
+// SetMaxTurns limits how many turns Run may take: orchestrator turns, or
+// under the other strategies, one per reply.
+func (c *Conversation) SetMaxTurns(n int) {
+	c.maxTurns = n
+}
+
+// SetTokenBudget limits the total tokens all agents may use during Run.
+// Zero means no limit.
+func (c *Conversation) SetTokenBudget(tokens int64) {
+	c.tokenBudget = tokens
+}
+
+// TotalTokens sums the tokens used by every agent in the conversation.
+func (c *Conversation) TotalTokens() int64 {
+	var total int64
+	for _, a := range c.agents {
+		total += a.TotalTokens
+	}
+	return total
+}
+
+func (c *Conversation) writeToFile(args WriteArgs) (string, error) {
+	return c.workspace.WriteToFile(args)
+}
+
+func (c *Conversation) readFromFile(args ReadArgs) (string, error) {
+	return c.workspace.ReadFromFile(args)
 }
 
 func (c *Conversation) Interact(agentName string, instruction string) (string, error) {
+	return c.interact(log.WithAttrs(c.ctx, "conversation", c.id), agentName, instruction)
+}
+
+func (c *Conversation) interact(ctx context.Context, agentName string, instruction string) (string, error) {
 	worker, exists := c.agents[agentName]
 	if !exists {
 		return "", fmt.Errorf("agent '%s' not found. Available agents: %s", agentName, c.listAgentNames())
@@ -89,13 +235,11 @@ func (c *Conversation) Interact(agentName string, instruction string) (string, e
 
 	worker.UserMessage(fmt.Sprintf("Task: %s", instruction))
 
-	resp, err := worker.Generate(c.ctx)
+	// Respond lets the worker use its own tools before answering.
+	content, err := worker.Respond(ctx)
 	if err != nil {
 		return "", fmt.Errorf("worker failed: %w", err)
 	}
 
-	content := resp.Choices[0].Message.Content
-	worker.Messages = append(worker.Messages, openai.AssistantMessage(content))
-
 	return content, nil
 }
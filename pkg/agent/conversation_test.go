@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -84,7 +85,7 @@ func TestConversation_ToolsRegistered(t *testing.T) {
 		Instructions: "Do work",
 	}
 
-	_, err := conv.delegateTask(args)
+	_, err := conv.delegateTask(context.Background(), args)
 	if err == nil {
 		t.Error("Expected error for unknown agent")
 	}
@@ -117,7 +118,7 @@ func TestConversation_ExecuteToolCall(t *testing.T) {
 		Type: "function",
 	}
 
-	res, err := conv.executeToolCall(tc)
+	res, err := conv.executeToolCall(context.Background(), tc)
 	if err != nil {
 		t.Fatalf("executeToolCall failed: %v", err)
 	}
@@ -126,3 +127,159 @@ func TestConversation_ExecuteToolCall(t *testing.T) {
 		t.Errorf("Unexpected result: %s", res)
 	}
 }
+
+func TestConversation_Run(t *testing.T) {
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "DelegateTask", `{"agent_name": "Worker", "instructions": "write"}`),
+		toolCallCompletion("call_2", "Finish", `{"result": "essay written"}`),
+	}}
+	workerClient := &mockClient{responses: []*openai.ChatCompletion{textCompletion("an essay")}}
+
+	orch := NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	worker := NewAgent(workerClient, WithName("Worker"))
+	conv, err := NewConversation(context.Background(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	result, err := conv.Run("write an essay")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "essay written" {
+		t.Errorf("Unexpected result: %s", result)
+	}
+	if len(workerClient.calls) != 1 {
+		t.Errorf("Expected the worker to be called once, got %d", len(workerClient.calls))
+	}
+}
+
+func TestConversation_RunLimits(t *testing.T) {
+	looping := func() *mockClient {
+		completion := textCompletion("thinking")
+		completion.Usage.TotalTokens = 100
+		return &mockClient{responses: []*openai.ChatCompletion{completion, completion, completion}}
+	}
+
+	orch := NewAgent(looping(), WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	conv, _ := NewConversation(context.Background(), orch, NewAgent(nil, WithName("Worker")))
+	conv.SetMaxTurns(2)
+	if _, err := conv.Run("loop"); !errors.Is(err, ErrMaxTurns) {
+		t.Errorf("Expected ErrMaxTurns, got %v", err)
+	}
+
+	orch = NewAgent(looping(), WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	conv, _ = NewConversation(context.Background(), orch, NewAgent(nil, WithName("Worker")))
+	conv.SetTokenBudget(150)
+	if _, err := conv.Run("loop"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+}
+
+func TestConversation_Events(t *testing.T) {
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "DelegateTask", `{"agent_name": "Worker", "instructions": "write"}`),
+		toolCallCompletion("call_2", "Finish", `{"result": "essay written"}`),
+	}}
+	workerClient := &mockClient{responses: []*openai.ChatCompletion{textCompletion("an essay")}}
+
+	orch := NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	worker := NewAgent(workerClient, WithName("Worker"))
+	conv, err := NewConversation(context.Background(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	var events []Event
+	conv.Events().Subscribe(func(ev Event) {
+		if ev.Seq != int64(len(events)+1) || ev.Conversation != conv.ID() {
+			t.Errorf("Expected seq %d in %s, got %+v", len(events)+1, conv.ID(), ev)
+		}
+		events = append(events, ev)
+	})
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Leave out the history and generation events; they are counted below.
+	var flow []Event
+	counts := map[EventType]int{}
+	for _, ev := range events {
+		counts[ev.Type]++
+		if ev.Type != EventMessage && ev.Type != EventGenerated {
+			flow = append(flow, ev)
+		}
+	}
+	want := []EventType{
+		EventRunStarted,
+		EventTurnStarted, EventToolCall, EventDelegation, EventToolResult,
+		EventTurnStarted, EventToolCall, EventToolResult, EventFinished,
+	}
+	if len(flow) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), flow)
+	}
+	for i, ev := range flow {
+		if ev.Type != want[i] {
+			t.Errorf("Event %d: expected %s, got %+v", i, want[i], ev)
+		}
+	}
+	if flow[0].Text != "write an essay" || flow[2].ToolCallID != "call_1" {
+		t.Errorf("Unexpected run start or tool call: %+v, %+v", flow[0], flow[2])
+	}
+	if flow[3].Agent != "Worker" || flow[3].Text != "write" {
+		t.Errorf("Unexpected delegation event %+v", flow[3])
+	}
+	if flow[5].Turn != 2 || flow[8].Text != "essay written" {
+		t.Errorf("Unexpected turn or result: %+v, %+v", flow[5], flow[8])
+	}
+	// Objective, two assistant messages and two tool results for the
+	// orchestrator; the task and the reply for the worker.
+	if counts[EventMessage] != 7 || counts[EventGenerated] != 3 {
+		t.Errorf("Expected 7 messages and 3 generations, got %v", counts)
+	}
+}
+
+func TestConversation_HooksApplyToAgents(t *testing.T) {
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "DelegateTask", `{"agent_name": "Worker", "instructions": "write"}`),
+		toolCallCompletion("call_2", "Finish", `{"result": "essay written"}`),
+	}}
+	workerClient := &mockClient{responses: []*openai.ChatCompletion{textCompletion("an essay")}}
+
+	orch := NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	worker := NewAgent(workerClient, WithName("Worker"))
+	conv, err := NewConversation(context.Background(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	var generations []string
+	conv.Events().Hook(Hooks{
+		BeforeGenerate: func(ctx context.Context, call *GenerateCall) error {
+			generations = append(generations, call.Agent.Name)
+			call.Params.Model = "rewritten"
+			return nil
+		},
+		BeforeToolCall: func(ctx context.Context, call *ToolCall) error {
+			if call.Name == "DelegateTask" {
+				return errors.New("delegation is disabled")
+			}
+			return nil
+		},
+	})
+
+	result, err := conv.Run("write an essay")
+	if err != nil || result != "essay written" {
+		t.Fatalf("Unexpected result %q, %v", result, err)
+	}
+	if len(workerClient.calls) != 0 {
+		t.Errorf("Expected the vetoed delegation not to reach the worker")
+	}
+	if len(generations) != 2 || orchClient.calls[0].Model != "rewritten" {
+		t.Errorf("Expected hooks to see and rewrite both requests, got %v / %s", generations, orchClient.calls[0].Model)
+	}
+	veto := orch.Messages[2].OfTool
+	if veto == nil || !strings.Contains(veto.Content.OfString.Value, "delegation is disabled") {
+		t.Errorf("Expected the veto to be reported to the model, got %+v", orch.Messages[2])
+	}
+}
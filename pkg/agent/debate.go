@@ -0,0 +1,289 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// DefaultDebateRounds is how many times the agents revise their answers in a
+// Debate that doesn't say.
+const DefaultDebateRounds = 2
+
+// Debate has the agents answer the objective on their own, then see each
+// other's answers and revise theirs over a number of rounds. A judge then
+// decides the answer or, without one, the most common answer wins, ties going
+// to the answer given by the earliest agent. The result is that answer.
+//
+// Agents answer in JSON, with their reasoning and their answer, unless they
+// have a response format of their own. The JSON is asked for with a schema
+// the server enforces and, if the server rejects the schema, by the prompt
+// alone. Replies that aren't JSON count as answers in full. Answers are
+// compared ignoring case, spacing and trailing punctuation.
+type Debate struct {
+	// Agents debate, in this order; empty means every worker but the judge.
+	// At least two are needed.
+	Agents []string
+	// Rounds is how many times the agents revise their answers; zero means
+	// DefaultDebateRounds.
+	Rounds int
+	// Judge names the agent that decides the answer; empty means a vote.
+	Judge string
+
+	// Agreement says how each agent's answers compared, once the debate is
+	// over.
+	Agreement []Agreement
+
+	objective string
+	speakers  []*Agent
+	judge     *Agent
+	answers   [][]debateAnswer // by round, then speaker
+	round     int
+	next      int  // the speaker answering in round
+	plain     bool // the server rejected answerFormat
+}
+
+// Agreement is how one agent fared in a Debate.
+type Agreement struct {
+	Agent string `json:"agent"`
+	// Answer is the agent's last answer.
+	Answer string `json:"answer"`
+	// Agreed says whether Answer is the debate's answer.
+	Agreed bool `json:"agreed"`
+	// Peers is the share of the other agents whose last answer was the same.
+	Peers float64 `json:"peers"`
+	// Changes counts the rounds in which the agent changed its answer.
+	Changes int `json:"changes"`
+}
+
+// debateAnswer is what an agent answers in a round of a Debate.
+type debateAnswer struct {
+	Reasoning string `json:"reasoning"`
+	Answer    string `json:"answer"`
+}
+
+// answerFormat asks for a debateAnswer.
+var answerFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+	OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+		JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:   "answer",
+			Strict: openai.Bool(true),
+			Schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"reasoning": map[string]any{"type": "string", "description": "How you reached your answer"},
+					"answer":    map[string]any{"type": "string", "description": "Your answer alone, as briefly as it can be put"},
+				},
+				"required":             []string{"reasoning", "answer"},
+				"additionalProperties": false,
+			},
+		},
+	},
+}
+
+const answerInstructions = `Reply in JSON with "reasoning", how you reached your answer, and "answer", your answer alone, as briefly as it can be put.`
+
+func (s *Debate) Name() string { return StrategyDebate }
+
+func (s *Debate) Start(ctx context.Context, c *Conversation, objective string) (err error) {
+	s.judge = nil
+	if s.Judge != "" {
+		if s.judge = c.agents[s.Judge]; s.judge == nil {
+			return fmt.Errorf("judge '%s' not found. Available agents: %s", s.Judge, c.listAgentNames())
+		}
+	}
+	s.speakers, err = c.speakers(s.Agents)
+	if err != nil {
+		return err
+	}
+	if len(s.Agents) == 0 {
+		// A judge who is a worker decides rather than debates.
+		s.speakers = slices.DeleteFunc(s.speakers, func(a *Agent) bool { return a == s.judge })
+	}
+	if len(s.speakers) < 2 {
+		return fmt.Errorf("a debate needs at least two agents, got %d", len(s.speakers))
+	}
+
+	// Every agent answers once a round, and the judge once at the end.
+	turns := (s.rounds() + 1) * len(s.speakers)
+	if s.judge != nil {
+		turns++
+	}
+	if err := c.fits(s, turns); err != nil {
+		return err
+	}
+
+	s.objective, s.round, s.next, s.plain, s.Agreement = objective, 0, 0, false, nil
+	s.answers = make([][]debateAnswer, s.rounds()+1)
+	for i := range s.answers {
+		s.answers[i] = make([]debateAnswer, len(s.speakers))
+	}
+	return nil
+}
+
+func (s *Debate) rounds() int {
+	if s.Rounds <= 0 {
+		return DefaultDebateRounds
+	}
+	return s.Rounds
+}
+
+func (s *Debate) Turn(ctx context.Context, c *Conversation) (string, bool, error) {
+	if s.round == len(s.answers) {
+		return s.decide(ctx, c)
+	}
+
+	agent := s.speakers[s.next]
+	reply, err := s.speak(ctx, c, agent, s.prompt(agent))
+	if err != nil {
+		return "", false, err
+	}
+	s.answers[s.round][s.next] = parseAnswer(reply)
+	if s.next++; s.next == len(s.speakers) {
+		s.round, s.next = s.round+1, 0
+	}
+	if s.round < len(s.answers) || s.judge != nil {
+		return "", false, nil
+	}
+	return s.decide(ctx, c)
+}
+
+// prompt is what agent is asked in the current round: the objective on its
+// own at first, then also its last answer and everyone else's.
+func (s *Debate) prompt(agent *Agent) []openai.ChatCompletionMessageParamUnion {
+	messages := append(systemMessages(agent), openai.UserMessage(s.objective+"\n\n"+answerInstructions))
+	if s.round == 0 {
+		return messages
+	}
+
+	previous := s.answers[s.round-1]
+	var others strings.Builder
+	for i, a := range s.speakers {
+		if a == agent {
+			data, _ := json.Marshal(previous[i])
+			messages = append(messages, openai.AssistantMessage(string(data)))
+		} else {
+			fmt.Fprintf(&others, "%s answered: %s\nReasoning: %s\n\n", a.Name, previous[i].Answer, previous[i].Reasoning)
+		}
+	}
+	others.WriteString("Weigh their answers against yours and answer again, changing your answer only if they convince you. " + answerInstructions)
+	return append(messages, openai.UserMessage(others.String()))
+}
+
+// speak has agent answer from messages in JSON, unless it has a response
+// format of its own. Once the server rejects answerFormat as a bad request,
+// the prompt alone asks for JSON for the rest of the run.
+func (s *Debate) speak(ctx context.Context, c *Conversation, agent *Agent, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
+	format := agent.ResponseFormat
+	if s.plain || format.OfText != nil || format.OfJSONObject != nil || format.OfJSONSchema != nil {
+		return c.speak(ctx, agent, messages)
+	}
+
+	agent.ResponseFormat = answerFormat
+	reply, err := c.speak(ctx, agent, messages)
+	agent.ResponseFormat = format
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		convLog.WarnContext(ctx, "Answer schema rejected, asking for JSON in the prompt instead", "agent", agent.Name, "error", err)
+		s.plain = true
+		return c.speak(ctx, agent, messages)
+	}
+	return reply, err
+}
+
+// decide settles the debate, by the judge or by a vote, and tells
+// subscribers how each agent agreed.
+func (s *Debate) decide(ctx context.Context, c *Conversation) (string, bool, error) {
+	last := s.answers[len(s.answers)-1]
+	var answer, by string
+	if s.judge != nil {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s\n\nAfter debating it, the agents' final answers are:\n\n", s.objective)
+		for i, a := range s.speakers {
+			fmt.Fprintf(&b, "%s answered: %s\nReasoning: %s\n\n", a.Name, last[i].Answer, last[i].Reasoning)
+		}
+		b.WriteString("Decide the correct answer. " + answerInstructions)
+
+		messages := append(systemMessages(s.judge), openai.UserMessage(b.String()))
+		reply, err := s.speak(ctx, c, s.judge, messages)
+		if err != nil {
+			return "", false, err
+		}
+		answer, by = parseAnswer(reply).Answer, s.judge.Name
+	} else {
+		answer = vote(last)
+	}
+
+	s.Agreement = s.agreement(answer)
+	convLog.DebugContext(ctx, "Debate settled", "answer", answer, "judge", by)
+	c.emit(Event{Type: EventConsensus, Agent: by, Text: answer, Agreement: append([]Agreement(nil), s.Agreement...)})
+	return answer, true, nil
+}
+
+// agreement compares each agent's answers with each other's and with answer.
+func (s *Debate) agreement(answer string) []Agreement {
+	last := s.answers[len(s.answers)-1]
+	stats := make([]Agreement, len(s.speakers))
+	for i, a := range s.speakers {
+		stats[i] = Agreement{Agent: a.Name, Answer: last[i].Answer, Agreed: sameAnswer(last[i].Answer, answer)}
+		same := 0
+		for j := range s.speakers {
+			if j != i && sameAnswer(last[i].Answer, last[j].Answer) {
+				same++
+			}
+		}
+		stats[i].Peers = float64(same) / float64(len(s.speakers)-1)
+		for r := 1; r < len(s.answers); r++ {
+			if !sameAnswer(s.answers[r-1][i].Answer, s.answers[r][i].Answer) {
+				stats[i].Changes++
+			}
+		}
+	}
+	return stats
+}
+
+// vote returns the most common answer, ties going to the earliest.
+func vote(answers []debateAnswer) string {
+	best, votes := "", 0
+	for i, a := range answers {
+		n := 0
+		for _, b := range answers[i:] {
+			if sameAnswer(a.Answer, b.Answer) {
+				n++
+			}
+		}
+		if n > votes {
+			best, votes = a.Answer, n
+		}
+	}
+	return best
+}
+
+// parseAnswer reads a debateAnswer from reply, or takes all of reply as the
+// answer if it isn't one.
+func parseAnswer(reply string) debateAnswer {
+	text := strings.TrimSpace(reply)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.Trim(text, "`\n ")
+	var answer debateAnswer
+	if json.Unmarshal([]byte(text), &answer) != nil || strings.TrimSpace(answer.Answer) == "" {
+		return debateAnswer{Answer: strings.TrimSpace(reply)}
+	}
+	answer.Answer = strings.TrimSpace(answer.Answer)
+	return answer
+}
+
+func sameAnswer(a, b string) bool {
+	return normalizeAnswer(a) == normalizeAnswer(b)
+}
+
+func normalizeAnswer(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimRight(s, ".!")
+}
@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestDebate_Vote(t *testing.T) {
+	a := replies(`{"reasoning": "2+2", "answer": "4"}`, `{"reasoning": "still 2+2", "answer": "4"}`)
+	b := replies("5", `{"reasoning": "A is right", "answer": "4."}`)
+	c := replies(`{"reasoning": "guess", "answer": "5"}`, "The answer is 5")
+	conv, err := NewConversation(t.Context(),
+		NewAgent(nil, WithName("Lead"), WithRole(RoleOrchestrator)),
+		NewAgent(a, WithName("A")),
+		NewAgent(b, WithName("B")),
+		NewAgent(c, WithName("C")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	debate := &Debate{Rounds: 1}
+	conv.SetStrategy(debate)
+
+	var consensus []Event
+	conv.Events().Subscribe(func(ev Event) {
+		if ev.Type == EventConsensus {
+			consensus = append(consensus, ev)
+		}
+	})
+	result, err := conv.Run("What is 2+2?")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "4" {
+		t.Errorf("Expected the majority answer, got %q", result)
+	}
+
+	want := []Agreement{
+		{Agent: "A", Answer: "4", Agreed: true, Peers: 0.5},
+		{Agent: "B", Answer: "4.", Agreed: true, Peers: 0.5, Changes: 1},
+		{Agent: "C", Answer: "The answer is 5", Changes: 1},
+	}
+	if len(consensus) != 1 || consensus[0].Text != "4" || len(consensus[0].Agreement) != len(want) {
+		t.Fatalf("Expected one consensus event, got %+v", consensus)
+	}
+	for i, w := range want {
+		if got := debate.Agreement[i]; got != w {
+			t.Errorf("Agent %s: expected %+v, got %+v", w.Agent, w, got)
+		}
+	}
+
+	// The first answers are given alone, in JSON.
+	first := b.calls[0]
+	if len(first.Messages) != 1 || first.ResponseFormat.OfJSONSchema == nil {
+		t.Errorf("Expected B to answer on its own in JSON, got %+v", first)
+	}
+	// Then each agent sees its own answer and the others'.
+	revise := a.calls[1].Messages
+	if len(revise) != 3 || revise[1].OfAssistant == nil {
+		t.Fatalf("Expected A's answer and the others', got %+v", revise)
+	}
+	others := revise[2].OfUser.Content.OfString.Value
+	if !strings.Contains(others, "B answered: 5\n") || !strings.Contains(others, "C answered: 5\nReasoning: guess") || strings.Contains(others, "A answered") {
+		t.Errorf("Unexpected revision prompt %q", others)
+	}
+}
+
+func TestDebate_Judge(t *testing.T) {
+	judge := replies(`{"reasoning": "it is the capital", "answer": "Paris"}`)
+	a := replies(`{"reasoning": "", "answer": "Paris"}`, `{"reasoning": "", "answer": "Paris"}`)
+	b := replies("Lyon", "Lyon")
+	conv, _ := NewConversation(t.Context(),
+		NewAgent(judge, WithName("Judge"), WithRole(RoleOrchestrator)),
+		NewAgent(a, WithName("A")),
+		NewAgent(b, WithName("B"), WithResponseFormat(openai.ChatCompletionNewParamsResponseFormatUnion{OfText: &openai.ResponseFormatTextParam{}})),
+	)
+
+	conv.SetStrategy(&Debate{Agents: []string{"A"}})
+	if _, err := conv.Run("What is the capital of France?"); err == nil {
+		t.Error("Expected an error for a debate of one")
+	}
+
+	debate := &Debate{Rounds: 1, Judge: "Judge"}
+	conv.SetStrategy(debate)
+	result, err := conv.Run("What is the capital of France?")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "Paris" {
+		t.Errorf("Expected the judge's answer, got %q", result)
+	}
+	if debate.Agreement[1].Agreed || debate.Agreement[1].Peers != 0 {
+		t.Errorf("Expected B to disagree, got %+v", debate.Agreement[1])
+	}
+	if b.calls[0].ResponseFormat.OfText == nil {
+		t.Error("Expected B to keep its own response format")
+	}
+	if asked := judge.calls[0].Messages[0].OfUser.Content.OfString.Value; !strings.Contains(asked, "A answered: Paris") || !strings.Contains(asked, "B answered: Lyon") {
+		t.Errorf("Expected the judge to see both answers, got %q", asked)
+	}
+}
+
+// schemaRejecter fails requests with a JSON schema as a bad request, as
+// servers without structured outputs do, and answers the rest.
+type schemaRejecter struct {
+	*mockClient
+	rejected int
+}
+
+func (s *schemaRejecter) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if params.ResponseFormat.OfJSONSchema != nil {
+		s.rejected++
+		req, _ := http.NewRequest("POST", "http://localhost/v1/chat/completions", nil)
+		return nil, &openai.Error{StatusCode: http.StatusBadRequest, Request: req, Response: &http.Response{StatusCode: http.StatusBadRequest}}
+	}
+	return s.mockClient.ChatCompletion(ctx, params)
+}
+
+func TestDebate_WorkerJudgeWithoutSchema(t *testing.T) {
+	a := &schemaRejecter{mockClient: replies(`{"reasoning": "", "answer": "4"}`, `{"reasoning": "", "answer": "4"}`)}
+	b := &schemaRejecter{mockClient: replies(`{"reasoning": "both said 4", "answer": "4"}`)}
+	c := &schemaRejecter{mockClient: replies(`{"reasoning": "", "answer": "4"}`, `{"reasoning": "", "answer": "4"}`)}
+	conv, _ := NewConversation(t.Context(),
+		NewAgent(nil, WithName("Lead"), WithRole(RoleOrchestrator)),
+		NewAgent(a, WithName("A")),
+		NewAgent(b, WithName("B")),
+		NewAgent(c, WithName("C")),
+	)
+	debate := &Debate{Rounds: 1, Judge: "B"}
+	conv.SetStrategy(debate)
+
+	result, err := conv.Run("What is 2+2?")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "4" {
+		t.Errorf("Expected the judge's answer, got %q", result)
+	}
+	// The judge only decides, and the schema is only tried once.
+	if len(debate.Agreement) != 2 || debate.Agreement[0].Agent != "A" || debate.Agreement[1].Agent != "C" {
+		t.Errorf("Expected A and C to debate, got %+v", debate.Agreement)
+	}
+	if a.rejected+b.rejected+c.rejected != 1 || len(b.calls) != 1 {
+		t.Errorf("Expected one rejected schema and one call to the judge, got %d, %d, %d and %d", a.rejected, b.rejected, c.rejected, len(b.calls))
+	}
+	if asked := c.calls[0].Messages[0].OfUser.Content.OfString.Value; !strings.Contains(asked, answerInstructions) {
+		t.Errorf("Expected the prompt to ask for JSON, got %q", asked)
+	}
+}
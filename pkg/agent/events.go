@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// EventType names something that happened to an agent or in a conversation.
+type EventType string
+
+const (
+	// EventRunStarted starts Run, with its objective and turn strategy.
+	EventRunStarted EventType = "run_started"
+	// EventTurnStarted starts each turn of Run, with the agent taking it.
+	EventTurnStarted EventType = "turn_started"
+	// EventDelta carries reply text as an agent streams it.
+	EventDelta EventType = "delta"
+	// EventGenerated follows each request to the model, with the tokens it
+	// used, in total and split into prompt and completion, or its error.
+	EventGenerated EventType = "generated"
+	// EventMessage is sent when a message is added to an agent's history.
+	EventMessage EventType = "message"
+	// EventToolCall is sent before an agent's tool call runs.
+	EventToolCall EventType = "tool_call"
+	// EventToolResult carries the outcome of a tool call.
+	EventToolResult EventType = "tool_result"
+	// EventDelegation is sent when the orchestrator hands a task to a worker.
+	EventDelegation EventType = "delegation"
+	// EventPlanCreated carries the steps of a plan the orchestrator made,
+	// and the plan itself. Plan events are the orchestrator's, and reach the
+	// conversation through its bus.
+	EventPlanCreated EventType = "plan_created"
+	// EventPlanUpdated carries the plan after a change, with what changed.
+	EventPlanUpdated EventType = "plan_updated"
+	// EventConsensus settles a Debate with its answer, the judge who decided
+	// it if there was one, and how each agent agreed.
+	EventConsensus EventType = "consensus"
+	// EventFinished ends a successful Run with its result.
+	EventFinished EventType = "finished"
+	// EventError ends a Run that failed.
+	EventError EventType = "error"
+	// EventClosed is the last event of a conversation, sent by Close.
+	EventClosed EventType = "closed"
+)
+
+// Event is something that happened to an agent or in a conversation. Which
+// fields are set depends on the type. Seq numbers the events a Bus delivers
+// in the order they happened.
+type Event struct {
+	Seq              int64                                   `json:"seq"`
+	Type             EventType                               `json:"type"`
+	Time             time.Time                               `json:"time"`
+	Conversation     string                                  `json:"conversation,omitempty"`
+	Agent            string                                  `json:"agent,omitempty"`
+	Turn             int                                     `json:"turn,omitempty"`
+	Model            string                                  `json:"model,omitempty"`
+	Tokens           int64                                   `json:"tokens,omitempty"`
+	PromptTokens     int64                                   `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64                                   `json:"completion_tokens,omitempty"`
+	Tool             string                                  `json:"tool,omitempty"`
+	ToolCallID       string                                  `json:"tool_call_id,omitempty"`
+	Arguments        string                                  `json:"arguments,omitempty"`
+	Text             string                                  `json:"text,omitempty"`
+	Strategy         string                                  `json:"strategy,omitempty"`
+	Steps            []string                                `json:"steps,omitempty"`
+	Plan             *Plan                                   `json:"plan,omitempty"`
+	Agreement        []Agreement                             `json:"agreement,omitempty"`
+	Message          *openai.ChatCompletionMessageParamUnion `json:"message,omitempty"`
+	Error            string                                  `json:"error,omitempty"`
+}
+
+// Events returns the conversation's bus. It carries the conversation's own
+// events and those of its agents, and its hooks apply to every agent.
+func (c *Conversation) Events() *Bus {
+	return &c.events
+}
+
+func (c *Conversation) emit(ev Event) {
+	c.events.publish(ev)
+}
+
+// decorate stamps events passing through the conversation's bus with the
+// conversation and the turn of Run they happened in.
+func (c *Conversation) decorate(ev *Event) {
+	ev.Conversation = c.id
+	if ev.Turn == 0 {
+		ev.Turn = int(c.turn.Load())
+	}
+}
+
+func toolResultEvent(agent string, call *ToolCall) Event {
+	ev := Event{Type: EventToolResult, Agent: agent, Tool: call.Name, ToolCallID: call.ID, Text: call.Result}
+	if call.Err != nil {
+		ev.Error = call.Err.Error()
+	}
+	return ev
+}
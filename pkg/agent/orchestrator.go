@@ -1,53 +1,52 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/openai/openai-go/v3"
+	"github.com/standrze/chorus/pkg/log"
 	"github.com/standrze/chorus/pkg/tools"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DelegateArgs struct {
 	AgentName    string `json:"agent_name" description:"The name of the agent to delegate to"`
 	Instructions string `json:"instructions" description:"The task instructions for the agent"`
-}
-
-type PlanArgs struct {
-	Steps []string `json:"steps" description:"The list of steps for the plan"`
+	StepID       string `json:"step_id,omitempty" description:"The ID of the plan step the task is for; it is marked in progress, then done or failed"`
 }
 
 type FinishArgs struct {
 	Result string `json:"result" description:"The final result of the conversation"`
 }
 
-func (c *Conversation) delegateTask(args DelegateArgs) (string, error) {
+func (c *Conversation) delegateTask(ctx context.Context, args DelegateArgs) (result string, err error) {
 	if args.AgentName == c.orchestrator.Name {
 		return "", fmt.Errorf("cannot delegate to the orchestrator")
 	}
-	return c.Interact(args.AgentName, args.Instructions)
-}
 
-func (c *Conversation) createPlan(args PlanArgs) (string, error) {
-	// Write plan to file
-	planContent := "Current Plan:\n"
-	for i, step := range args.Steps {
-		planContent += fmt.Sprintf("%d. %s\n", i+1, step)
-	}
+	// The worker's spans nest under this one, inside the orchestrator's tool call.
+	ctx, span := tracer.Start(ctx, "invoke_agent "+args.AgentName, trace.WithAttributes(
+		semconv.GenAIOperationNameInvokeAgent,
+		semconv.GenAIAgentName(args.AgentName),
+		semconv.GenAIConversationID(c.id),
+	))
+	defer func() { EndSpan(span, err) }()
 
-	writeArgs := WriteArgs{
-		Filename: "plan.txt", // Writing to workspace/plan.txt via WriteToFile logic implicitly or explicitly
-		Content:  planContent,
+	if err := c.stepStarted(args.StepID, args.AgentName); err != nil {
+		return "", err
 	}
-
-	// We can reuse the WriteToFile logic, but WriteToFile expects to be called as a tool.
-	// We can just call the underlying logic if we want, or call WriteToFile directly since it's in the same package.
-	_, err := WriteToFile(writeArgs)
-	if err != nil {
-		return "", fmt.Errorf("failed to save plan: %w", err)
+	convLog.DebugContext(ctx, "Delegating task", "worker", args.AgentName, "step", args.StepID)
+	c.emit(Event{Type: EventDelegation, Agent: args.AgentName, Text: args.Instructions})
+	c.board.post(Post{From: c.orchestrator.Name, To: args.AgentName, Text: args.Instructions})
+	result, err = c.interact(ctx, args.AgentName, args.Instructions)
+	c.stepEnded(args.StepID, result, err)
+	if err == nil {
+		c.board.post(Post{From: args.AgentName, To: c.orchestrator.Name, Text: result})
 	}
-
-	return fmt.Sprintf("Plan created with %d steps and saved to plan.txt.", len(args.Steps)), nil
+	return result, err
 }
 
 func (c *Conversation) listAgentNames() string {
@@ -58,83 +57,180 @@ func (c *Conversation) listAgentNames() string {
 	return strings.Join(names, ", ")
 }
 
-func (c *Conversation) Run(objective string) (string, error) {
-	// Setup Orchestrator Tools
-	c.orchestrator.AddFunctionTool(tools.FunctionTool{
-		Name:        "DelegateTask",
-		Description: "Delegate a task to a worker agent. Returns the worker's output.",
-		Func:        c.delegateTask,
-	})
-
-	c.orchestrator.AddFunctionTool(tools.FunctionTool{
-		Name:        "CreatePlan",
-		Description: "Define the plan of execution.",
-		Func:        c.createPlan,
-	})
-
-	// We can't easily break the loop with a tool call unless we handle a specific return value/error.
-	// For now, let's say the Orchestrator is done when it returns a message without tool calls,
-	// or we can add a explicit Finish tool.
-	finished := false
-	finalResult := ""
-
-	c.orchestrator.AddFunctionTool(tools.FunctionTool{
-		Name:        "Finish",
-		Description: "Call this when the objective is met.",
-		Func: func(args FinishArgs) (string, error) {
-			finished = true
-			finalResult = args.Result
-			return "Conversation finished.", nil
+// orchestratorTools are given to the orchestrator when Run starts. finish
+// implements the Finish tool.
+func (c *Conversation) orchestratorTools(finish func(FinishArgs) (string, error)) []tools.FunctionTool {
+	return []tools.FunctionTool{
+		{
+			Name:        "DelegateTask",
+			Description: "Delegate a task to a worker agent. Returns the worker's output.",
+			Func:        c.delegateTask,
+		},
+		{
+			Name:        "CreatePlan",
+			Description: "Define the plan of execution, replacing any plan made before.",
+			Func:        c.createPlan,
+		},
+		{
+			Name:        "UpdatePlanStep",
+			Description: "Record the status of a plan step, and what it produced or why it failed.",
+			Func:        c.updatePlanStep,
+		},
+		{
+			Name:        "GetPlan",
+			Description: "Show the plan with the status and result of each step.",
+			Func:        c.getPlan,
+		},
+		{
+			Name:        "RevisePlan",
+			Description: "Replace the plan's steps when the approach changes, keeping the progress of the steps that remain.",
+			Func:        c.revisePlan,
 		},
-	})
+		{
+			Name:        "Finish",
+			Description: "Call this when the objective is met.",
+			Func:        finish,
+		},
+	}
+}
+
+// Orchestrated is the default turn strategy: the orchestrator works towards
+// the objective, delegating to the other agents, until it calls Finish.
+type Orchestrated struct {
+	finished bool
+	result   string
+}
+
+func (s *Orchestrated) Name() string { return StrategyOrchestrator }
 
-	// Initial Prompt
+func (s *Orchestrated) Start(ctx context.Context, c *Conversation, objective string) error {
+	s.finished, s.result = false, ""
+	for _, tool := range c.orchestratorTools(func(args FinishArgs) (string, error) {
+		s.finished, s.result = true, args.Result
+		c.board.post(Post{From: c.orchestrator.Name, Text: args.Result})
+		return "Conversation finished.", nil
+	}) {
+		c.orchestrator.AddFunctionTool(tool)
+	}
 	c.orchestrator.UserMessage(fmt.Sprintf("Objective: %s", objective))
+	return nil
+}
+
+func (s *Orchestrated) Turn(ctx context.Context, c *Conversation) (string, bool, error) {
+	if err := c.orchestratorTurn(ctx); err != nil {
+		return "", false, err
+	}
+	return s.result, s.finished, nil
+}
+
+// Run works towards objective with the conversation's turn strategy, by
+// default having the orchestrator delegate to the other agents until it calls
+// Finish. It starts with an EventRunStarted and ends with an EventFinished or
+// EventError.
+// The run is traced in a span that every turn, generation and tool call nests
+// under.
+func (c *Conversation) Run(objective string) (string, error) {
+	ctx := log.WithAttrs(c.ctx, "conversation", c.id)
+	ctx, span := tracer.Start(ctx, "chorus.run", trace.WithAttributes(
+		semconv.GenAIConversationID(c.id),
+		semconv.GenAIAgentName(c.orchestrator.Name),
+		strategyKey.String(c.strategy.Name()),
+	))
+	c.emit(Event{Type: EventRunStarted, Agent: c.orchestrator.Name, Text: objective, Strategy: c.strategy.Name()})
+	result, err := c.run(ctx, objective)
+	span.SetAttributes(turnsKey.Int64(c.turn.Load()), totalTokensKey.Int64(c.TotalTokens()))
+	EndSpan(span, err)
+	convLog.DebugContext(ctx, "Run ended", "turns", c.turn.Load(), "tokens", c.TotalTokens(), "error", err)
+
+	if err != nil {
+		c.emit(Event{Type: EventError, Agent: c.orchestrator.Name, Error: err.Error()})
+	} else {
+		c.emit(Event{Type: EventFinished, Agent: c.orchestrator.Name, Text: result})
+	}
+	c.turn.Store(0)
+	return result, err
+}
+
+func (c *Conversation) run(ctx context.Context, objective string) (string, error) {
+	strategy := c.strategy
+	c.board.post(Post{From: userPoster, Text: fmt.Sprintf("Objective: %s", objective)})
+	if err := strategy.Start(ctx, c, objective); err != nil {
+		return "", err
+	}
 
 	for i := 0; i < c.maxTurns; i++ {
-		if finished {
-			return finalResult, nil
+		if c.tokenBudget > 0 && c.TotalTokens() > c.tokenBudget {
+			return "", fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, c.TotalTokens(), c.tokenBudget)
 		}
 
-		resp, err := c.orchestrator.Generate(c.ctx)
+		c.turn.Store(int64(i + 1))
+		result, done, err := c.takeTurn(ctx, strategy)
 		if err != nil {
-			return "", fmt.Errorf("orchestrator generation failed: %w", err)
+			return "", err
+		}
+		if done {
+			return result, nil
 		}
+	}
 
-		choice := resp.Choices[0]
-		msg := choice.Message
+	return "", fmt.Errorf("%w (%d)", ErrMaxTurns, c.maxTurns)
+}
 
-		// Add assistant message to history
-		c.orchestrator.Messages = append(c.orchestrator.Messages, msg.ToParam())
+// takeTurn has strategy take a turn, traced in its own span.
+func (c *Conversation) takeTurn(ctx context.Context, strategy TurnStrategy) (result string, done bool, err error) {
+	turn := c.turn.Load()
+	ctx = log.WithAttrs(ctx, "turn", turn)
+	ctx, span := tracer.Start(ctx, "chorus.turn", trace.WithAttributes(turnKey.Int64(turn)))
+	defer func() { EndSpan(span, err) }()
+	convLog.DebugContext(ctx, "Turn started")
+	return strategy.Turn(ctx, c)
+}
 
-		if len(msg.ToolCalls) == 0 {
-			// If Orchestrator just talks, maybe we should continue or stop?
-			// The user wants strict guidance. Let's assume if it stops calling tools, it might be waiting for user input,
-			// but here we are automated. Let's assume it's just a comment.
-			// Or maybe we treat a non-tool message as the final result if 'Finish' wasn't called?
-			// Let's force it to use 'Finish'.
-			continue
-		}
+// orchestratorTurn has the orchestrator generate once and runs the tools it
+// calls.
+func (c *Conversation) orchestratorTurn(ctx context.Context) error {
+	c.emit(Event{Type: EventTurnStarted, Agent: c.orchestrator.Name})
+
+	// The orchestrator sees where the plan stands each turn, without it
+	// piling up in its history.
+	var options []SendOption
+	if plan := c.Plan(); plan != nil {
+		options = append(options, withNote(plan.String()))
+	}
+	resp, err := c.orchestrator.Generate(ctx, options...)
+	if err != nil {
+		return fmt.Errorf("orchestrator generation failed: %w", err)
+	}
+
+	choice := resp.Choices[0]
+	msg := choice.Message
 
-		// Handle Tool Calls
-		for _, toolCall := range msg.ToolCalls {
-			res, err := c.executeToolCall(toolCall)
-			if err != nil {
-				// Feed error back to agent
-				c.orchestrator.Messages = append(c.orchestrator.Messages, openai.ToolMessage(fmt.Sprintf("Error: %v", err), toolCall.ID))
-			} else {
-				c.orchestrator.Messages = append(c.orchestrator.Messages, openai.ToolMessage(res, toolCall.ID))
-			}
+	// Add assistant message to history
+	c.orchestrator.appendMessage(msg.ToParam())
+
+	if len(msg.ToolCalls) == 0 {
+		// If Orchestrator just talks, maybe we should continue or stop?
+		// The user wants strict guidance. Let's assume if it stops calling tools, it might be waiting for user input,
+		// but here we are automated. Let's assume it's just a comment.
+		// Or maybe we treat a non-tool message as the final result if 'Finish' wasn't called?
+		// Let's force it to use 'Finish'.
+		return nil
+	}
+
+	// Handle Tool Calls
+	for _, toolCall := range msg.ToolCalls {
+		res, err := c.executeToolCall(ctx, toolCall)
+		if err != nil {
+			// Feed error back to agent
+			c.orchestrator.appendMessage(openai.ToolMessage(fmt.Sprintf("Error: %v", err), toolCall.ID))
+		} else {
+			c.orchestrator.appendMessage(openai.ToolMessage(res, toolCall.ID))
 		}
 	}
 
-	return "", fmt.Errorf("max turns reached")
+	return nil
 }
 
-func (c *Conversation) executeToolCall(toolCall openai.ChatCompletionMessageToolCallUnion) (string, error) {
-	// Extract the function name and arguments
-	name := toolCall.Function.Name
-	args := toolCall.Function.Arguments
-
-	return c.orchestrator.CallFunction(name, args)
+func (c *Conversation) executeToolCall(ctx context.Context, toolCall openai.ChatCompletionMessageToolCallUnion) (string, error) {
+	return c.orchestrator.callTool(ctx, toolCall)
 }
@@ -0,0 +1,435 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StepStatus is how far a plan step has got.
+type StepStatus string
+
+const (
+	StepPending    StepStatus = "pending"
+	StepInProgress StepStatus = "in_progress"
+	StepDone       StepStatus = "done"
+	StepFailed     StepStatus = "failed"
+)
+
+// StepStatuses lists the statuses a step can have.
+var StepStatuses = []StepStatus{StepPending, StepInProgress, StepDone, StepFailed}
+
+// PlanFile is the workspace file the plan is saved to whenever it changes.
+const PlanFile = "plan.json"
+
+// PlanStep is one step of a Plan. DependsOn lists the IDs of the steps that
+// must be done before it starts.
+type PlanStep struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Assignee    string     `json:"assignee,omitempty"`
+	Status      StepStatus `json:"status"`
+	DependsOn   []string   `json:"depends_on,omitempty"`
+	Result      string     `json:"result,omitempty"`
+}
+
+// Plan is the orchestrator's plan for a run. It makes one with CreatePlan,
+// tracks progress with UpdatePlanStep, reads it back with GetPlan and changes
+// course with RevisePlan. Delegating a step with DelegateTask moves the step
+// along by itself.
+type Plan struct {
+	Steps []PlanStep `json:"steps"`
+	// Revision counts the changes made since the plan was created.
+	Revision int `json:"revision"`
+}
+
+// Step returns the step with the given ID, or nil.
+func (p *Plan) Step(id string) *PlanStep {
+	for i := range p.Steps {
+		if p.Steps[i].ID == id {
+			return &p.Steps[i]
+		}
+	}
+	return nil
+}
+
+// ready returns an error unless every step that step depends on is done.
+func (p *Plan) ready(step *PlanStep) error {
+	for _, dep := range step.DependsOn {
+		if d := p.Step(dep); d != nil && d.Status != StepDone {
+			return fmt.Errorf("step %s depends on step %s, which is %s", step.ID, dep, d.Status)
+		}
+	}
+	return nil
+}
+
+// Progress returns how many steps are done out of how many there are.
+func (p *Plan) Progress() (done, total int) {
+	for _, step := range p.Steps {
+		if step.Status == StepDone {
+			done++
+		}
+	}
+	return done, len(p.Steps)
+}
+
+// Clone returns a deep copy of p.
+func (p *Plan) Clone() *Plan {
+	if p == nil {
+		return nil
+	}
+	clone := &Plan{Steps: slices.Clone(p.Steps), Revision: p.Revision}
+	for i := range clone.Steps {
+		clone.Steps[i].DependsOn = slices.Clone(clone.Steps[i].DependsOn)
+	}
+	return clone
+}
+
+// String renders the plan as the summary the orchestrator sees each turn.
+func (p *Plan) String() string {
+	done, total := p.Progress()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan (revision %d, %d of %d steps done):\n", p.Revision, done, total)
+	for _, step := range p.Steps {
+		mark := map[StepStatus]string{StepInProgress: "[~]", StepDone: "[x]", StepFailed: "[!]"}[step.Status]
+		if mark == "" {
+			mark = "[ ]"
+		}
+		fmt.Fprintf(&b, "%s %s. %s", mark, step.ID, step.Description)
+
+		var about []string
+		if step.Assignee != "" {
+			about = append(about, step.Assignee)
+		}
+		if len(step.DependsOn) > 0 {
+			about = append(about, "after "+strings.Join(step.DependsOn, ", "))
+		}
+		if len(about) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(about, "; "))
+		}
+		if step.Result != "" {
+			fmt.Fprintf(&b, " → %s", clip(step.Result, 200))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// clip shortens s to about n bytes on one line.
+func clip(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "…"
+}
+
+// PlanStepArgs describes a step to CreatePlan and RevisePlan.
+type PlanStepArgs struct {
+	ID          string   `json:"id,omitempty" description:"A short ID for the step; defaults to its position, 1, 2 and so on. When revising, the ID of an existing step keeps its status and result."`
+	Description string   `json:"description" description:"What the step achieves"`
+	Assignee    string   `json:"assignee,omitempty" description:"The agent expected to carry out the step"`
+	DependsOn   []string `json:"depends_on,omitempty" description:"IDs of the steps that must be done first"`
+}
+
+// UnmarshalJSON also accepts a bare string as a step's description, which
+// smaller models tend to send.
+func (s *PlanStepArgs) UnmarshalJSON(data []byte) error {
+	var description string
+	if json.Unmarshal(data, &description) == nil {
+		*s = PlanStepArgs{Description: description}
+		return nil
+	}
+	type plain PlanStepArgs
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+type PlanArgs struct {
+	Steps []PlanStepArgs `json:"steps" description:"The steps of the plan, in order"`
+}
+
+type UpdatePlanStepArgs struct {
+	StepID string `json:"step_id" description:"The ID of the step"`
+	Status string `json:"status" description:"One of pending, in_progress, done or failed"`
+	Result string `json:"result,omitempty" description:"What the step produced, or why it failed"`
+}
+
+type RevisePlanArgs struct {
+	Steps  []PlanStepArgs `json:"steps" description:"The whole revised plan, in order. Steps left out are dropped."`
+	Reason string         `json:"reason,omitempty" description:"Why the plan changed"`
+}
+
+type GetPlanArgs struct{}
+
+// Plan returns a copy of the conversation's plan, or nil if the orchestrator
+// hasn't made one.
+func (c *Conversation) Plan() *Plan {
+	c.planMu.Lock()
+	defer c.planMu.Unlock()
+	return c.plan.Clone()
+}
+
+func (c *Conversation) createPlan(args PlanArgs) (string, error) {
+	steps, err := c.planSteps(args.Steps, nil)
+	if err != nil {
+		return "", err
+	}
+	plan := &Plan{Steps: steps}
+	if err := c.savePlan(plan); err != nil {
+		return "", err
+	}
+
+	c.planMu.Lock()
+	c.plan = plan
+	c.planMu.Unlock()
+
+	descriptions := make([]string, len(steps))
+	for i, step := range steps {
+		descriptions[i] = step.Description
+	}
+	// Plan events come from the orchestrator, so whoever watches it sees them.
+	c.orchestrator.Events.publish(Event{Type: EventPlanCreated, Agent: c.orchestrator.Name, Steps: descriptions, Plan: plan.Clone()})
+	return fmt.Sprintf("Plan created with %d steps and saved to %s.\n%s", len(steps), PlanFile, plan), nil
+}
+
+func (c *Conversation) getPlan(GetPlanArgs) (string, error) {
+	plan := c.Plan()
+	if plan == nil {
+		return "There is no plan yet. Make one with CreatePlan.", nil
+	}
+	return plan.String(), nil
+}
+
+func (c *Conversation) updatePlanStep(args UpdatePlanStepArgs) (string, error) {
+	status := StepStatus(args.Status)
+	if !slices.Contains(StepStatuses, status) {
+		return "", fmt.Errorf("unknown status %q, want one of pending, in_progress, done or failed", args.Status)
+	}
+
+	text := fmt.Sprintf("Step %s is %s.", args.StepID, status)
+	plan, err := c.changePlan(text, func(plan *Plan) error {
+		step := plan.Step(args.StepID)
+		if step == nil {
+			return fmt.Errorf("no step %q in the plan", args.StepID)
+		}
+		if status == StepInProgress || status == StepDone {
+			if err := plan.ready(step); err != nil {
+				return err
+			}
+		}
+		step.Status = status
+		if args.Result != "" {
+			step.Result = args.Result
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return text + "\n" + plan.String(), nil
+}
+
+func (c *Conversation) revisePlan(args RevisePlanArgs) (string, error) {
+	text := "Plan revised."
+	if args.Reason != "" {
+		text = "Plan revised: " + args.Reason
+	}
+	plan, err := c.changePlan(text, func(plan *Plan) error {
+		steps, err := c.planSteps(args.Steps, plan)
+		if err != nil {
+			return err
+		}
+		plan.Steps = steps
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return text + "\n" + plan.String(), nil
+}
+
+// stepStarted and stepEnded move the step a delegated task is for along.
+// They do nothing if id is empty or names no step. Like UpdatePlanStep,
+// stepStarted refuses to start a step whose dependencies aren't done.
+func (c *Conversation) stepStarted(id, assignee string) error {
+	return c.moveStep(id, fmt.Sprintf("Step %s delegated to %s.", id, assignee), func(plan *Plan, step *PlanStep) error {
+		if err := plan.ready(step); err != nil {
+			return err
+		}
+		step.Status = StepInProgress
+		step.Assignee = assignee
+		return nil
+	})
+}
+
+func (c *Conversation) stepEnded(id, result string, err error) {
+	if err != nil {
+		c.moveStep(id, fmt.Sprintf("Step %s failed.", id), func(_ *Plan, step *PlanStep) error {
+			step.Status, step.Result = StepFailed, err.Error()
+			return nil
+		})
+		return
+	}
+	c.moveStep(id, fmt.Sprintf("Step %s done.", id), func(_ *Plan, step *PlanStep) error {
+		step.Status, step.Result = StepDone, result
+		return nil
+	})
+}
+
+// moveStep changes the step id with move, returning move's error. Without a
+// plan or such a step nothing changes.
+func (c *Conversation) moveStep(id, text string, move func(*Plan, *PlanStep) error) error {
+	if id == "" {
+		return nil
+	}
+	var moveErr error
+	_, err := c.changePlan(text, func(plan *Plan) error {
+		step := plan.Step(id)
+		if step == nil {
+			return fmt.Errorf("no step %q in the plan", id)
+		}
+		moveErr = move(plan, step)
+		return moveErr
+	})
+	if moveErr != nil {
+		return moveErr
+	}
+	if err != nil {
+		convLog.Debug("Plan not updated", "step", id, "error", err)
+	}
+	return nil
+}
+
+// changePlan applies change to a copy of the plan, saves it and, if that
+// works, makes it the plan and emits an EventPlanUpdated with text.
+func (c *Conversation) changePlan(text string, change func(*Plan) error) (*Plan, error) {
+	plan := c.Plan()
+	if plan == nil {
+		return nil, fmt.Errorf("there is no plan yet, make one with CreatePlan")
+	}
+	if err := change(plan); err != nil {
+		return nil, err
+	}
+	plan.Revision++
+	if err := c.savePlan(plan); err != nil {
+		return nil, err
+	}
+
+	c.planMu.Lock()
+	c.plan = plan
+	c.planMu.Unlock()
+
+	c.orchestrator.Events.publish(Event{Type: EventPlanUpdated, Agent: c.orchestrator.Name, Text: text, Plan: plan.Clone()})
+	return plan, nil
+}
+
+// planSteps turns step arguments into steps. Steps whose ID is in previous
+// keep their status and result; the others start pending.
+func (c *Conversation) planSteps(args []PlanStepArgs, previous *Plan) ([]PlanStep, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("a plan needs at least one step")
+	}
+
+	used := make(map[string]bool)
+	for _, arg := range args {
+		used[arg.ID] = true
+	}
+	next := 0
+	steps := make([]PlanStep, len(args))
+	ids := make(map[string]bool)
+	for i, arg := range args {
+		if strings.TrimSpace(arg.Description) == "" {
+			return nil, fmt.Errorf("step %d has no description", i+1)
+		}
+		id := arg.ID
+		// New steps take the lowest number no other step has had.
+		for id == "" {
+			next++
+			if candidate := strconv.Itoa(next); !used[candidate] && (previous == nil || previous.Step(candidate) == nil) {
+				id = candidate
+			}
+		}
+		if ids[id] {
+			return nil, fmt.Errorf("duplicate step ID %q", id)
+		}
+		ids[id] = true
+		if arg.Assignee != "" && c.agents[arg.Assignee] == nil {
+			return nil, fmt.Errorf("step %s is assigned to unknown agent %q (agents: %s)", id, arg.Assignee, c.listAgentNames())
+		}
+
+		steps[i] = PlanStep{ID: id, Description: arg.Description, Assignee: arg.Assignee, Status: StepPending, DependsOn: slices.Clone(arg.DependsOn)}
+		if previous != nil {
+			if old := previous.Step(id); old != nil {
+				steps[i].Status, steps[i].Result = old.Status, old.Result
+			}
+		}
+	}
+
+	for _, step := range steps {
+		for _, dep := range step.DependsOn {
+			if dep == step.ID || !ids[dep] {
+				return nil, fmt.Errorf("step %s depends on unknown step %q", step.ID, dep)
+			}
+		}
+	}
+	if cycle := dependencyCycle(steps); cycle != nil {
+		return nil, fmt.Errorf("steps %s depend on each other, so none of them could start", strings.Join(cycle, " → "))
+	}
+	return steps, nil
+}
+
+// dependencyCycle returns the IDs along a cycle of dependencies among steps,
+// the first repeated at the end, or nil if there is none.
+func dependencyCycle(steps []PlanStep) []string {
+	deps := make(map[string][]string, len(steps))
+	for _, step := range steps {
+		deps[step.ID] = step.DependsOn
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(steps))
+	var path []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch state[id] {
+		case visiting:
+			start := slices.Index(path, id)
+			return append(slices.Clone(path[start:]), id)
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		path = append(path, id)
+		for _, dep := range deps[id] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, step := range steps {
+		if cycle := visit(step.ID); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// savePlan writes plan to PlanFile in the workspace.
+func (c *Conversation) savePlan(plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := c.workspace.WriteToFile(WriteArgs{Filename: PlanFile, Content: string(data)}); err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	return nil
+}
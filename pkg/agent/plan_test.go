@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestPlan_Run(t *testing.T) {
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "CreatePlan", `{"steps": ["research", {"description": "write", "assignee": "Worker", "depends_on": ["1"]}]}`),
+		toolCallCompletion("call_2", "UpdatePlanStep", `{"step_id": "2", "status": "in_progress"}`),
+		toolCallCompletion("call_3", "UpdatePlanStep", `{"step_id": "1", "status": "done", "result": "notes"}`),
+		toolCallCompletion("call_4", "DelegateTask", `{"agent_name": "Worker", "instructions": "write", "step_id": "2"}`),
+		toolCallCompletion("call_5", "Finish", `{"result": "essay written"}`),
+	}}
+	workerClient := &mockClient{responses: []*openai.ChatCompletion{textCompletion("an essay")}}
+
+	orch := NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	worker := NewAgent(workerClient, WithName("Worker"))
+	conv, err := NewConversation(t.Context(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	dir := t.TempDir()
+	conv.SetWorkspace(dir)
+
+	var updates []string
+	conv.Events().Subscribe(func(ev Event) {
+		if ev.Type == EventPlanUpdated {
+			updates = append(updates, ev.Text)
+		}
+	})
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	plan := conv.Plan()
+	if plan == nil || len(plan.Steps) != 2 {
+		t.Fatalf("Expected a plan of two steps, got %+v", plan)
+	}
+	if step := plan.Steps[0]; step.ID != "1" || step.Status != StepDone || step.Result != "notes" {
+		t.Errorf("Unexpected first step %+v", step)
+	}
+	if step := plan.Steps[1]; step.Status != StepDone || step.Result != "an essay" || step.Assignee != "Worker" {
+		t.Errorf("Unexpected second step %+v", step)
+	}
+	want := []string{"Step 1 is done.", "Step 2 delegated to Worker.", "Step 2 done."}
+	if strings.Join(updates, "|") != strings.Join(want, "|") || plan.Revision != 3 {
+		t.Errorf("Expected updates %q, got %q at revision %d", want, updates, plan.Revision)
+	}
+
+	// Step 2 couldn't start before step 1 was done.
+	if got := toolText(orchClient.calls[2]); !strings.Contains(got, "step 2 depends on step 1, which is pending") {
+		t.Errorf("Expected the dependency error, got %q", got)
+	}
+
+	// Each turn ends with the plan, which doesn't stay in the history.
+	note := orchClient.calls[3].Messages[len(orchClient.calls[3].Messages)-1]
+	if note.OfUser == nil || !strings.HasPrefix(note.OfUser.Content.OfString.Value, "Plan (revision 1, 1 of 2 steps done):") {
+		t.Errorf("Expected the plan at the end of the request, got %+v", note)
+	}
+	for _, msg := range orch.Messages {
+		if msg.OfUser != nil && strings.HasPrefix(msg.OfUser.Content.OfString.Value, "Plan (") {
+			t.Errorf("Expected the plan to be left out of the history")
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, PlanFile))
+	if err != nil {
+		t.Fatalf("Expected the plan to be saved: %v", err)
+	}
+	var saved Plan
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Revision != 3 || saved.Steps[1].Status != StepDone {
+		t.Errorf("Unexpected saved plan %s: %v", data, err)
+	}
+}
+
+func TestPlan_Revise(t *testing.T) {
+	orch := NewAgent(nil, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	conv, err := NewConversation(t.Context(), orch, NewAgent(nil, WithName("Worker")))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	conv.SetWorkspace(t.TempDir())
+
+	if _, err := conv.revisePlan(RevisePlanArgs{Steps: []PlanStepArgs{{Description: "research"}}}); err == nil {
+		t.Error("Expected an error revising without a plan")
+	}
+	if _, err := conv.createPlan(PlanArgs{Steps: []PlanStepArgs{{Description: "research"}, {Description: "draft"}, {Description: "edit"}}}); err != nil {
+		t.Fatalf("CreatePlan failed: %v", err)
+	}
+	if _, err := conv.updatePlanStep(UpdatePlanStepArgs{StepID: "1", Status: "done", Result: "notes"}); err != nil {
+		t.Fatalf("UpdatePlanStep failed: %v", err)
+	}
+	if _, err := conv.updatePlanStep(UpdatePlanStepArgs{StepID: "1", Status: "finished"}); err == nil {
+		t.Error("Expected an error for an unknown status")
+	}
+
+	// Steps 1 and 3 stay; the new step doesn't reuse the dropped step's ID.
+	out, err := conv.revisePlan(RevisePlanArgs{
+		Steps:  []PlanStepArgs{{ID: "1", Description: "research"}, {Description: "outline"}, {ID: "3", Description: "edit", DependsOn: []string{"4"}}},
+		Reason: "outline first",
+	})
+	if err != nil {
+		t.Fatalf("RevisePlan failed: %v", err)
+	}
+	if !strings.HasPrefix(out, "Plan revised: outline first\nPlan (revision 2, 1 of 3 steps done):\n[x] 1. research → notes\n[ ] 4. outline\n") {
+		t.Errorf("Unexpected revision %q", out)
+	}
+
+	for _, steps := range [][]PlanStepArgs{
+		{{ID: "1", Description: "a"}, {ID: "1", Description: "b"}},
+		{{Description: "a", Assignee: "Nobody"}},
+		{{Description: "a", DependsOn: []string{"9"}}},
+		{{ID: "a", Description: "a", DependsOn: []string{"b"}}, {ID: "b", Description: "b", DependsOn: []string{"c"}}, {ID: "c", Description: "c", DependsOn: []string{"a"}}},
+		{{Description: " "}},
+		nil,
+	} {
+		if _, err := conv.revisePlan(RevisePlanArgs{Steps: steps}); err == nil {
+			t.Errorf("Expected an error revising to %+v", steps)
+		}
+	}
+	if plan := conv.Plan(); plan.Revision != 2 || len(plan.Steps) != 3 {
+		t.Errorf("Expected failed revisions to leave the plan alone, got %+v", plan)
+	}
+
+	// Delegating a step checks its dependencies as UpdatePlanStep does.
+	if _, err := conv.delegateTask(t.Context(), DelegateArgs{AgentName: "Worker", Instructions: "edit", StepID: "3"}); err == nil || !strings.Contains(err.Error(), "depends on step 4") {
+		t.Errorf("Expected the delegation to wait for step 4, got %v", err)
+	}
+	if step := conv.Plan().Step("3"); step.Status != StepPending {
+		t.Errorf("Expected step 3 to stay pending, got %s", step.Status)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	steps := []PlanStep{{ID: "1"}, {ID: "2", DependsOn: []string{"3"}}, {ID: "3", DependsOn: []string{"1", "2"}}}
+	if got := strings.Join(dependencyCycle(steps), " "); got != "2 3 2" {
+		t.Errorf("Expected the cycle 2 3 2, got %q", got)
+	}
+	steps[2].DependsOn = []string{"1"}
+	if cycle := dependencyCycle(steps); cycle != nil {
+		t.Errorf("Expected no cycle, got %v", cycle)
+	}
+}
+
+// toolText returns the content of the last tool message in params.
+func toolText(params openai.ChatCompletionNewParams) string {
+	for i := len(params.Messages) - 1; i >= 0; i-- {
+		if msg := params.Messages[i]; msg.OfTool != nil {
+			return msg.OfTool.Content.OfString.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Names of the built-in turn strategies.
+const (
+	StrategyOrchestrator = "orchestrator"
+	StrategyRoundRobin   = "round_robin"
+	StrategyGroupChat    = "group_chat"
+	StrategyPipeline     = "pipeline"
+	StrategyDebate       = "debate"
+)
+
+// TurnStrategies lists the built-in turn strategies by name.
+var TurnStrategies = []string{StrategyOrchestrator, StrategyRoundRobin, StrategyGroupChat, StrategyPipeline, StrategyDebate}
+
+// TurnStrategy decides who speaks in a conversation run, and when the run is
+// over. Run posts the objective to the board, calls Start, then calls Turn
+// until it says the run is done, the turn limit is hit or the token budget
+// runs out.
+type TurnStrategy interface {
+	// Name identifies the strategy in events and transcripts.
+	Name() string
+	// Start prepares a run of c towards objective.
+	Start(ctx context.Context, c *Conversation, objective string) error
+	// Turn takes the run's next turn, usually by having an agent Speak. It
+	// returns done, with the run's result, once the run is over.
+	Turn(ctx context.Context, c *Conversation) (result string, done bool, err error)
+}
+
+// DefaultRounds is how many times each agent speaks in a RoundRobin that
+// doesn't say.
+const DefaultRounds = 3
+
+// RoundRobin has the agents speak in turn from the board, for a number of
+// rounds. The result is the last reply.
+type RoundRobin struct {
+	// Agents speak in this order; empty means every worker, in the order
+	// they were given to the conversation.
+	Agents []string
+	// Rounds is how many times each agent speaks; zero means DefaultRounds.
+	Rounds int
+
+	speakers []*Agent
+	turn     int
+}
+
+func (s *RoundRobin) Name() string { return StrategyRoundRobin }
+
+func (s *RoundRobin) Start(ctx context.Context, c *Conversation, objective string) (err error) {
+	s.turn = 0
+	if s.speakers, err = c.speakers(s.Agents); err != nil {
+		return err
+	}
+	return c.fits(s, s.rounds()*len(s.speakers))
+}
+
+func (s *RoundRobin) rounds() int {
+	if s.Rounds <= 0 {
+		return DefaultRounds
+	}
+	return s.Rounds
+}
+
+func (s *RoundRobin) Turn(ctx context.Context, c *Conversation) (string, bool, error) {
+	reply, err := c.Speak(ctx, s.speakers[s.turn%len(s.speakers)])
+	if err != nil {
+		return "", false, err
+	}
+	s.turn++
+	return reply, s.turn == s.rounds()*len(s.speakers), nil
+}
+
+// Pipeline has each agent speak once from the board, in order, so each
+// builds on what the ones before it posted. The result is the last reply.
+type Pipeline struct {
+	// Agents speak in this order, and may appear more than once; empty means
+	// every worker, in the order they were given to the conversation.
+	Agents []string
+
+	speakers []*Agent
+	step     int
+}
+
+func (s *Pipeline) Name() string { return StrategyPipeline }
+
+func (s *Pipeline) Start(ctx context.Context, c *Conversation, objective string) (err error) {
+	s.step = 0
+	if s.speakers, err = c.speakers(s.Agents); err != nil {
+		return err
+	}
+	return c.fits(s, len(s.speakers))
+}
+
+func (s *Pipeline) Turn(ctx context.Context, c *Conversation) (string, bool, error) {
+	reply, err := c.Speak(ctx, s.speakers[s.step])
+	if err != nil {
+		return "", false, err
+	}
+	s.step++
+	return reply, s.step == len(s.speakers), nil
+}
+
+// Finish is what the orchestrator answers in a GroupChat when the objective
+// is met.
+const Finish = "FINISH"
+
+// GroupChat has the orchestrator choose who speaks next from the board, until
+// it answers Finish. The result is the last reply. If the orchestrator's
+// answer names no one, the agents take turns.
+type GroupChat struct {
+	// Agents are who the orchestrator chooses from; empty means every worker.
+	Agents []string
+
+	speakers []*Agent
+	last     int // index of the last speaker, or -1
+}
+
+func (s *GroupChat) Name() string { return StrategyGroupChat }
+
+func (s *GroupChat) Start(ctx context.Context, c *Conversation, objective string) (err error) {
+	s.last = -1
+	s.speakers, err = c.speakers(s.Agents)
+	return err
+}
+
+func (s *GroupChat) Turn(ctx context.Context, c *Conversation) (string, bool, error) {
+	next, err := s.choose(ctx, c)
+	if err != nil {
+		return "", false, err
+	}
+	if next < 0 {
+		last, _ := c.board.last()
+		return last.Text, true, nil
+	}
+	s.last = next
+	_, err = c.Speak(ctx, s.speakers[next])
+	return "", false, err
+}
+
+// choose asks the orchestrator who speaks next. It returns -1 to finish.
+func (s *GroupChat) choose(ctx context.Context, c *Conversation) (int, error) {
+	names := make([]string, len(s.speakers))
+	for i, a := range s.speakers {
+		names[i] = a.Name
+	}
+	prompt := fmt.Sprintf("Who should speak next? Answer with one name from %s and nothing else, or with %s if the objective is met.", strings.Join(names, ", "), Finish)
+
+	// The orchestrator sees the board but keeps nothing of the choice.
+	orchestrator := c.orchestrator
+	messages := orchestrator.Messages
+	orchestrator.Messages = c.board.view(orchestrator)
+	resp, err := orchestrator.Generate(ctx, withNote(prompt))
+	orchestrator.Messages = messages
+	if err != nil {
+		return 0, fmt.Errorf("choosing the next speaker failed: %w", err)
+	}
+	var answer string
+	if len(resp.Choices) > 0 {
+		answer = strings.TrimSpace(resp.Choices[0].Message.Content)
+	}
+
+	_, spoken := c.board.last()
+	if strings.EqualFold(strings.Trim(answer, ".*` "), Finish) && spoken {
+		return -1, nil
+	}
+	if next := mentioned(answer, names); next >= 0 {
+		return next, nil
+	}
+	convLog.DebugContext(ctx, "No speaker chosen, taking turns", "answer", answer)
+	return (s.last + 1) % len(s.speakers), nil
+}
+
+// mentioned returns the index of the name answer is, or failing that the one
+// it mentions first, or -1.
+func mentioned(answer string, names []string) int {
+	for i, name := range names {
+		if strings.EqualFold(strings.Trim(answer, ".*` "), name) {
+			return i
+		}
+	}
+	first, at := -1, len(answer)
+	lower := strings.ToLower(answer)
+	for i, name := range names {
+		if j := strings.Index(lower, strings.ToLower(name)); j >= 0 && j < at {
+			first, at = i, j
+		}
+	}
+	return first
+}
+
+// SetStrategy sets how the agents take turns in Run. The default, and nil,
+// is Orchestrated.
+func (c *Conversation) SetStrategy(strategy TurnStrategy) {
+	if strategy == nil {
+		strategy = &Orchestrated{}
+	}
+	c.strategy = strategy
+}
+
+// Strategy returns how the agents take turns in Run.
+func (c *Conversation) Strategy() TurnStrategy {
+	return c.strategy
+}
+
+// Board returns the message board the conversation's agents share.
+func (c *Conversation) Board() *Board {
+	return &c.board
+}
+
+// Speak has agent take a turn from the board: it replies to what has been
+// posted, using its tools as it likes, and its reply is posted for everyone.
+// Its history is replaced by the board, after its system messages.
+func (c *Conversation) Speak(ctx context.Context, agent *Agent) (string, error) {
+	return c.speak(ctx, agent, c.board.view(agent))
+}
+
+// speak has agent take a turn from messages, and posts its reply.
+func (c *Conversation) speak(ctx context.Context, agent *Agent, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
+	c.emit(Event{Type: EventTurnStarted, Agent: agent.Name})
+	agent.Messages = messages
+	reply, err := agent.Respond(ctx)
+	if err != nil {
+		return "", fmt.Errorf("agent %s failed: %w", agent.Name, err)
+	}
+	c.board.post(Post{From: agent.Name, Text: reply})
+	return reply, nil
+}
+
+// fits checks that strategy, which takes turns turns to finish, can finish
+// within the turn limit, so a run doesn't fail only after every reply.
+func (c *Conversation) fits(strategy TurnStrategy, turns int) error {
+	if turns > c.maxTurns {
+		return fmt.Errorf("%w: %s takes %d turns, one per reply, but the limit is %d", ErrMaxTurns, strategy.Name(), turns, c.maxTurns)
+	}
+	return nil
+}
+
+// speakers looks up the named agents, or returns every worker if there are
+// no names.
+func (c *Conversation) speakers(names []string) ([]*Agent, error) {
+	if len(names) == 0 {
+		var workers []*Agent
+		for _, a := range c.order {
+			if a != c.orchestrator {
+				workers = append(workers, a)
+			}
+		}
+		return workers, nil
+	}
+	agents := make([]*Agent, len(names))
+	for i, name := range names {
+		if agents[i] = c.agents[name]; agents[i] == nil {
+			return nil, fmt.Errorf("agent '%s' not found. Available agents: %s", name, c.listAgentNames())
+		}
+	}
+	return agents, nil
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// replies returns a client that answers with each text in turn.
+func replies(texts ...string) *mockClient {
+	m := &mockClient{}
+	for _, text := range texts {
+		m.responses = append(m.responses, textCompletion(text))
+	}
+	return m
+}
+
+func TestRoundRobin(t *testing.T) {
+	teacher := replies("lesson", "better lesson")
+	professor := replies("needs examples", "good")
+	conv, err := NewConversation(t.Context(),
+		NewAgent(nil, WithName("Coordinator"), WithRole(RoleOrchestrator)),
+		NewAgent(teacher, WithName("Teacher"), WithSystemMessage("You teach.")),
+		NewAgent(professor, WithName("Professor")),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	conv.SetStrategy(&RoundRobin{Rounds: 2})
+
+	var speakers []string
+	conv.Events().Subscribe(func(ev Event) {
+		if ev.Type == EventTurnStarted {
+			speakers = append(speakers, ev.Agent)
+		}
+	})
+	result, err := conv.Run("write a lesson")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "good" {
+		t.Errorf("Expected the last reply, got %q", result)
+	}
+	if got := strings.Join(speakers, ","); got != "Teacher,Professor,Teacher,Professor" {
+		t.Errorf("Unexpected turns %s", got)
+	}
+
+	// The teacher's second turn starts from its system message and the board.
+	msgs := teacher.calls[1].Messages
+	if len(msgs) != 4 || msgs[0].OfSystem == nil || msgs[2].OfAssistant == nil {
+		t.Fatalf("Unexpected history %+v", msgs)
+	}
+	for i, want := range []string{"Objective: write a lesson", "", "Professor: needs examples"} {
+		if msg := msgs[i+1]; msg.OfUser != nil && msg.OfUser.Content.OfString.Value != want {
+			t.Errorf("Message %d: expected %q, got %q", i+1, want, msg.OfUser.Content.OfString.Value)
+		}
+	}
+	if posts := conv.Board().Posts(); len(posts) != 5 || posts[4].From != "Professor" {
+		t.Errorf("Unexpected board %+v", posts)
+	}
+}
+
+func TestStrategy_FitsTurnLimit(t *testing.T) {
+	for _, tt := range []struct {
+		strategy TurnStrategy
+		turns    int
+	}{
+		{&RoundRobin{Rounds: 2}, 4},
+		{&Pipeline{Agents: []string{"Writer", "Editor", "Writer"}}, 3},
+		// Three answers each from two agents, and the judge's decision.
+		{&Debate{Judge: "Lead"}, 7},
+	} {
+		writer, editor := replies(), replies()
+		conv, _ := NewConversation(t.Context(),
+			NewAgent(replies(), WithName("Lead"), WithRole(RoleOrchestrator)),
+			NewAgent(writer, WithName("Writer")),
+			NewAgent(editor, WithName("Editor")),
+		)
+		conv.SetStrategy(tt.strategy)
+		conv.SetMaxTurns(tt.turns - 1)
+
+		_, err := conv.Run("write")
+		if !errors.Is(err, ErrMaxTurns) || !strings.Contains(err.Error(), fmt.Sprintf("takes %d turns", tt.turns)) {
+			t.Errorf("%s: expected the turn limit to be too low, got %v", tt.strategy.Name(), err)
+		}
+		if len(writer.calls)+len(editor.calls) != 0 {
+			t.Errorf("%s: expected no one to speak", tt.strategy.Name())
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	writer := replies("draft")
+	editor := replies("edited")
+	conv, _ := NewConversation(t.Context(),
+		NewAgent(nil, WithName("Lead"), WithRole(RoleOrchestrator)),
+		NewAgent(editor, WithName("Editor")),
+		NewAgent(writer, WithName("Writer")),
+	)
+
+	conv.SetStrategy(&Pipeline{Agents: []string{"Writer", "Nobody"}})
+	if _, err := conv.Run("write"); err == nil || !strings.Contains(err.Error(), "Nobody") {
+		t.Errorf("Expected an error for an unknown agent, got %v", err)
+	}
+
+	conv.SetStrategy(&Pipeline{Agents: []string{"Writer", "Editor"}})
+	result, err := conv.Run("write")
+	if err != nil || result != "edited" {
+		t.Fatalf("Expected the editor's reply, got %q, %v", result, err)
+	}
+	if last := editor.calls[0].Messages; last[len(last)-1].OfUser.Content.OfString.Value != "Writer: draft" {
+		t.Errorf("Expected the editor to see the draft, got %+v", last)
+	}
+}
+
+func TestGroupChat(t *testing.T) {
+	// The first answer names no one, so the agents take turns until the
+	// orchestrator picks one or finishes.
+	lead := replies("Hmm.", "**Critic**", "FINISH")
+	author := replies("a poem")
+	critic := replies("a critique")
+	conv, _ := NewConversation(t.Context(),
+		NewAgent(lead, WithName("Lead"), WithRole(RoleOrchestrator)),
+		NewAgent(author, WithName("Author")),
+		NewAgent(critic, WithName("Critic")),
+	)
+	conv.SetStrategy(&GroupChat{})
+
+	result, err := conv.Run("write a poem")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "a critique" {
+		t.Errorf("Expected the last reply, got %q", result)
+	}
+
+	// The choice is asked for at the end of each request and not kept.
+	choice := lead.calls[2].Messages
+	if note := choice[len(choice)-1]; note.OfUser == nil || !strings.Contains(note.OfUser.Content.OfString.Value, "Author, Critic") {
+		t.Errorf("Expected the question last, got %+v", note)
+	}
+	if len(choice) != 4 {
+		t.Errorf("Expected the board and the question, got %d messages", len(choice))
+	}
+	if len(conv.orchestrator.Messages) != 0 {
+		t.Errorf("Expected the orchestrator's history untouched, got %+v", conv.orchestrator.Messages)
+	}
+}
+
+func TestOrchestrated_Board(t *testing.T) {
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		toolCallCompletion("call_1", "DelegateTask", `{"agent_name": "Worker", "instructions": "write"}`),
+		toolCallCompletion("call_2", "Finish", `{"result": "essay written"}`),
+	}}
+	worker := NewAgent(replies("an essay"), WithName("Worker"))
+	conv, _ := NewConversation(t.Context(), NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator)), worker)
+
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	board, err := worker.CallFunction("ReadBoard", `{"last": 3}`)
+	if err != nil {
+		t.Fatalf("ReadBoard failed: %v", err)
+	}
+	want := "Orchestrator → Worker: write\n\nWorker → Orchestrator: an essay\n\nOrchestrator: essay written"
+	if board != want {
+		t.Errorf("Expected the board\n%s\ngot\n%s", want, board)
+	}
+}
@@ -14,10 +14,8 @@ type SummarizeArgs struct {
 // Summarize creates a temporary agent to summarize the given text.
 // It requires an OpenAI client. Since FunctionTool functions need to match a specific signature,
 // we'll return a closure that captures the client.
-func NewSummarizeTool(client client.Client) func(SummarizeArgs) (string, error) {
-	return func(args SummarizeArgs) (string, error) {
-		ctx := context.Background()
-
+func NewSummarizeTool(client client.Client) func(context.Context, SummarizeArgs) (string, error) {
+	return func(ctx context.Context, args SummarizeArgs) (string, error) {
 		// Create a temporary agent for summarization
 		summarizer := NewAgent(client,
 			WithName("Summarizer"),
@@ -4,13 +4,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// Ensure workspace exists
-const workspaceDir = "workspace"
+// WorkspaceDir is the directory each conversation's workspace is made in, and
+// the one the file tools use outside a conversation.
+const WorkspaceDir = "workspace"
 
-func ensureWorkspace() error {
-	return os.MkdirAll(workspaceDir, 0755)
+// Workspace is the directory the file tools operate in. Filenames are
+// resolved relative to it and may not escape it.
+type Workspace struct {
+	Dir string
+}
+
+func (w Workspace) ensure() error {
+	return os.MkdirAll(w.Dir, 0755)
+}
+
+// path resolves filename inside the workspace.
+func (w Workspace) path(filename string) (string, error) {
+	path := filepath.Join(w.Dir, filename)
+	rel, err := filepath.Rel(w.Dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside the workspace", filename)
+	}
+	return path, nil
 }
 
 type WriteArgs struct {
@@ -22,12 +40,18 @@ type ReadArgs struct {
 	Filename string `json:"filename" description:"The name of the file to read"`
 }
 
-func WriteToFile(args WriteArgs) (string, error) {
-	if err := ensureWorkspace(); err != nil {
+func (w Workspace) WriteToFile(args WriteArgs) (string, error) {
+	if err := w.ensure(); err != nil {
 		return "", fmt.Errorf("failed to create workspace: %w", err)
 	}
 
-	path := filepath.Join(workspaceDir, args.Filename)
+	path, err := w.path(args.Filename)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
 	if err := os.WriteFile(path, []byte(args.Content), 0644); err != nil {
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
@@ -35,12 +59,15 @@ func WriteToFile(args WriteArgs) (string, error) {
 	return fmt.Sprintf("Successfully wrote to %s", args.Filename), nil
 }
 
-func ReadFromFile(args ReadArgs) (string, error) {
-	if err := ensureWorkspace(); err != nil {
+func (w Workspace) ReadFromFile(args ReadArgs) (string, error) {
+	if err := w.ensure(); err != nil {
 		return "", fmt.Errorf("failed to create workspace: %w", err)
 	}
 
-	path := filepath.Join(workspaceDir, args.Filename)
+	path, err := w.path(args.Filename)
+	if err != nil {
+		return "", err
+	}
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -51,3 +78,13 @@ func ReadFromFile(args ReadArgs) (string, error) {
 
 	return string(data), nil
 }
+
+// WriteToFile writes to the shared default workspace.
+func WriteToFile(args WriteArgs) (string, error) {
+	return Workspace{Dir: WorkspaceDir}.WriteToFile(args)
+}
+
+// ReadFromFile reads from the shared default workspace.
+func ReadFromFile(args ReadArgs) (string, error) {
+	return Workspace{Dir: WorkspaceDir}.ReadFromFile(args)
+}
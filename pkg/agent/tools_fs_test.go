@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace_ReadWrite(t *testing.T) {
+	ws := Workspace{Dir: filepath.Join(t.TempDir(), "run")}
+
+	if _, err := ws.WriteToFile(WriteArgs{Filename: "notes/a.txt", Content: "hello"}); err != nil {
+		t.Fatalf("WriteToFile failed: %v", err)
+	}
+
+	content, err := ws.ReadFromFile(ReadArgs{Filename: "notes/a.txt"})
+	if err != nil {
+		t.Fatalf("ReadFromFile failed: %v", err)
+	}
+	if content != "hello" {
+		t.Errorf("Expected 'hello', got '%s'", content)
+	}
+}
+
+func TestWorkspace_RejectsEscape(t *testing.T) {
+	ws := Workspace{Dir: filepath.Join(t.TempDir(), "run")}
+
+	if _, err := ws.WriteToFile(WriteArgs{Filename: "../escape.txt", Content: "x"}); err == nil {
+		t.Error("Expected error for path outside the workspace")
+	}
+	if _, err := ws.ReadFromFile(ReadArgs{Filename: "../../etc/passwd"}); err == nil {
+		t.Error("Expected error for path outside the workspace")
+	}
+}
+
+func TestConversation_Workspace(t *testing.T) {
+	orch := NewAgent(nil, WithName("Orchestrator"), WithRole(RoleOrchestrator))
+	worker := NewAgent(nil, WithName("Worker"))
+	conv, err := NewConversation(t.Context(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if conv.Workspace() != filepath.Join(WorkspaceDir, conv.ID()) {
+		t.Errorf("Expected per-run workspace, got %s", conv.Workspace())
+	}
+
+	dir := t.TempDir()
+	conv.SetWorkspace(dir)
+	if _, err := orch.CallFunction("WriteToFile", `{"filename": "out.txt", "content": "data"}`); err != nil {
+		t.Fatalf("WriteToFile tool failed: %v", err)
+	}
+	if content, err := (Workspace{Dir: dir}).ReadFromFile(ReadArgs{Filename: "out.txt"}); err != nil || content != "data" {
+		t.Errorf("Expected file in the new workspace, got %q, %v", content, err)
+	}
+}
+
+func TestBuiltinTools(t *testing.T) {
+	names := func(role Role) []string {
+		var out []string
+		for _, tool := range BuiltinTools(role) {
+			out = append(out, tool.Name)
+		}
+		return out
+	}
+
+	worker := names(RoleAgent)
+	if len(worker) != 4 || worker[0] != "WriteToFile" {
+		t.Errorf("Unexpected worker tools: %v", worker)
+	}
+	orchestrator := names(RoleOrchestrator)
+	if len(orchestrator) != 10 || orchestrator[len(orchestrator)-1] != "Finish" {
+		t.Errorf("Unexpected orchestrator tools: %v", orchestrator)
+	}
+}
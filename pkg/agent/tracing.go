@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"github.com/openai/openai-go/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the spans of agents and conversations. It uses the global
+// tracer provider, so nothing is recorded unless the program installs one.
+var tracer = otel.Tracer("github.com/standrze/chorus/pkg/agent")
+
+// Attributes chorus records alongside the GenAI semantic conventions.
+const (
+	turnKey        = attribute.Key("chorus.turn")
+	turnsKey       = attribute.Key("chorus.turns")
+	strategyKey    = attribute.Key("chorus.strategy")
+	totalTokensKey = attribute.Key("chorus.usage.total_tokens")
+)
+
+// generateAttributes describes a request to the model.
+func generateAttributes(a *Agent, params *openai.ChatCompletionNewParams) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameChat,
+		semconv.GenAIProviderNameOpenAI,
+		semconv.GenAIAgentName(a.Name),
+		semconv.GenAIRequestModel(params.Model),
+	}
+	if params.Temperature.Valid() {
+		attrs = append(attrs, semconv.GenAIRequestTemperature(params.Temperature.Value))
+	}
+	if params.TopP.Valid() {
+		attrs = append(attrs, semconv.GenAIRequestTopP(params.TopP.Value))
+	}
+	if params.MaxCompletionTokens.Valid() {
+		attrs = append(attrs, semconv.GenAIRequestMaxTokens(int(params.MaxCompletionTokens.Value)))
+	}
+	if params.Seed.Valid() {
+		attrs = append(attrs, semconv.GenAIRequestSeed(int(params.Seed.Value)))
+	}
+	return attrs
+}
+
+// completionAttributes describes the model's response.
+func completionAttributes(completion *openai.ChatCompletion) []attribute.KeyValue {
+	reasons := make([]string, 0, len(completion.Choices))
+	for _, choice := range completion.Choices {
+		reasons = append(reasons, choice.FinishReason)
+	}
+	return []attribute.KeyValue{
+		semconv.GenAIResponseID(completion.ID),
+		semconv.GenAIResponseModel(completion.Model),
+		semconv.GenAIResponseFinishReasons(reasons...),
+		semconv.GenAIUsageInputTokens(int(completion.Usage.PromptTokens)),
+		semconv.GenAIUsageOutputTokens(int(completion.Usage.CompletionTokens)),
+	}
+}
+
+// EndSpan records err, if there is one, and ends span. Spans traced around
+// the agents, such as calls to MCP tools, end with it so errors read the same.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(semconv.ErrorType(err))
+	}
+	span.End()
+}
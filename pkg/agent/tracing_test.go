@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestConversation_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	delegate := toolCallCompletion("call_1", "DelegateTask", `{"agent_name": "Worker", "instructions": "write"}`)
+	delegate.Model = "orch-model"
+	delegate.Usage = openai.CompletionUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}
+	orchClient := &mockClient{responses: []*openai.ChatCompletion{
+		delegate,
+		toolCallCompletion("call_2", "Finish", `{"result": "essay written"}`),
+	}}
+	workerClient := &mockClient{responses: []*openai.ChatCompletion{textCompletion("an essay")}}
+
+	orch := NewAgent(orchClient, WithName("Orchestrator"), WithRole(RoleOrchestrator), WithModel("orch-model"))
+	worker := NewAgent(workerClient, WithName("Worker"), WithModel("worker-model"))
+	conv, err := NewConversation(context.Background(), orch, worker)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		if _, seen := spans[span.Name()]; !seen {
+			spans[span.Name()] = span
+		}
+	}
+	parentOf := func(child, parent string) {
+		t.Helper()
+		c, p := spans[child], spans[parent]
+		if c == nil || p == nil {
+			t.Fatalf("Expected spans %q and %q, got %v", child, parent, spans)
+		}
+		if c.Parent().SpanID() != p.SpanContext().SpanID() {
+			t.Errorf("Expected %q to nest under %q", child, parent)
+		}
+	}
+
+	// The worker's generation nests under the delegation, inside the
+	// orchestrator's tool call, inside the first turn of the run.
+	parentOf("chorus.turn", "chorus.run")
+	parentOf("chat orch-model", "chorus.turn")
+	parentOf("execute_tool DelegateTask", "chorus.turn")
+	parentOf("invoke_agent Worker", "execute_tool DelegateTask")
+	parentOf("chat worker-model", "invoke_agent Worker")
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range spans["chat orch-model"].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["gen_ai.request.model"].AsString() != "orch-model" ||
+		attrs["gen_ai.usage.input_tokens"].AsInt64() != 10 ||
+		attrs["gen_ai.usage.output_tokens"].AsInt64() != 5 ||
+		attrs["gen_ai.response.finish_reasons"].AsStringSlice()[0] != "tool_calls" {
+		t.Errorf("Unexpected generation attributes %v", attrs)
+	}
+}
@@ -2,26 +2,89 @@ package client
 
 import (
 	"context"
+	"net/http"
+	"time"
 
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/standrze/chorus/pkg/log"
 )
 
+var logger = log.Component(log.ComponentClient)
+
 type Client interface {
 	ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error)
 }
 
+// StreamingClient is implemented by clients that can stream a completion.
+// onChunk is called for every chunk as it arrives; the accumulated
+// completion is returned once the stream ends.
+type StreamingClient interface {
+	Client
+	ChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(openai.ChatCompletionChunk)) (*openai.ChatCompletion, error)
+}
+
+// RetryCountHeader is set on every attempt at a request; it is above zero
+// when the client retries after a failure.
+const RetryCountHeader = "X-Stainless-Retry-Count"
+
 type OpenAIClient struct {
 	client *openai.Client
 }
 
-func NewClient(baseURL string) *OpenAIClient {
-	client := openai.NewClient(option.WithBaseURL(baseURL))
+// NewClient creates a client for the OpenAI-compatible API at baseURL. An
+// empty apiKey falls back to the OPENAI_API_KEY environment variable. Extra
+// options, such as middleware, apply to every request.
+func NewClient(baseURL, apiKey string, extra ...option.RequestOption) *OpenAIClient {
+	opts := []option.RequestOption{option.WithBaseURL(baseURL), option.WithMiddleware(logRequest)}
+	if apiKey != "" {
+		opts = append(opts, option.WithAPIKey(apiKey))
+	}
+	opts = append(opts, extra...)
+	client := openai.NewClient(opts...)
 	return &OpenAIClient{
 		client: &client,
 	}
 }
 
+// logRequest logs every attempt at a request, warning about retries.
+func logRequest(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	ctx := req.Context()
+	if n := req.Header.Get(RetryCountHeader); n != "" && n != "0" {
+		logger.WarnContext(ctx, "Retrying request", "method", req.Method, "path", req.URL.Path, "retry", n)
+	}
+
+	start := time.Now()
+	resp, err := next(req)
+	if err != nil {
+		logger.DebugContext(ctx, "Request failed", "method", req.Method, "path", req.URL.Path, "duration", time.Since(start), "error", err)
+		return resp, err
+	}
+	logger.DebugContext(ctx, "Request", "method", req.Method, "path", req.URL.Path, "status", resp.StatusCode, "duration", time.Since(start))
+	return resp, nil
+}
+
 func (c *OpenAIClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
 	return c.client.Chat.Completions.New(ctx, params)
 }
+
+func (c *OpenAIClient) ChatCompletionStream(ctx context.Context, params openai.ChatCompletionNewParams, onChunk func(openai.ChatCompletionChunk)) (*openai.ChatCompletion, error) {
+	params.StreamOptions.IncludeUsage = openai.Bool(true)
+
+	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return &acc.ChatCompletion, nil
+}
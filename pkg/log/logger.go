@@ -1,18 +1,220 @@
 package log
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
+
+	"github.com/standrze/chorus/pkg/redact"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Components whose level can be set on its own, see Options.Components.
+const (
+	ComponentClient       = "client"       // requests to the model API
+	ComponentMCP          = "mcp"          // MCP servers, roots and sampling
+	ComponentTools        = "tools"        // tool calls and toolsets
+	ComponentConversation = "conversation" // agents generating and conversations running
+)
+
+// Components lists every component.
+var Components = []string{ComponentClient, ComponentMCP, ComponentTools, ComponentConversation}
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-var defaultLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+// Options configure logging. The zero value writes text at info level to
+// stderr, keeping stdout free for agent output.
+type Options struct {
+	// Format is text or json.
+	Format string
+	Level  slog.Level
+	// Components sets the level of individual components, overriding Level.
+	Components map[string]slog.Level
+	// Output receives the logs. Nil means stderr.
+	Output io.Writer
+	// Redactor masks secrets in messages and attribute values. Nil logs them as they are.
+	Redactor *redact.Redactor
+}
+
+var (
+	mu      sync.RWMutex
+	options Options
+	base    slog.Handler // formats and writes; levels are checked by handler
+
+	defaultLogger = slog.New(&handler{})
+)
+
+func init() {
+	base, _ = newHandler(Options{})
+}
+
+// Configure replaces the logging configuration. Loggers obtained earlier,
+// including component loggers, follow the new configuration. The standard
+// log and slog packages' default loggers are routed here too.
+func Configure(opts Options) error {
+	h, err := newHandler(opts)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	options, base = opts, h
+	mu.Unlock()
+
+	slog.SetDefault(defaultLogger)
+	return nil
+}
+
+func newHandler(opts Options) (slog.Handler, error) {
+	for name := range opts.Components {
+		if !slices.Contains(Components, name) {
+			return nil, fmt.Errorf("unknown log component %q", name)
+		}
+	}
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// Everything reaches the handler; handler.Enabled applies the levels.
+	handlerOpts := &slog.HandlerOptions{Level: slog.Level(-100)}
+	if opts.Redactor != nil {
+		handlerOpts.ReplaceAttr = redactAttr(opts.Redactor)
+	}
+	switch opts.Format {
+	case "", FormatText:
+		return slog.NewTextHandler(output, handlerOpts), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(output, handlerOpts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+}
+
+// redactAttr masks the secrets in the message and attribute values, and the
+// whole value of attributes whose key is secret.
+func redactAttr(r *redact.Redactor) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.SourceKey) {
+			return a
+		}
+		if r.SecretKey(a.Key) {
+			return slog.String(a.Key, redact.Mask)
+		}
 
+		var s string
+		switch v := a.Value.Resolve(); v.Kind() {
+		case slog.KindString:
+			s = v.String()
+		case slog.KindAny:
+			switch x := v.Any().(type) {
+			case error:
+				s = x.Error()
+			case json.RawMessage:
+				s = string(x)
+			case []byte:
+				s = string(x)
+			default:
+				s = fmt.Sprint(x)
+			}
+		default:
+			return a
+		}
+		// Values without secrets keep their kind, so JSON logs still nest them.
+		if redacted := r.String(s); redacted != s || a.Value.Kind() == slog.KindString {
+			return slog.String(a.Key, redacted)
+		}
+		return a
+	}
+}
+
+// RotatingFile opens path for appending logs, rotating it once it reaches
+// maxSizeMB and keeping at most maxBackups old files for maxAgeDays. Zero
+// limits keep the defaults: 100 MB, every backup, forever.
+func RotatingFile(path string, maxSizeMB, maxBackups, maxAgeDays int) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	}
+}
+
+// SetDebug switches between the debug and info levels.
 func SetDebug(debug bool) {
-	level := slog.LevelInfo
+	mu.RLock()
+	opts := options
+	mu.RUnlock()
+
+	opts.Level = slog.LevelInfo
 	if debug {
-		level = slog.LevelDebug
+		opts.Level = slog.LevelDebug
+	}
+	Configure(opts)
+}
+
+// SetOutput redirects log output, e.g. to keep stdout free for a protocol stream.
+func SetOutput(w io.Writer) {
+	mu.RLock()
+	opts := options
+	mu.RUnlock()
+
+	opts.Output = w
+	Configure(opts)
+}
+
+// Output returns where logs are written.
+func Output() io.Writer {
+	mu.RLock()
+	defer mu.RUnlock()
+	if options.Output == nil {
+		return os.Stderr
 	}
-	defaultLogger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+	return options.Output
+}
+
+type attrsKey struct{}
+
+// WithAttrs returns a context whose logs carry the given attributes, as
+// key-value pairs or slog.Attrs, e.g. the conversation or agent a log line is
+// about. They replace attributes of the same key already on ctx.
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	var r slog.Record
+	r.Add(args...)
+
+	attrs := slices.Clone(contextAttrs(ctx))
+	r.Attrs(func(a slog.Attr) bool {
+		if i := slices.IndexFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key }); i >= 0 {
+			attrs[i] = a
+		} else {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Component returns the logger of a component, which logs at the
+// component's level and tags its records with it.
+func Component(name string) *slog.Logger {
+	return slog.New(&handler{component: name})
 }
 
 func Debug(msg string, args ...any) {
@@ -23,10 +225,81 @@ func Info(msg string, args ...any) {
 	defaultLogger.Info(msg, args...)
 }
 
+func Warn(msg string, args ...any) {
+	defaultLogger.Warn(msg, args...)
+}
+
 func Error(msg string, args ...any) {
 	defaultLogger.Error(msg, args...)
 }
 
+func DebugContext(ctx context.Context, msg string, args ...any) {
+	defaultLogger.DebugContext(ctx, msg, args...)
+}
+
+func InfoContext(ctx context.Context, msg string, args ...any) {
+	defaultLogger.InfoContext(ctx, msg, args...)
+}
+
+func WarnContext(ctx context.Context, msg string, args ...any) {
+	defaultLogger.WarnContext(ctx, msg, args...)
+}
+
+func ErrorContext(ctx context.Context, msg string, args ...any) {
+	defaultLogger.ErrorContext(ctx, msg, args...)
+}
+
 func Get() *slog.Logger {
 	return defaultLogger
 }
+
+// handler applies the configured levels and adds the component and the
+// context's attributes before passing records to the configured handler.
+// It looks the configuration up on every record, so it follows Configure.
+type handler struct {
+	component string
+	// with replays WithAttrs and WithGroup calls on the configured handler.
+	with []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	threshold, ok := options.Components[h.component]
+	if !ok {
+		threshold = options.Level
+	}
+	return level >= threshold
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	mu.RLock()
+	next := base
+	mu.RUnlock()
+
+	var attrs []slog.Attr
+	if h.component != "" {
+		attrs = append(attrs, slog.String("component", h.component))
+	}
+	attrs = append(attrs, contextAttrs(ctx)...)
+	if len(attrs) > 0 {
+		next = next.WithAttrs(attrs)
+	}
+	for _, with := range h.with {
+		next = with(next)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.and(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.and(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) and(with func(slog.Handler) slog.Handler) *handler {
+	return &handler{component: h.component, with: append(slices.Clip(h.with), with)}
+}
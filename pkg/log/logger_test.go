@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+// configure directs the logs to a buffer for the rest of the test.
+func configure(t *testing.T, opts Options) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	opts.Output = &buf
+	if err := Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Configure(Options{}) })
+	return &buf
+}
+
+func TestComponentLevels(t *testing.T) {
+	buf := configure(t, Options{
+		Level:      slog.LevelWarn,
+		Components: map[string]slog.Level{ComponentTools: slog.LevelDebug},
+	})
+
+	Component(ComponentTools).Debug("tool called")
+	Component(ComponentClient).Info("request sent")
+	Info("started")
+	Warn("careful")
+
+	out := buf.String()
+	if !strings.Contains(out, "component=tools") || !strings.Contains(out, "tool called") {
+		t.Errorf("Expected the tools debug line, got:\n%s", out)
+	}
+	if strings.Contains(out, "request sent") || strings.Contains(out, "started") {
+		t.Errorf("Expected info lines below warn to be dropped, got:\n%s", out)
+	}
+	if !strings.Contains(out, "careful") {
+		t.Errorf("Expected the warning, got:\n%s", out)
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	buf := configure(t, Options{Format: FormatJSON})
+
+	ctx := WithAttrs(context.Background(), "conversation", "c1", "turn", 1)
+	ctx = WithAttrs(ctx, "turn", 2, "agent", "Teacher")
+	Component(ComponentConversation).With("model", "m").InfoContext(ctx, "generating")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":          "generating",
+		"component":    "conversation",
+		"conversation": "c1",
+		"turn":         float64(2),
+		"agent":        "Teacher",
+		"model":        "m",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, got[k])
+		}
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	buf := configure(t, Options{Format: FormatJSON, Redactor: redact.Default()})
+
+	Info("sending to bob@example.com",
+		"args", json.RawMessage(`{"api_key":"hunter22"}`),
+		"password", "hunter22",
+		"error", errors.New("rejected sk-abcdefghijklmnop1234"),
+		"tokens", 12,
+	)
+
+	out := buf.String()
+	for _, leak := range []string{"bob@example.com", "hunter22", "sk-abcdefghijklmnop1234"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("Expected %q to be redacted, got %s", leak, out)
+		}
+	}
+	if !strings.Contains(out, `"tokens":12`) {
+		t.Errorf("Expected other attributes to be kept, got %s", out)
+	}
+}
+
+func TestConfigureRejectsUnknown(t *testing.T) {
+	if err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if err := Configure(Options{Components: map[string]slog.Level{"db": slog.LevelDebug}}); err == nil {
+		t.Error("Expected an error for an unknown component")
+	}
+}
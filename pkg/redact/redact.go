@@ -0,0 +1,184 @@
+// Package redact masks secrets and personal data before they leave the
+// process in logs, traces or transcripts.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Mask replaces whatever is redacted.
+const Mask = "[REDACTED]"
+
+// Rule redacts every match of Pattern.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// DefaultRules catch common credentials and email addresses.
+var DefaultRules = []Rule{
+	{Name: "openai_key", Pattern: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`)},
+	{Name: "github_token", Pattern: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`)},
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{Name: "bearer", Pattern: regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{8,}`)},
+	{Name: "email", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+}
+
+// DefaultKeywords name keys whose values are always secret.
+var DefaultKeywords = []string{"api_key", "apikey", "password", "passwd", "secret", "token", "authorization"}
+
+// minSecretLen keeps short literal secrets, such as placeholder API keys,
+// from masking ordinary text.
+const minSecretLen = 8
+
+// Options configure a Redactor.
+type Options struct {
+	Rules []Rule
+	// Keywords name secret keys. A key matches a keyword when it equals it or
+	// ends with it after a "_" or ".", ignoring case and with "-" read as "_":
+	// "token" matches "access_token" and "X-Token" but not "max_tokens".
+	Keywords []string
+	// Secrets are literal values, such as configured API keys, masked wherever
+	// they appear. Values shorter than 8 characters are ignored.
+	Secrets []string
+}
+
+// A Redactor masks the matches of its rules, its secrets and the values of
+// keys named by its keywords. A nil *Redactor redacts nothing.
+type Redactor struct {
+	rules    []*regexp.Regexp
+	keywords []string
+	// pairs finds key=value and "key": "value" pairs, JSON escaped or not,
+	// whose key may be secret.
+	pairs *regexp.Regexp
+}
+
+// New returns a Redactor with opts.
+func New(opts Options) *Redactor {
+	r := &Redactor{}
+	for _, rule := range opts.Rules {
+		r.rules = append(r.rules, rule.Pattern)
+	}
+	for _, secret := range opts.Secrets {
+		if len(secret) >= minSecretLen {
+			r.rules = append(r.rules, regexp.MustCompile(regexp.QuoteMeta(secret)))
+		}
+	}
+
+	var alts []string
+	for _, kw := range opts.Keywords {
+		kw = normalizeKey(kw)
+		if kw == "" || slices.Contains(r.keywords, kw) {
+			continue
+		}
+		r.keywords = append(r.keywords, kw)
+		alts = append(alts, strings.ReplaceAll(regexp.QuoteMeta(kw), "_", "[_-]"))
+	}
+	if len(alts) > 0 {
+		r.pairs = regexp.MustCompile(`(?i)([A-Za-z0-9_.-]*(?:` + strings.Join(alts, "|") + `))(\\?"?\s*[:=]\s*\\?"?)([^\s"'\\,;&}\[\]]+)`)
+	}
+	return r
+}
+
+// Default returns a Redactor with the default rules and keywords, masking
+// secrets too.
+func Default(secrets ...string) *Redactor {
+	return New(Options{Rules: DefaultRules, Keywords: DefaultKeywords, Secrets: secrets})
+}
+
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "-", "_")
+}
+
+// SecretKey reports whether the value of key is secret.
+func (r *Redactor) SecretKey(key string) bool {
+	if r == nil {
+		return false
+	}
+	key = normalizeKey(key)
+	for _, kw := range r.keywords {
+		if key == kw || strings.HasSuffix(key, "_"+kw) || strings.HasSuffix(key, "."+kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the secrets in s.
+func (r *Redactor) String(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, rule := range r.rules {
+		s = rule.ReplaceAllLiteralString(s, Mask)
+	}
+	if r.pairs != nil {
+		s = r.pairs.ReplaceAllStringFunc(s, func(pair string) string {
+			m := r.pairs.FindStringSubmatch(pair)
+			if !r.SecretKey(m[1]) {
+				return pair
+			}
+			return m[1] + m[2] + Mask
+		})
+	}
+	return s
+}
+
+// Value masks all of s if key is secret, and the secrets in s otherwise.
+func (r *Redactor) Value(key, s string) string {
+	if r.SecretKey(key) {
+		return Mask
+	}
+	return r.String(s)
+}
+
+// JSON masks the secrets in every string of a JSON document, and the values
+// of secret object keys.
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	if r == nil {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(r.walk("", v))
+}
+
+// Marshal encodes v as JSON with its secrets masked.
+func (r *Redactor) Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.JSON(data)
+}
+
+func (r *Redactor) walk(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			v[k] = r.walk(k, item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = r.walk(key, item)
+		}
+		return v
+	case string:
+		return r.Value(key, v)
+	default:
+		if v != nil && r.SecretKey(key) {
+			return Mask
+		}
+		return v
+	}
+}
@@ -0,0 +1,68 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	r := Default("my-configured-key")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"key sk-abcdefghijklmnop1234 here", "key [REDACTED] here"},
+		{"mail bob@example.com now", "mail [REDACTED] now"},
+		{"Authorization: Bearer abc.def.ghi123", "Authorization: [REDACTED]"},
+		{"using my-configured-key", "using [REDACTED]"},
+		{`{"api_key": "hunter22", "model": "m"}`, `{"api_key": "[REDACTED]", "model": "m"}`},
+		{`{\"password\":\"hunter22\"}`, `{\"password\":\"[REDACTED]\"}`},
+		{"access_token=abc123 max_tokens=5 tokens: 7", "access_token=[REDACTED] max_tokens=5 tokens: 7"},
+		{"nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSecretKey(t *testing.T) {
+	r := Default()
+	for key, want := range map[string]bool{
+		"api_key":           true,
+		"X-Api-Key":         true,
+		"github.token":      true,
+		"client_secret":     true,
+		"max_tokens":        false,
+		"token_budget":      false,
+		"gen_ai.usage.type": false,
+	} {
+		if got := r.SecretKey(key); got != want {
+			t.Errorf("SecretKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r := New(Options{Rules: DefaultRules, Keywords: []string{"pin"}})
+
+	got, err := r.JSON([]byte(`{"pin":1234,"messages":[{"content":"write to ann@example.org"}],"count":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"count":3,"messages":[{"content":"write to [REDACTED]"}],"pin":"[REDACTED]"}`
+	if string(got) != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestNil(t *testing.T) {
+	var r *Redactor
+	if got := r.String("bob@example.com"); got != "bob@example.com" {
+		t.Errorf("Expected a nil Redactor to leave text alone, got %q", got)
+	}
+	if got, _ := r.JSON([]byte(`{"api_key":"x"}`)); !strings.Contains(string(got), `"x"`) {
+		t.Errorf("Expected a nil Redactor to leave JSON alone, got %s", got)
+	}
+}
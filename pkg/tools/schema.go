@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
@@ -8,28 +9,53 @@ import (
 	"github.com/openai/openai-go/v3"
 )
 
-// GenerateSchema inspects a function's argument (which must be a struct)
-// and generates a JSON schema compatible with OpenAI's FunctionParameters.
-func GenerateSchema(f any) (openai.FunctionParameters, error) {
+var contextType = reflect.TypeFor[context.Context]()
+
+// ArgumentType returns the type of the argument a tool function takes.
+// Besides its argument a tool function may take a leading context.Context,
+// in which case takesContext is true and the caller's context is passed in.
+func ArgumentType(f any) (argType reflect.Type, takesContext bool, err error) {
 	t := reflect.TypeOf(f)
-	if t.Kind() != reflect.Func {
-		return nil, fmt.Errorf("input is not a function")
+	if t == nil || t.Kind() != reflect.Func {
+		return nil, false, fmt.Errorf("input is not a function")
 	}
 
-	if t.NumIn() != 1 {
-		return nil, fmt.Errorf("function must accept exactly one argument")
+	switch {
+	case t.NumIn() == 1:
+		return t.In(0), false, nil
+	case t.NumIn() == 2 && t.In(0) == contextType:
+		return t.In(1), true, nil
+	default:
+		return nil, false, fmt.Errorf("function must accept exactly one argument, optionally after a context.Context")
 	}
+}
 
-	argType := t.In(0)
+// GenerateSchema inspects a function's argument (which must be a struct)
+// and generates a JSON schema compatible with OpenAI's FunctionParameters.
+func GenerateSchema(f any) (openai.FunctionParameters, error) {
+	argType, _, err := ArgumentType(f)
+	if err != nil {
+		return nil, err
+	}
 	if argType.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("function argument must be a struct")
 	}
 
+	schema, err := structSchema(argType)
+	if err != nil {
+		return nil, err
+	}
+	return openai.FunctionParameters(schema), nil
+}
+
+// structSchema describes a struct as an object whose properties are its
+// exported fields. Fields tagged omitempty are optional.
+func structSchema(t reflect.Type) (map[string]any, error) {
 	properties := make(map[string]any)
 	required := []string{}
 
-	for i := 0; i < argType.NumField(); i++ {
-		field := argType.Field(i)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
 
 		// Skip unexported fields
 		if field.PkgPath != "" {
@@ -70,13 +96,11 @@ func GenerateSchema(f any) (openai.FunctionParameters, error) {
 		}
 	}
 
-	schema := map[string]any{
+	return map[string]any{
 		"type":       "object",
 		"properties": properties,
 		"required":   required,
-	}
-
-	return openai.FunctionParameters(schema), nil
+	}, nil
 }
 
 func getTypeSchema(t reflect.Type) (map[string]any, error) {
@@ -102,9 +126,7 @@ func getTypeSchema(t reflect.Type) (map[string]any, error) {
 			"items": elemSchema,
 		}, nil
 	case reflect.Struct:
-		// Recursive handling for nested structs could be added here
-		// For now, let's stick to primitives/slices as requested
-		return nil, fmt.Errorf("nested structs are not yet supported")
+		return structSchema(t)
 	default:
 		return nil, fmt.Errorf("unsupported type: %v", t.Kind())
 	}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 )
@@ -84,6 +85,34 @@ func TestGenerateSchema_Slice(t *testing.T) {
 	}
 }
 
+func TestGenerateSchema_NestedStruct(t *testing.T) {
+	type Step struct {
+		Description string   `json:"description"`
+		DependsOn   []string `json:"depends_on,omitempty"`
+	}
+	type PlanArgs struct {
+		Steps []Step `json:"steps"`
+	}
+	fn := func(args PlanArgs) {}
+
+	schema, err := GenerateSchema(fn)
+	if err != nil {
+		t.Fatalf("GenerateSchema failed: %v", err)
+	}
+
+	steps := schema["properties"].(map[string]any)["steps"].(map[string]any)
+	items := steps["items"].(map[string]any)
+	if items["type"] != "object" {
+		t.Fatalf("Expected object items, got %v", items)
+	}
+	if _, ok := items["properties"].(map[string]any)["depends_on"]; !ok {
+		t.Errorf("Expected the depends_on property, got %v", items)
+	}
+	if required := items["required"].([]string); len(required) != 1 || required[0] != "description" {
+		t.Errorf("Expected only description to be required, got %v", required)
+	}
+}
+
 func TestGenerateSchema_Invalid(t *testing.T) {
 	// Not a function
 	_, err := GenerateSchema("not a func")
@@ -102,4 +131,26 @@ func TestGenerateSchema_Invalid(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for non-struct arg")
 	}
+
+	// Two args, the first not a context
+	_, err = GenerateSchema(func(s string, args struct{}) {})
+	if err == nil {
+		t.Error("Expected error for two args without a context")
+	}
+}
+
+func TestGenerateSchema_Context(t *testing.T) {
+	type QueryArgs struct {
+		Query string `json:"query"`
+	}
+	fn := func(ctx context.Context, args QueryArgs) (string, error) { return "", nil }
+
+	schema, err := GenerateSchema(fn)
+	if err != nil {
+		t.Fatalf("GenerateSchema failed: %v", err)
+	}
+	props := schema["properties"].(map[string]any)
+	if _, ok := props["query"]; !ok {
+		t.Errorf("Expected the query property, got %v", props)
+	}
 }
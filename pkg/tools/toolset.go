@@ -0,0 +1,43 @@
+package tools
+
+import "sync"
+
+// Toolset is a named group of function tools whose contents can change at
+// runtime, e.g. when an MCP server announces a new tool list. Agents holding
+// a Toolset pick up changes before their next generation.
+type Toolset struct {
+	name string
+
+	mu      sync.RWMutex
+	tools   []FunctionTool
+	version uint64
+}
+
+func NewToolset(name string, tools ...FunctionTool) *Toolset {
+	return &Toolset{
+		name:  name,
+		tools: tools,
+	}
+}
+
+func (s *Toolset) Name() string {
+	return s.name
+}
+
+// Set replaces the tools in the set.
+func (s *Toolset) Set(tools ...FunctionTool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tools = tools
+	s.version++
+}
+
+// Snapshot returns the current tools together with a version that changes
+// every time Set is called.
+func (s *Toolset) Snapshot() ([]FunctionTool, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]FunctionTool(nil), s.tools...), s.version
+}
@@ -0,0 +1,195 @@
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+)
+
+// Formats lists the export formats.
+var Formats = []string{FormatMarkdown, FormatJSON, FormatHTML}
+
+// FormatFor guesses the format from a file name's extension, defaulting to
+// Markdown.
+func FormatFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".html", ".htm":
+		return FormatHTML
+	default:
+		return FormatMarkdown
+	}
+}
+
+// Export writes t to w in format.
+func Export(w io.Writer, t *Transcript, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return WriteMarkdown(w, t)
+	case FormatJSON:
+		return WriteJSON(w, t)
+	case FormatHTML:
+		return WriteHTML(w, t)
+	default:
+		return fmt.Errorf("unknown format %q, want %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// WriteJSON writes t in the stable JSON format that Read decodes.
+func WriteJSON(w io.Writer, t *Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// WriteMarkdown writes t as a Markdown document. Tool calls fold away in
+// <details> blocks, which most Markdown renderers support.
+func WriteMarkdown(w io.Writer, t *Transcript) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# Conversation %s\n\n", t.Conversation)
+	if len(t.Agents) > 0 {
+		b.WriteString("| Agent | Role | Model |\n|---|---|---|\n")
+		for _, p := range t.Agents {
+			fmt.Fprintf(b, "| %s | %s | %s |\n", cell(p.Name), cell(p.Role), cell(p.Model))
+		}
+		b.WriteString("\n")
+	}
+
+	for i, run := range t.Runs {
+		fmt.Fprintf(b, "## Run %d\n\n", i+1)
+		fmt.Fprintf(b, "- **Objective:** %s\n", inline(run.Objective))
+		if run.Strategy != "" {
+			fmt.Fprintf(b, "- **Strategy:** %s\n", run.Strategy)
+		}
+		fmt.Fprintf(b, "- **Started:** %s\n", run.Started.Format(time.RFC3339))
+		if !run.Ended.IsZero() {
+			fmt.Fprintf(b, "- **Took:** %s over %d turns\n", run.Ended.Sub(run.Started).Round(time.Millisecond), run.Turns)
+		}
+		switch {
+		case run.Error != "":
+			fmt.Fprintf(b, "- **Error:** %s\n", inline(run.Error))
+		case run.Result != "":
+			fmt.Fprintf(b, "- **Result:** %s\n", inline(run.Result))
+		}
+		b.WriteString("\n")
+		if len(run.Agreement) > 0 {
+			b.WriteString("| Agent | Answer | Agreed | Peers | Changes |\n|---|---|---|---|---|\n")
+			for _, a := range run.Agreement {
+				fmt.Fprintf(b, "| %s | %s | %s | %s | %d |\n", cell(a.Agent), cell(a.Answer), yesNo(a.Agreed), percent(a.Peers), a.Changes)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("## Transcript\n")
+	turn := -1
+	for _, e := range t.Entries {
+		if e.Kind == KindGeneration {
+			continue
+		}
+		if e.Turn != turn && e.Turn > 0 {
+			fmt.Fprintf(b, "\n### Turn %d\n", e.Turn)
+		}
+		turn = e.Turn
+		b.WriteString("\n")
+
+		stamp := e.Time.Format("15:04:05")
+		switch e.Kind {
+		case KindMessage:
+			fmt.Fprintf(b, "**%s → %s** · %s\n\n%s\n", e.From, e.To, stamp, e.Text)
+		case KindDelegation:
+			fmt.Fprintf(b, "**%s delegates to %s** · %s\n\n%s\n", e.From, e.To, stamp, quote(e.Text))
+		case KindPlan:
+			if e.Text != "" {
+				fmt.Fprintf(b, "**%s updated the plan** · %s\n\n%s\n\n", e.From, stamp, inline(e.Text))
+			} else {
+				fmt.Fprintf(b, "**%s made a plan** · %s\n\n", e.From, stamp)
+			}
+			if len(e.Plan) == 0 {
+				for i, step := range e.Steps {
+					fmt.Fprintf(b, "%d. %s\n", i+1, inline(step))
+				}
+			}
+			for _, step := range e.Plan {
+				fmt.Fprintf(b, "- `%s` **%s.** %s", step.Status, step.ID, inline(step.Description))
+				if about := step.About(); about != "" {
+					fmt.Fprintf(b, " (%s)", about)
+				}
+				if step.Result != "" {
+					fmt.Fprintf(b, " → %s", inline(step.Result))
+				}
+				b.WriteString("\n")
+			}
+		case KindToolCall:
+			summary := fmt.Sprintf("%s called <code>%s</code> · %s", e.From, e.Tool.Name, stamp)
+			if e.Tool.Error != "" {
+				summary += " · failed"
+			}
+			fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n", summary)
+			fmt.Fprintf(b, "Arguments:\n\n%s\n", fence(e.Tool.Arguments, "json"))
+			if e.Tool.Error != "" {
+				fmt.Fprintf(b, "\nError:\n\n%s\n", fence(e.Tool.Error, ""))
+			} else {
+				fmt.Fprintf(b, "\nResult:\n\n%s\n", fence(e.Tool.Result, ""))
+			}
+			b.WriteString("\n</details>\n")
+		}
+	}
+
+	if len(t.Usage) > 0 {
+		b.WriteString("\n## Token Usage\n\n| Agent | Model | Requests | Tokens |\n|---|---|---:|---:|\n")
+		for _, u := range t.Usage {
+			fmt.Fprintf(b, "| %s | %s | %d | %d |\n", cell(u.Agent), cell(u.Model), u.Generations, u.Tokens)
+		}
+		fmt.Fprintf(b, "| **Total** | | | **%d** |\n", t.TotalTokens())
+	}
+	return b.Flush()
+}
+
+// cell escapes text for a table cell.
+func cell(s string) string {
+	return strings.ReplaceAll(inline(s), "|", `\|`)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
+
+// inline folds text onto one line.
+func inline(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func quote(s string) string {
+	return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+}
+
+// fence wraps s in a code block whose fence is longer than any backtick run
+// in s.
+func fence(s, lang string) string {
+	ticks := "```"
+	for strings.Contains(s, ticks) {
+		ticks += "`"
+	}
+	return ticks + lang + "\n" + s + "\n" + ticks
+}
@@ -0,0 +1,120 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+)
+
+// WriteHTML writes t as a self-contained HTML page: styles are inline, there
+// are no scripts, and tool calls are collapsible.
+func WriteHTML(w io.Writer, t *Transcript) error {
+	return htmlTemplate.Execute(w, t)
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"clock": func(t time.Time) string { return t.Format("15:04:05") },
+	"stamp": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"took": func(run Run) string {
+		return run.Ended.Sub(run.Started).Round(time.Millisecond).String()
+	},
+	"add":     func(a, b int) int { return a + b },
+	"yesNo":   yesNo,
+	"percent": percent,
+	"pretty": func(s string) string {
+		var buf bytes.Buffer
+		if json.Indent(&buf, []byte(s), "", "  ") != nil {
+			return s
+		}
+		return buf.String()
+	},
+	// newTurn reports whether entry i starts a turn, skipping generations.
+	"newTurn": func(entries []Entry, i int) bool {
+		turn := entries[i].Turn
+		if turn == 0 {
+			return false
+		}
+		for j := i - 1; j >= 0; j-- {
+			if entries[j].Kind != KindGeneration {
+				return entries[j].Turn != turn
+			}
+		}
+		return true
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Conversation {{.Conversation}}</title>
+<style>
+body { font: 15px/1.5 system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+h1 { font-size: 1.5rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; }
+h3 { font-size: 1rem; color: #59636e; border-bottom: 1px solid #d1d9e0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d1d9e0; padding: .25rem .75rem; text-align: left; }
+td.n { text-align: right; }
+.entry { margin: .75rem 0; padding: .5rem .75rem; border-left: 3px solid #d1d9e0; }
+.entry.assistant { border-color: #0969da; }
+.entry.user { border-color: #1a7f37; }
+.entry.system { border-color: #8c959f; color: #59636e; }
+.entry.delegation { border-color: #8250df; }
+.entry.plan { border-color: #bf8700; }
+.entry.failed { border-color: #cf222e; }
+.who { font-weight: 600; }
+.time { color: #59636e; font-size: .85em; margin-left: .5rem; }
+.text { white-space: pre-wrap; }
+pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; white-space: pre-wrap; }
+summary { cursor: pointer; }
+.error { color: #cf222e; }
+.steps { list-style: none; padding-left: 0; }
+.status { font-size: .8em; padding: 0 .4em; border-radius: .6em; background: #eff1f3; }
+.status.in_progress { background: #ddf4ff; }
+.status.done { background: #dafbe1; }
+.status.failed { background: #ffebe9; }
+</style>
+</head>
+<body>
+<h1>Conversation {{.Conversation}}</h1>
+{{with .Agents}}<table>
+<tr><th>Agent</th><th>Role</th><th>Model</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Role}}</td><td>{{.Model}}</td></tr>
+{{end}}</table>{{end}}
+{{range $i, $run := .Runs}}<h2>Run {{add $i 1}}</h2>
+<ul>
+<li><b>Objective:</b> {{$run.Objective}}</li>
+{{with $run.Strategy}}<li><b>Strategy:</b> {{.}}</li>{{end}}
+<li><b>Started:</b> {{stamp $run.Started}}</li>
+{{if not $run.Ended.IsZero}}<li><b>Took:</b> {{took $run}} over {{$run.Turns}} turns</li>{{end}}
+{{if $run.Error}}<li class="error"><b>Error:</b> {{$run.Error}}</li>{{else if $run.Result}}<li><b>Result:</b> <span class="text">{{$run.Result}}</span></li>{{end}}
+</ul>
+{{with $run.Agreement}}<table>
+<tr><th>Agent</th><th>Answer</th><th>Agreed</th><th>Peers</th><th>Changes</th></tr>
+{{range .}}<tr><td>{{.Agent}}</td><td>{{.Answer}}</td><td>{{yesNo .Agreed}}</td><td>{{percent .Peers}}</td><td>{{.Changes}}</td></tr>
+{{end}}</table>{{end}}
+{{end}}
+<h2>Transcript</h2>
+{{range $i, $e := .Entries}}{{if ne $e.Kind "generation"}}{{if newTurn $.Entries $i}}<h3>Turn {{$e.Turn}}</h3>
+{{end}}{{if eq $e.Kind "message"}}<div class="entry {{$e.Role}}"><span class="who">{{$e.From}} → {{$e.To}}</span><span class="time">{{clock $e.Time}}</span>
+<div class="text">{{$e.Text}}</div></div>
+{{else if eq $e.Kind "delegation"}}<div class="entry delegation"><span class="who">{{$e.From}} delegates to {{$e.To}}</span><span class="time">{{clock $e.Time}}</span>
+<div class="text">{{$e.Text}}</div></div>
+{{else if eq $e.Kind "plan"}}<div class="entry plan"><span class="who">{{$e.From}} {{if $e.Text}}updated{{else}}made{{end}} a plan</span><span class="time">{{clock $e.Time}}</span>
+{{with $e.Text}}<div class="text">{{.}}</div>{{end}}{{if $e.Plan}}<ul class="steps">{{range $e.Plan}}<li><span class="status {{.Status}}">{{.Status}}</span> <b>{{.ID}}.</b> {{.Description}}{{with .About}} <span class="time">({{.}})</span>{{end}}{{with .Result}}<div class="text">{{.}}</div>{{end}}</li>{{end}}</ul>{{else}}<ol>{{range $e.Steps}}<li>{{.}}</li>{{end}}</ol>{{end}}</div>
+{{else if eq $e.Kind "tool_call"}}<details class="entry tool{{if $e.Tool.Error}} failed{{end}}"><summary><span class="who">{{$e.From}}</span> called <code>{{$e.Tool.Name}}</code><span class="time">{{clock $e.Time}}</span>{{if $e.Tool.Error}} <span class="error">failed</span>{{end}}</summary>
+<p>Arguments</p><pre>{{pretty $e.Tool.Arguments}}</pre>
+{{if $e.Tool.Error}}<p>Error</p><pre class="error">{{$e.Tool.Error}}</pre>{{else}}<p>Result</p><pre>{{$e.Tool.Result}}</pre>{{end}}
+</details>
+{{end}}{{end}}{{end}}
+{{with .Usage}}<h2>Token Usage</h2>
+<table>
+<tr><th>Agent</th><th>Model</th><th>Requests</th><th>Tokens</th></tr>
+{{range .}}<tr><td>{{.Agent}}</td><td>{{.Model}}</td><td class="n">{{.Generations}}</td><td class="n">{{.Tokens}}</td></tr>
+{{end}}<tr><th colspan="3">Total</th><td class="n"><b>{{$.TotalTokens}}</b></td></tr>
+</table>{{end}}
+</body>
+</html>
+`))
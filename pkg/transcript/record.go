@@ -0,0 +1,242 @@
+package transcript
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+)
+
+// Recorder builds a conversation's transcript from its events as they
+// happen.
+type Recorder struct {
+	mu      sync.Mutex
+	t       Transcript
+	partner map[string]string // whom each agent answers
+	// group is set while a run has the agents speak from the board.
+	group bool
+	// delegated marks workers whose next user message is the delegated task,
+	// already recorded as a delegation.
+	delegated map[string]bool
+	calls     map[string]int // open tool calls by id, as entry indexes
+}
+
+// Record starts recording conv. The agents' current messages, such as their
+// system messages, open the transcript.
+func Record(conv *chorus.Conversation) *Recorder {
+	r := &Recorder{
+		t:         Transcript{Version: Version, Conversation: conv.ID()},
+		partner:   map[string]string{},
+		delegated: map[string]bool{},
+		calls:     map[string]int{},
+	}
+
+	now := time.Now()
+	for _, agent := range conv.Agents() {
+		r.t.Agents = append(r.t.Agents, Participant{Name: agent.Name, Role: string(agent.Role), Model: agent.Model})
+		for _, msg := range agent.Messages {
+			r.message(chorus.Event{Time: now, Agent: agent.Name}, msg)
+		}
+	}
+	conv.Events().Subscribe(r.record)
+	return r
+}
+
+// Transcript returns the transcript so far.
+func (r *Recorder) Transcript() *Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.t
+	t.Agents = slices.Clone(r.t.Agents)
+	t.Runs = slices.Clone(r.t.Runs)
+	for i := range t.Runs {
+		t.Runs[i].Agreement = slices.Clone(t.Runs[i].Agreement)
+	}
+	t.Entries = make([]Entry, len(r.t.Entries))
+	for i, e := range r.t.Entries {
+		if e.Tool != nil {
+			tool := *e.Tool
+			e.Tool = &tool
+		}
+		// Redact rewrites these in place.
+		e.Steps = slices.Clone(e.Steps)
+		e.Plan = slices.Clone(e.Plan)
+		t.Entries[i] = e
+	}
+	t.Usage = r.usage()
+	return &t
+}
+
+// usage totals the generations of each agent.
+func (r *Recorder) usage() []Usage {
+	usage := make([]Usage, len(r.t.Agents))
+	index := map[string]int{}
+	for i, p := range r.t.Agents {
+		usage[i] = Usage{Agent: p.Name, Model: p.Model}
+		index[p.Name] = i
+	}
+	for _, e := range r.t.Entries {
+		if i, ok := index[e.From]; ok && e.Kind == KindGeneration {
+			usage[i].Generations++
+			usage[i].Tokens += e.Tokens
+		}
+	}
+	return usage
+}
+
+func (r *Recorder) record(ev chorus.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch ev.Type {
+	case chorus.EventRunStarted:
+		r.t.Runs = append(r.t.Runs, Run{Objective: ev.Text, Strategy: ev.Strategy, Started: ev.Time})
+		r.group = ev.Strategy != "" && ev.Strategy != chorus.StrategyOrchestrator
+	case chorus.EventTurnStarted:
+		if r.group {
+			r.partner[ev.Agent] = Everyone
+		}
+	case chorus.EventFinished, chorus.EventError:
+		if n := len(r.t.Runs); n > 0 {
+			run := &r.t.Runs[n-1]
+			run.Ended, run.Turns, run.Result, run.Error = ev.Time, ev.Turn, ev.Text, ev.Error
+		}
+	case chorus.EventConsensus:
+		if n := len(r.t.Runs); n > 0 {
+			run := &r.t.Runs[n-1]
+			run.Agreement = nil
+			for _, a := range ev.Agreement {
+				run.Agreement = append(run.Agreement, Agreement(a))
+			}
+		}
+	case chorus.EventMessage:
+		if ev.Message != nil {
+			r.message(ev, *ev.Message)
+		}
+	case chorus.EventDelegation:
+		from := r.orchestrator()
+		r.partner[ev.Agent] = from
+		r.delegated[ev.Agent] = true
+		r.add(ev, Entry{Kind: KindDelegation, From: from, To: ev.Agent, Text: ev.Text})
+	case chorus.EventToolCall:
+		r.add(ev, Entry{Kind: KindToolCall, From: ev.Agent, Tool: &ToolCall{ID: ev.ToolCallID, Name: ev.Tool, Arguments: ev.Arguments}})
+		r.calls[callKey(ev)] = len(r.t.Entries) - 1
+	case chorus.EventToolResult:
+		i, ok := r.calls[callKey(ev)]
+		if !ok {
+			return
+		}
+		delete(r.calls, callKey(ev))
+		tool := r.t.Entries[i].Tool
+		tool.Result, tool.Error, tool.Ended = ev.Text, ev.Error, ev.Time
+	case chorus.EventPlanCreated, chorus.EventPlanUpdated:
+		e := Entry{Kind: KindPlan, From: ev.Agent, Text: ev.Text, Steps: slices.Clone(ev.Steps)}
+		if ev.Plan != nil {
+			e.Steps = nil
+			for _, step := range ev.Plan.Steps {
+				e.Steps = append(e.Steps, step.Description)
+				e.Plan = append(e.Plan, Step{
+					ID:          step.ID,
+					Description: step.Description,
+					Assignee:    step.Assignee,
+					Status:      string(step.Status),
+					DependsOn:   slices.Clone(step.DependsOn),
+					Result:      step.Result,
+				})
+			}
+		}
+		r.add(ev, e)
+	case chorus.EventGenerated:
+		r.add(ev, Entry{Kind: KindGeneration, From: ev.Agent, Model: ev.Model, Tokens: ev.Tokens, Error: ev.Error})
+	}
+}
+
+// callKey matches tool results to their calls, by id when the model gave one.
+func callKey(ev chorus.Event) string {
+	if ev.ToolCallID != "" {
+		return ev.ToolCallID
+	}
+	return ev.Agent + "/" + ev.Tool
+}
+
+func (r *Recorder) orchestrator() string {
+	for _, p := range r.t.Agents {
+		if p.Role == string(chorus.RoleOrchestrator) {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// message records a message added to an agent's history, working out who
+// it is from and to.
+func (r *Recorder) message(ev chorus.Event, msg openai.ChatCompletionMessageParamUnion) {
+	agent := ev.Agent
+	text := MessageText(msg)
+
+	switch {
+	case msg.OfSystem != nil || msg.OfDeveloper != nil:
+		r.add(ev, Entry{Kind: KindMessage, From: "system", To: agent, Role: "system", Text: text})
+	case msg.OfUser != nil:
+		if r.delegated[agent] {
+			delete(r.delegated, agent)
+			return
+		}
+		r.partner[agent] = User
+		r.add(ev, Entry{Kind: KindMessage, From: User, To: agent, Role: "user", Text: text})
+	case msg.OfAssistant != nil:
+		if text == "" {
+			return
+		}
+		to := r.partner[agent]
+		if to == "" {
+			to = User
+		}
+		r.add(ev, Entry{Kind: KindMessage, From: agent, To: to, Role: "assistant", Text: text})
+	}
+}
+
+func (r *Recorder) add(ev chorus.Event, e Entry) {
+	e.Seq = int64(len(r.t.Entries) + 1)
+	e.Time = ev.Time
+	e.Turn = ev.Turn
+	r.t.Entries = append(r.t.Entries, e)
+}
+
+// MessageText joins the text parts of a message, or returns its refusal.
+func MessageText(msg openai.ChatCompletionMessageParamUnion) string {
+	var parts []string
+	switch content := msg.GetContent().AsAny().(type) {
+	case *string:
+		parts = append(parts, *content)
+	case *[]openai.ChatCompletionContentPartTextParam:
+		for _, p := range *content {
+			parts = append(parts, p.Text)
+		}
+	case *[]openai.ChatCompletionContentPartUnionParam:
+		for _, p := range *content {
+			if p.OfText != nil {
+				parts = append(parts, p.OfText.Text)
+			}
+		}
+	case *[]openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion:
+		for _, p := range *content {
+			if p.OfText != nil {
+				parts = append(parts, p.OfText.Text)
+			} else if p.OfRefusal != nil {
+				parts = append(parts, p.OfRefusal.Refusal)
+			}
+		}
+	}
+	if text := strings.Join(parts, "\n"); text != "" {
+		return text
+	}
+	if refusal := msg.GetRefusal(); refusal != nil {
+		return *refusal
+	}
+	return ""
+}
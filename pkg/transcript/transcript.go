@@ -0,0 +1,197 @@
+// Package transcript records what happened in a conversation, interleaved
+// by time across its agents, and renders it as Markdown, JSON or HTML.
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+// Version is the version of the JSON format. Readers reject transcripts of
+// a later version.
+const Version = 1
+
+// User stands for whoever talks to the agents from outside the conversation.
+const User = "user"
+
+// Everyone stands for all the agents, whom replies are addressed to when the
+// agents speak from a shared board rather than to the orchestrator.
+const Everyone = "everyone"
+
+// Transcript is the record of a conversation.
+type Transcript struct {
+	Version      int           `json:"version"`
+	Conversation string        `json:"conversation"`
+	Agents       []Participant `json:"agents"`
+	Runs         []Run         `json:"runs"`
+	Entries      []Entry       `json:"entries"`
+	Usage        []Usage       `json:"usage"`
+}
+
+// Participant is an agent taking part in the conversation.
+type Participant struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+	Model string `json:"model"`
+}
+
+// Run is one Conversation.Run. Result or Error are set once it has ended.
+type Run struct {
+	Objective string `json:"objective"`
+	// Strategy is how the agents took turns, such as orchestrator or round_robin.
+	Strategy string    `json:"strategy,omitempty"`
+	Started  time.Time `json:"started"`
+	Ended    time.Time `json:"ended,omitzero"`
+	Turns    int       `json:"turns,omitempty"`
+	Result   string    `json:"result,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Agreement is how each agent agreed with a debate's answer.
+	Agreement []Agreement `json:"agreement,omitempty"`
+}
+
+// Agreement is how one agent fared in a debate: its last answer, whether that
+// was the debate's answer, the share of the other agents that gave the same
+// answer and how many times it changed its answer.
+type Agreement struct {
+	Agent   string  `json:"agent"`
+	Answer  string  `json:"answer"`
+	Agreed  bool    `json:"agreed"`
+	Peers   float64 `json:"peers"`
+	Changes int     `json:"changes"`
+}
+
+// EntryKind says what an Entry records.
+type EntryKind string
+
+const (
+	// KindMessage is a message From one participant To another. Messages
+	// carrying only tool calls or tool results are left to KindToolCall.
+	KindMessage EntryKind = "message"
+	// KindDelegation is the orchestrator (From) handing a task to a worker (To).
+	KindDelegation EntryKind = "delegation"
+	// KindToolCall is a tool call made by From, with its result once it ran.
+	KindToolCall EntryKind = "tool_call"
+	// KindPlan is a snapshot of the plan From made or changed. Text says
+	// what changed.
+	KindPlan EntryKind = "plan"
+	// KindGeneration is a request From made to its model.
+	KindGeneration EntryKind = "generation"
+)
+
+// Entry is something that happened in the conversation. Which fields are set
+// depends on the kind.
+type Entry struct {
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	Turn int       `json:"turn,omitempty"`
+	Kind EntryKind `json:"kind"`
+	// From and To are agent names, User, Everyone or, for system messages,
+	// "system".
+	From string `json:"from"`
+	To   string `json:"to,omitempty"`
+	// Role is the chat role of a message: system, user or assistant.
+	Role   string    `json:"role,omitempty"`
+	Text   string    `json:"text,omitempty"`
+	Tool   *ToolCall `json:"tool,omitempty"`
+	Steps  []string  `json:"steps,omitempty"`
+	Plan   []Step    `json:"plan,omitempty"`
+	Model  string    `json:"model,omitempty"`
+	Tokens int64     `json:"tokens,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// ToolCall is a tool call and its outcome.
+type ToolCall struct {
+	ID        string    `json:"id,omitempty"`
+	Name      string    `json:"name"`
+	Arguments string    `json:"arguments"`
+	Result    string    `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Ended     time.Time `json:"ended,omitzero"`
+}
+
+// Step is a plan step as it stood when the plan entry was recorded.
+type Step struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Status      string   `json:"status"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	Result      string   `json:"result,omitempty"`
+}
+
+// About names the step's assignee and the steps it waits for.
+func (s Step) About() string {
+	var about []string
+	if s.Assignee != "" {
+		about = append(about, s.Assignee)
+	}
+	if len(s.DependsOn) > 0 {
+		about = append(about, "after "+strings.Join(s.DependsOn, ", "))
+	}
+	return strings.Join(about, "; ")
+}
+
+// Usage is the requests an agent made to its model during the
+// conversation, and the tokens they used.
+type Usage struct {
+	Agent       string `json:"agent"`
+	Model       string `json:"model"`
+	Generations int    `json:"generations"`
+	Tokens      int64  `json:"tokens"`
+}
+
+// TotalTokens sums the tokens every agent used.
+func (t *Transcript) TotalTokens() int64 {
+	var total int64
+	for _, u := range t.Usage {
+		total += u.Tokens
+	}
+	return total
+}
+
+// Redact masks the secrets in everything t quotes: objectives, results,
+// messages, plans and tool calls.
+func (t *Transcript) Redact(r *redact.Redactor) {
+	if r == nil {
+		return
+	}
+	for i := range t.Runs {
+		run := &t.Runs[i]
+		run.Objective, run.Result, run.Error = r.String(run.Objective), r.String(run.Result), r.String(run.Error)
+		for j := range run.Agreement {
+			run.Agreement[j].Answer = r.String(run.Agreement[j].Answer)
+		}
+	}
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		e.Text, e.Error = r.String(e.Text), r.String(e.Error)
+		for j, step := range e.Steps {
+			e.Steps[j] = r.String(step)
+		}
+		for j := range e.Plan {
+			step := &e.Plan[j]
+			step.Description, step.Result = r.String(step.Description), r.String(step.Result)
+		}
+		if tool := e.Tool; tool != nil {
+			tool.Arguments, tool.Result, tool.Error = r.String(tool.Arguments), r.String(tool.Result), r.String(tool.Error)
+		}
+	}
+}
+
+// Read decodes a transcript written by WriteJSON.
+func Read(r io.Reader) (*Transcript, error) {
+	var t Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid transcript: %w", err)
+	}
+	if t.Version < 1 || t.Version > Version {
+		return nil, fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	return &t, nil
+}
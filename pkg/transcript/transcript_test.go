@@ -0,0 +1,212 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	chorus "github.com/standrze/chorus/pkg/agent"
+	"github.com/standrze/chorus/pkg/redact"
+)
+
+type scriptedClient struct {
+	responses []*openai.ChatCompletion
+}
+
+func (c *scriptedClient) ChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams) (*openai.ChatCompletion, error) {
+	if len(c.responses) == 0 {
+		return nil, fmt.Errorf("unexpected call")
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func completion(tokens int64, content string, calls ...openai.ChatCompletionMessageToolCallUnion) *openai.ChatCompletion {
+	return &openai.ChatCompletion{
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: "assistant", Content: content, ToolCalls: calls},
+		}},
+		Usage: openai.CompletionUsage{TotalTokens: tokens},
+	}
+}
+
+func call(id, name, args string) openai.ChatCompletionMessageToolCallUnion {
+	return openai.ChatCompletionMessageToolCallUnion{
+		ID:       id,
+		Type:     "function",
+		Function: openai.ChatCompletionMessageFunctionToolCallFunction{Name: name, Arguments: args},
+	}
+}
+
+// record runs a conversation in which the lead plans, delegates once and
+// finishes.
+func record(t *testing.T) *Transcript {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	lead := chorus.NewAgent(&scriptedClient{responses: []*openai.ChatCompletion{
+		completion(10, "", call("call_1", "CreatePlan", `{"steps":["research","write"]}`)),
+		completion(20, "Handing off.", call("call_2", "DelegateTask", `{"agent_name":"Writer","instructions":"write it"}`)),
+		completion(30, "", call("call_3", "Finish", `{"result":"done"}`)),
+	}}, chorus.WithName("Lead"), chorus.WithRole(chorus.RoleOrchestrator), chorus.WithModel("big"))
+	lead.SystemMessage("You lead.")
+	writer := chorus.NewAgent(&scriptedClient{responses: []*openai.ChatCompletion{
+		completion(5, "an essay"),
+	}}, chorus.WithName("Writer"), chorus.WithModel("small"))
+
+	conv, err := chorus.NewConversation(context.Background(), lead, writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := Record(conv)
+	if _, err := conv.Run("write an essay"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return rec.Transcript()
+}
+
+func TestRecord(t *testing.T) {
+	tr := record(t)
+
+	if len(tr.Runs) != 1 || tr.Runs[0].Objective != "write an essay" || tr.Runs[0].Result != "done" || tr.Runs[0].Turns != 3 {
+		t.Errorf("Unexpected runs %+v", tr.Runs)
+	}
+
+	type step struct {
+		kind     EntryKind
+		from, to string
+		detail   string
+	}
+	var got []step
+	for _, e := range tr.Entries {
+		s := step{kind: e.Kind, from: e.From, to: e.To, detail: e.Text}
+		switch e.Kind {
+		case KindGeneration:
+			continue
+		case KindToolCall:
+			s.detail = e.Tool.Name + "=" + e.Tool.Result
+		case KindPlan:
+			s.detail = strings.Join(e.Steps, ",")
+		}
+		got = append(got, s)
+	}
+	want := []step{
+		{KindMessage, "system", "Lead", "You lead."},
+		{KindMessage, User, "Lead", "Objective: write an essay"},
+		{KindToolCall, "Lead", "", "CreatePlan=Plan created with 2 steps and saved to plan.json.\nPlan (revision 0, 0 of 2 steps done):\n[ ] 1. research\n[ ] 2. write\n"},
+		{KindPlan, "Lead", "", "research,write"},
+		{KindMessage, "Lead", User, "Handing off."},
+		{KindToolCall, "Lead", "", "DelegateTask=an essay"},
+		{KindDelegation, "Lead", "Writer", "write it"},
+		{KindMessage, "Writer", "Lead", "an essay"},
+		{KindToolCall, "Lead", "", "Finish=Conversation finished."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if len(tr.Usage) != 2 || tr.Usage[0] != (Usage{Agent: "Lead", Model: "big", Generations: 3, Tokens: 60}) ||
+		tr.Usage[1] != (Usage{Agent: "Writer", Model: "small", Generations: 1, Tokens: 5}) {
+		t.Errorf("Unexpected usage %+v", tr.Usage)
+	}
+}
+
+func TestExport(t *testing.T) {
+	tr := record(t)
+
+	var md bytes.Buffer
+	if err := Export(&md, tr, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"- **Objective:** write an essay", "**Lead delegates to Writer**", "<summary>Lead called <code>DelegateTask</code>", "### Turn 3", "| **Total** | | | **65** |"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Expected %q in the Markdown:\n%s", want, md.String())
+		}
+	}
+
+	var page bytes.Buffer
+	if err := Export(&page, tr, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(page.String(), "<details"); n != 3 || strings.Contains(page.String(), "<script") {
+		t.Errorf("Expected 3 collapsible tool calls and no scripts, got %d:\n%s", n, page.String())
+	}
+
+	var js bytes.Buffer
+	if err := Export(&js, tr, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	back, err := Read(&js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Entries) != len(tr.Entries) || back.Entries[len(back.Entries)-1].Tool.Result != "Conversation finished." {
+		t.Errorf("Expected the JSON to round trip, got %+v", back)
+	}
+
+	if err := Export(&js, tr, "pdf"); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
+
+func TestRecord_Debate(t *testing.T) {
+	debater := func(name string, answers ...string) *chorus.Agent {
+		client := &scriptedClient{}
+		for _, answer := range answers {
+			client.responses = append(client.responses, completion(1, answer))
+		}
+		return chorus.NewAgent(client, chorus.WithName(name))
+	}
+	lead := chorus.NewAgent(nil, chorus.WithName("Lead"), chorus.WithRole(chorus.RoleOrchestrator))
+	conv, err := chorus.NewConversation(t.Context(), lead, debater("A", "4", "4"), debater("B", "5", "4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv.SetStrategy(&chorus.Debate{Rounds: 1})
+	rec := Record(conv)
+	if _, err := conv.Run("What is 2+2?"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	tr := rec.Transcript()
+
+	if run := tr.Runs[0]; run.Strategy != chorus.StrategyDebate || len(run.Agreement) != 2 || run.Agreement[1] != (Agreement{Agent: "B", Answer: "4", Agreed: true, Peers: 1, Changes: 1}) {
+		t.Errorf("Unexpected run %+v", run)
+	}
+	if e := tr.Entries[1]; e.From != "A" || e.To != Everyone || e.Text != "4" {
+		t.Errorf("Expected answers addressed to everyone, got %+v", e)
+	}
+
+	var md bytes.Buffer
+	if err := Export(&md, tr, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(md.String(), "| B | 4 | yes | 100% | 1 |") {
+		t.Errorf("Expected the agreement in the Markdown:\n%s", md.String())
+	}
+}
+
+func TestRead_RejectsNewerVersions(t *testing.T) {
+	if _, err := Read(strings.NewReader(`{"version":2}`)); err == nil {
+		t.Error("Expected a newer version to be rejected")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tr := &Transcript{
+		Runs:    []Run{{Objective: "mail bob@example.com"}},
+		Entries: []Entry{{Kind: KindToolCall, Tool: &ToolCall{Name: "login", Arguments: `{"password":"hunter22"}`}}},
+	}
+	tr.Redact(redact.Default())
+	if tr.Runs[0].Objective != "mail [REDACTED]" || tr.Entries[0].Tool.Arguments != `{"password":"[REDACTED]"}` {
+		t.Errorf("Expected the email and password to be redacted, got %+v, %+v", tr.Runs[0], tr.Entries[0].Tool)
+	}
+}